@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"net"
+
+	"github.com/spf13/viper"
+	"github.com/ultraviolet-black/cruiser/pkg/admin"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"github.com/ultraviolet-black/cruiser/pkg/server"
+	"github.com/ultraviolet-black/cruiser/pkg/tls"
+)
+
+var (
+	adminAddress   string
+	adminEnableTls bool
+	adminCertFile  string
+	adminKeyFile   string
+	adminAuthToken string
+
+	// Deprecated alias of adminAddress, kept for configurations predating the
+	// admin listener.
+	metricsAddress string
+
+	adminTlsContext       tls.TlsContext
+	adminListenerProtocol server.ListenerProtocol
+
+	adminServer server.Server
+)
+
+func isLoopbackAddress(address string) bool {
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+
+}
+
+func resolveAdminAddress() (string, error) {
+
+	flags := rootCmd.PersistentFlags()
+
+	if !flags.Changed("metrics-address") {
+		return adminAddress, nil
+	}
+
+	if flags.Changed("admin-address") && adminAddress != metricsAddress {
+		return "", ErrConflictingAdminAddress
+	}
+
+	return metricsAddress, nil
+
+}
+
+func openAdminServer(opts ...admin.HandlerOption) error {
+
+	address, err := resolveAdminAddress()
+	if err != nil {
+		return err
+	}
+
+	if len(address) == 0 {
+		return nil
+	}
+
+	exposed := !isLoopbackAddress(address)
+
+	if exposed && len(adminAuthToken) == 0 {
+		observability.Log.Warnw("admin listener is not bound to loopback and has no auth token, only health and metrics endpoints are served",
+			"address", address,
+		)
+	}
+
+	adminListenerProtocol = server.H2C
+
+	if adminEnableTls {
+
+		adminListenerProtocol = server.HTTP2
+
+		if len(adminCertFile) != 0 && len(adminKeyFile) != 0 {
+			tlsCtx, err := tls.FromFile(
+				tls.FromFileWithCertificate(adminCertFile, adminKeyFile),
+			)
+
+			if err != nil {
+				return err
+			}
+
+			adminTlsContext = tlsCtx
+		} else {
+			tlsCtx, err := tls.FromMemory()
+
+			if err != nil {
+				return err
+			}
+
+			adminTlsContext = tlsCtx
+		}
+
+	}
+
+	handler := admin.NewHandler(
+		append(
+			[]admin.HandlerOption{
+				admin.WithAuthToken(adminAuthToken),
+				admin.WithExposed(exposed),
+				admin.WithLogger(observability.Log.With("component", "admin")),
+				admin.WithHandler("/metrics", observability.MetricsHandler()),
+				admin.WithHandler("/loglevel", observability.LogLevelHandler()),
+			},
+			opts...,
+		)...,
+	)
+
+	adminServer = server.NewServer(
+		server.WithListenerAddress(address),
+		server.WithShutdownTimeout(shutdownTimeout),
+		server.WithListenerProtocol(adminListenerProtocol),
+		server.WithHTTPHandler(handler),
+		server.WithTLSConfig(adminTlsContext),
+	)
+
+	return adminServer.Open()
+
+}
+
+func closeAdminServer() error {
+
+	if adminServer == nil {
+		return nil
+	}
+
+	return adminServer.Close()
+
+}
+
+func initAdmin() {
+
+	rootCmd.PersistentFlags().StringVar(&adminAddress, "admin-address", "127.0.0.1:9090", "admin listener address (empty to disable)")
+	rootCmd.PersistentFlags().BoolVar(&adminEnableTls, "admin-enable-tls", false, "enable TLS on the admin listener")
+	rootCmd.PersistentFlags().StringVar(&adminCertFile, "admin-tls-certificate", "", "admin TLS certificate file")
+	rootCmd.PersistentFlags().StringVar(&adminKeyFile, "admin-tls-private-key", "", "admin TLS private key file")
+	rootCmd.PersistentFlags().StringVar(&adminAuthToken, "admin-auth-token", "", "bearer token required by the admin endpoints (required for all but health and metrics on non-loopback addresses)")
+	rootCmd.PersistentFlags().StringVar(&metricsAddress, "metrics-address", "", "metrics listener address")
+	rootCmd.PersistentFlags().MarkDeprecated("metrics-address", "metrics are served by the admin listener, use --admin-address")
+
+	viper.BindPFlag("admin_address", rootCmd.PersistentFlags().Lookup("admin-address"))
+	viper.BindPFlag("admin_enable_tls", rootCmd.PersistentFlags().Lookup("admin-enable-tls"))
+	viper.BindPFlag("admin_tls_certificate", rootCmd.PersistentFlags().Lookup("admin-tls-certificate"))
+	viper.BindPFlag("admin_tls_private_key", rootCmd.PersistentFlags().Lookup("admin-tls-private-key"))
+	viper.BindPFlag("admin_auth_token", rootCmd.PersistentFlags().Lookup("admin-auth-token"))
+	viper.BindPFlag("metrics_address", rootCmd.PersistentFlags().Lookup("metrics-address"))
+
+}
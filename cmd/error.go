@@ -5,4 +5,17 @@ import "errors"
 var (
 	ErrEmptyAwsTfstateBucket        = errors.New("empty aws tfstate bucket")
 	ErrInvalidTfstateSourceSelector = errors.New("invalid tfstate source selector")
+	ErrEmptyTfstateDirectory        = errors.New("empty tfstate directory")
+	ErrEmptyAccessLogFile           = errors.New("empty access log file")
+	ErrInvalidAccessLogSink         = errors.New("invalid access log sink")
+	ErrRouterNotReady               = errors.New("router not ready")
+	ErrInvalidRoutePolicy           = errors.New("invalid route policy")
+	ErrConfigTooStale               = errors.New("served configuration exceeds maximum staleness")
+	ErrServingSnapshot              = errors.New("serving restored snapshot, no successful state sync yet")
+	ErrNoTfstateSource              = errors.New("no tfstate source selected")
+	ErrDuplicateTfstateSource       = errors.New("duplicate tfstate source selector")
+	ErrInvalidCollisionPolicy       = errors.New("invalid collision policy")
+	ErrInvalidKMSSelector           = errors.New("invalid kms selector")
+	ErrInvalidTfstate               = errors.New("input is not a tfstate")
+	ErrConflictingAdminAddress      = errors.New("--metrics-address is a deprecated alias of --admin-address and both are set to different addresses")
 )
@@ -13,18 +13,35 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/ultraviolet-black/cruiser/pkg/config"
+	"github.com/ultraviolet-black/cruiser/pkg/encryption"
+	"github.com/ultraviolet-black/cruiser/pkg/hooks"
 	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	"github.com/ultraviolet-black/cruiser/pkg/providers/aws"
+	awskms "github.com/ultraviolet-black/cruiser/pkg/providers/aws/kms"
 	"github.com/ultraviolet-black/cruiser/pkg/providers/aws/s3"
+	"github.com/ultraviolet-black/cruiser/pkg/providers/file"
+	"github.com/ultraviolet-black/cruiser/pkg/providers/git"
+	"github.com/ultraviolet-black/cruiser/pkg/providers/kubernetes"
+	"github.com/ultraviolet-black/cruiser/pkg/providers/terraform"
 	"github.com/ultraviolet-black/cruiser/pkg/server"
+	"github.com/ultraviolet-black/cruiser/pkg/signature"
 	"github.com/ultraviolet-black/cruiser/pkg/state"
 	"github.com/ultraviolet-black/cruiser/pkg/tls"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 var (
 	cfgFile string
 
-	signalCh  = make(chan os.Signal)
+	logLevel              string
+	logFormat             string
+	logSamplingInitial    int
+	logSamplingThereafter int
+
+	signalCh  = make(chan os.Signal, 1)
 	waitClose = new(sync.WaitGroup)
 
 	certFile              string
@@ -39,9 +56,54 @@ var (
 
 	periodSyncInterval time.Duration
 
-	tfstateSourceSelector string
+	syncRetryInitialBackoff    time.Duration
+	syncRetryMaxBackoff        time.Duration
+	syncMaxConsecutiveFailures int
+
+	tfstateSourceSelectors []string
+	collisionPolicy        string
+	stateVariables         map[string]string
+	configVariables        map[string]string
+
+	tfstateDirectory    string
+	tfstateFilePatterns []string
+
+	tfstateHttpAddresses []string
+	tfstateHttpUsername  string
+	tfstateHttpPassword  string
+
+	tfcAddress      string
+	tfcToken        string
+	tfcOrganization string
+	tfcWorkspaces   []string
+
+	tfstateGitRepository     string
+	tfstateGitRef            string
+	tfstateGitDirectory      string
+	tfstateGitPatterns       []string
+	tfstateGitCloneDirectory string
+	tfstateGitUsername       string
+	tfstateGitPassword       string
+	tfstateGitSSHKeyFile     string
+
+	tfstateConfigFiles []string
+
+	kubeConfig        string
+	kubeNamespace     string
+	kubeLabelSelector string
+	kubeCruiserRoutes bool
+
+	tfstateOrigins []state.TfstateOrigin
+
+	tfstateAgeIdentityFiles  []string
+	tfstateKMS               []string
+	tfstateKMSLocalKeyFiles  []string
+	tfstateRequireEncryption bool
 
-	tfstateSource state.TfstateSource
+	tfstateTrustedKeys []string
+
+	tfstateDecrypter state.TfstateDecrypter
+	tfstateVerifier  state.TfstateVerifier
 
 	backendProviders = []server.BackendProvider{}
 
@@ -50,11 +112,43 @@ var (
 	awsTfstateBucket string
 	awsS3AssumeRole  string
 
+	s3Endpoint                    string
+	sqsEndpoint                   string
+	kmsEndpoint                   string
+	awsTfstateQueueUrl            string
+	awsTfstatePrefixes            []string
+	awsTfstateInclude             []string
+	awsTfstateExclude             []string
+	awsTfstateWorkspaces          []string
+	awsTfstateWorkspaceKeyPrefix  string
+	awsTfstateDownloadConcurrency int
+	awsTfstateReconcileInterval   time.Duration
+
 	healthCheckInterval    time.Duration
 	healthCheckParallelism = 4
 
+	lambdaLogTail bool
+
 	listenerProtocol server.ListenerProtocol = server.H2C
 
+	requestIdHeader      string
+	trustRequestIdHeader bool
+
+	otlpEndpoint     string
+	otlpInsecure     bool
+	traceSampleRatio float64
+
+	tracingShutdown = func(context.Context) error { return nil }
+
+	webhookURL        string
+	webhookSecret     string
+	webhookQueueSize  int
+	webhookMaxRetries int
+
+	webhookNotifier hooks.Webhook
+
+	changeNotifiers = []state.ChangeNotifier{}
+
 	stateManager state.StateManager
 
 	rootCmd = &cobra.Command{
@@ -89,41 +183,87 @@ var (
 
 			}
 
+			shutdown, err := observability.InitializeTracing(
+				cmd.Context(),
+				observability.WithOtlpEndpoint(otlpEndpoint, otlpInsecure),
+				observability.WithTracingSampleRatio(traceSampleRatio),
+			)
+			if err != nil {
+				return err
+			}
+
+			tracingShutdown = shutdown
+
 			awsProvider = aws.NewProvider(
 				aws.WithDynamoDBEndpoint(dynamodbEndpoint),
+				aws.WithS3Endpoint(s3Endpoint),
+				aws.WithSQSEndpoint(sqsEndpoint),
+				aws.WithKMSEndpoint(kmsEndpoint),
 				aws.WithHealthCheckInterval(healthCheckInterval),
 				aws.WithHealthCheckParallelism(healthCheckParallelism),
+				aws.WithLambdaLogTail(lambdaLogTail),
+				aws.WithLogger(observability.Log.With("component", "aws")),
 			)
 
 			backendProviders = append(backendProviders, awsProvider)
 
-			switch tfstateSourceSelector {
+			if len(webhookURL) > 0 {
+
+				webhookNotifier = hooks.NewWebhook(
+					hooks.WithWebhookURL(webhookURL),
+					hooks.WithWebhookSecret(webhookSecret),
+					hooks.WithWebhookQueueSize(webhookQueueSize),
+					hooks.WithWebhookMaxRetries(webhookMaxRetries),
+					hooks.WithWebhookLogger(observability.Log.With("component", "webhook")),
+				)
 
-			case "aws-s3":
+				changeNotifiers = append(changeNotifiers, webhookNotifier)
 
-				if len(awsTfstateBucket) == 0 {
-					return ErrEmptyAwsTfstateBucket
-				}
+			}
 
-				s3ClientFactory := func() *awss3.Client {
-					return awsProvider.GetS3Client()
+			configVariables = viper.GetStringMapString("vars")
+
+			decrypter, err := newTfstateDecrypter()
+			if err != nil {
+				return err
+			}
+
+			tfstateDecrypter = decrypter
+
+			if len(tfstateTrustedKeys) > 0 {
+
+				verifier, err := signature.NewVerifier(
+					signature.WithTrustedKeyFiles(tfstateTrustedKeys...),
+				)
+				if err != nil {
+					return err
 				}
 
-				if len(awsS3AssumeRole) > 0 {
-					s3ClientFactory = awsProvider.GetS3ClientWithRole(awsS3AssumeRole)
+				tfstateVerifier = verifier
+
+			}
+
+			tfstateOrigins = []state.TfstateOrigin{}
+
+			for _, selector := range tfstateSourceSelectors {
+
+				for _, origin := range tfstateOrigins {
+					if origin.Name == selector {
+						return ErrDuplicateTfstateSource
+					}
 				}
 
-				tfstateSource = s3.NewTfstateSource(
-					s3.WithS3ClientFactory(s3ClientFactory),
-					s3.WithTfstateBucket(awsTfstateBucket),
-				)
+				source, err := newTfstateSource(selector)
+				if err != nil {
+					return err
+				}
 
-			case "":
-				observability.Log.Warn("No tfstate source selected, tfstate source will be disabled")
+				tfstateOrigins = append(tfstateOrigins, state.TfstateOrigin{Name: selector, Source: source})
 
-			default:
-				return ErrInvalidTfstateSourceSelector
+			}
 
+			if len(tfstateOrigins) == 0 {
+				observability.Log.Warn("No tfstate source selected, tfstate source will be disabled")
 			}
 
 			return nil
@@ -131,14 +271,175 @@ var (
 	}
 )
 
+func newTfstateSource(selector string) (state.TfstateSource, error) {
+
+	switch selector {
+
+	case "aws-s3":
+
+		if len(awsTfstateBucket) == 0 {
+			return nil, ErrEmptyAwsTfstateBucket
+		}
+
+		s3ClientFactory := func() *awss3.Client {
+			return awsProvider.GetS3Client()
+		}
+
+		if len(awsS3AssumeRole) > 0 {
+			s3ClientFactory = awsProvider.GetS3ClientWithRole(awsS3AssumeRole)
+		}
+
+		s3Opts := []s3.TfstateSourceOption{
+			s3.WithS3ClientFactory(s3ClientFactory),
+			s3.WithDecrypter(tfstateDecrypter),
+			s3.WithVerifier(tfstateVerifier),
+			s3.WithTfstateBucket(awsTfstateBucket),
+			s3.WithReconcileInterval(awsTfstateReconcileInterval),
+			s3.WithPrefixes(awsTfstatePrefixes...),
+			s3.WithIncludePatterns(awsTfstateInclude...),
+			s3.WithExcludePatterns(awsTfstateExclude...),
+			s3.WithWorkspaces(awsTfstateWorkspaces...),
+			s3.WithWorkspaceKeyPrefix(awsTfstateWorkspaceKeyPrefix),
+			s3.WithDownloadConcurrency(awsTfstateDownloadConcurrency),
+			s3.WithLogger(observability.Log.With("component", "s3", "bucket", awsTfstateBucket)),
+		}
+
+		if len(awsTfstateQueueUrl) > 0 {
+			s3Opts = append(s3Opts, s3.WithNotificationQueue(awsProvider.GetSQSClient(), awsTfstateQueueUrl))
+		}
+
+		return s3.NewTfstateSource(s3Opts...), nil
+
+	case "file":
+
+		if len(tfstateDirectory) == 0 {
+			return nil, ErrEmptyTfstateDirectory
+		}
+
+		return file.NewTfstateSource(
+			file.WithDirectory(tfstateDirectory),
+			file.WithPatterns(tfstateFilePatterns...),
+			file.WithDecrypter(tfstateDecrypter),
+			file.WithVerifier(tfstateVerifier),
+			file.WithLogger(observability.Log.With("component", "file", "directory", tfstateDirectory)),
+		), nil
+
+	case "terraform-http":
+
+		source, err := terraform.NewHttpBackendSource(
+			terraform.WithHttpBackendAddresses(tfstateHttpAddresses...),
+			terraform.WithHttpBackendBasicAuth(tfstateHttpUsername, tfstateHttpPassword),
+			terraform.WithHttpBackendDecrypter(tfstateDecrypter),
+			terraform.WithHttpBackendVerifier(tfstateVerifier),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return source, nil
+
+	case "terraform-cloud":
+
+		source, err := terraform.NewCloudSource(
+			terraform.WithCloudAddress(tfcAddress),
+			terraform.WithCloudToken(tfcToken),
+			terraform.WithCloudOrganization(tfcOrganization),
+			terraform.WithCloudWorkspaces(tfcWorkspaces...),
+			terraform.WithCloudDecrypter(tfstateDecrypter),
+			terraform.WithCloudVerifier(tfstateVerifier),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return source, nil
+
+	case "git":
+
+		source, err := git.NewTfstateSource(
+			git.WithRepository(tfstateGitRepository),
+			git.WithRef(tfstateGitRef),
+			git.WithDirectory(tfstateGitDirectory),
+			git.WithPatterns(tfstateGitPatterns...),
+			git.WithCloneDirectory(tfstateGitCloneDirectory),
+			git.WithBasicAuth(tfstateGitUsername, tfstateGitPassword),
+			git.WithSSHKeyFile("git", tfstateGitSSHKeyFile, ""),
+			git.WithDecrypter(tfstateDecrypter),
+			git.WithVerifier(tfstateVerifier),
+			git.WithLogger(observability.Log.With("component", "git", "repository", tfstateGitRepository, "ref", tfstateGitRef)),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return source, nil
+
+	case "config":
+
+		source, err := config.NewTfstateSource(
+			config.WithFiles(tfstateConfigFiles...),
+			config.WithDecrypter(tfstateDecrypter),
+			config.WithVerifier(tfstateVerifier),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return source, nil
+
+	case "kubernetes":
+
+		restConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := k8s.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		kubernetesOpts := []kubernetes.TfstateSourceOption{
+			kubernetes.WithClient(client),
+			kubernetes.WithNamespace(kubeNamespace),
+			kubernetes.WithLabelSelector(kubeLabelSelector),
+			kubernetes.WithDecrypter(tfstateDecrypter),
+			kubernetes.WithVerifier(tfstateVerifier),
+		}
+
+		if kubeCruiserRoutes {
+
+			dynamicClient, err := dynamic.NewForConfig(restConfig)
+			if err != nil {
+				return nil, err
+			}
+
+			kubernetesOpts = append(kubernetesOpts, kubernetes.WithCruiserRoutes(dynamicClient))
+
+		}
+
+		source, err := kubernetes.NewTfstateSource(kubernetesOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return source, nil
+
+	}
+
+	return nil, ErrInvalidTfstateSourceSelector
+
+}
+
 func Execute() error {
 
 	ctx := context.Background()
 
 	ctx, cancel := context.WithCancel(ctx)
 
+	waitClose.Add(1)
+
 	go func() {
-		waitClose.Add(1)
 		defer waitClose.Done()
 		select {
 		case <-signalCh:
@@ -147,6 +448,17 @@ func Execute() error {
 		}
 	}()
 
+	defer func() {
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := tracingShutdown(ctx); err != nil {
+			observability.Log.Errorw("error shutting down tracing", "error", err)
+		}
+
+	}()
+
 	return rootCmd.ExecuteContext(ctx)
 
 }
@@ -157,6 +469,10 @@ func init() {
 	signal.Notify(signalCh, os.Interrupt, os.Kill, syscall.SIGTERM)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cruiser.toml)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level, valid values: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "json", "log format, valid values: json, console")
+	rootCmd.PersistentFlags().IntVar(&logSamplingInitial, "log-sampling-initial", 100, "log sampling initial entries per second (0 to disable sampling)")
+	rootCmd.PersistentFlags().IntVar(&logSamplingThereafter, "log-sampling-thereafter", 100, "log sampling every nth entry after the initial ones")
 	rootCmd.PersistentFlags().BoolVar(&enableTls, "enable-tls", true, "enable TLS")
 	rootCmd.PersistentFlags().StringVar(&certFile, "tls-certificate", "", "TLS certificate file")
 	rootCmd.PersistentFlags().StringVar(&keyFile, "tls-private-key", "", "TLS private key file")
@@ -164,13 +480,70 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&listenerAddress, "listener-address", "0.0.0.0:4880", "listener address")
 	rootCmd.PersistentFlags().DurationVar(&shutdownTimeout, "shutdown-timeout", 20*time.Second, "shutdown timeout")
 	rootCmd.PersistentFlags().DurationVar(&periodSyncInterval, "period-sync-interval", 5*time.Second, "period sync interval")
-	rootCmd.PersistentFlags().StringVar(&tfstateSourceSelector, "tfstate-source", "", "tfstate source, valid values: aws-s3")
+	rootCmd.PersistentFlags().DurationVar(&syncRetryInitialBackoff, "sync-retry-initial-backoff", time.Second, "initial backoff after a transient state sync failure")
+	rootCmd.PersistentFlags().DurationVar(&syncRetryMaxBackoff, "sync-retry-max-backoff", time.Minute, "maximum backoff between failed state syncs")
+	rootCmd.PersistentFlags().IntVar(&syncMaxConsecutiveFailures, "sync-max-consecutive-failures", 0, "consecutive transient state sync failures before exiting, permanent failures are not counted (0 to never exit)")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateSourceSelectors, "tfstate-source", []string{}, "tfstate sources in ascending precedence, valid values: aws-s3, file, terraform-http, terraform-cloud, git, config, kubernetes")
+	rootCmd.PersistentFlags().StringToStringVar(&stateVariables, "var", map[string]string{}, "variables available as ${var.NAME} in proto_json, taking precedence over the [vars] table of the config file")
+	rootCmd.PersistentFlags().StringVar(&collisionPolicy, "collision-policy", "error", "policy for resources defined more than once, in the same or in different tfstate sources, valid values: error, highest-wins, warn")
+	rootCmd.PersistentFlags().StringVar(&tfstateDirectory, "tfstate-dir", "", "directory containing tfstate files for the file tfstate source")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateFilePatterns, "tfstate-file-patterns", []string{"*.tfstate"}, "glob patterns selecting tfstate files for the file tfstate source")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateHttpAddresses, "tfstate-http-addresses", []string{}, "Terraform HTTP backend state addresses")
+	rootCmd.PersistentFlags().StringVar(&tfstateHttpUsername, "tfstate-http-username", "", "Terraform HTTP backend basic auth username")
+	rootCmd.PersistentFlags().StringVar(&tfstateHttpPassword, "tfstate-http-password", "", "Terraform HTTP backend basic auth password")
+	rootCmd.PersistentFlags().StringVar(&tfcAddress, "tfc-address", "https://app.terraform.io", "Terraform Cloud/Enterprise address")
+	rootCmd.PersistentFlags().StringVar(&tfcToken, "tfc-token", "", "Terraform Cloud/Enterprise API token")
+	rootCmd.PersistentFlags().StringVar(&tfcOrganization, "tfc-organization", "", "Terraform Cloud/Enterprise organization")
+	rootCmd.PersistentFlags().StringSliceVar(&tfcWorkspaces, "tfc-workspaces", []string{}, "Terraform Cloud/Enterprise workspaces")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitRepository, "tfstate-git-repository", "", "git repository (local path, file://, https:// or ssh) for the git tfstate source")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitRef, "tfstate-git-ref", "HEAD", "git branch, tag or commit to read tfstate files from")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitDirectory, "tfstate-git-dir", "", "directory within the git repository containing tfstate files")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateGitPatterns, "tfstate-git-patterns", git.DefaultPatterns, "glob patterns selecting tfstate files and yaml, json or textproto route and xDS definitions in the git repository")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitCloneDirectory, "tfstate-git-clone-dir", "", "directory to keep the git clone in (empty to clone in memory)")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitUsername, "tfstate-git-username", "", "git HTTPS basic auth username")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitPassword, "tfstate-git-password", "", "git HTTPS basic auth password or token")
+	rootCmd.PersistentFlags().StringVar(&tfstateGitSSHKeyFile, "tfstate-git-ssh-key", "", "SSH private key file for git repositories")
+	rootCmd.PersistentFlags().StringVar(&kubeConfig, "kube-config", "", "kubeconfig file for the kubernetes tfstate source (empty for in-cluster config)")
+	rootCmd.PersistentFlags().StringVar(&kubeNamespace, "kube-namespace", "", "namespace watched by the kubernetes tfstate source (empty for all namespaces)")
+	rootCmd.PersistentFlags().StringVar(&kubeLabelSelector, "kube-label-selector", kubernetes.DefaultLabelSelector, "label selector for ConfigMaps and CruiserRoutes")
+	rootCmd.PersistentFlags().BoolVar(&kubeCruiserRoutes, "kube-cruiser-routes", false, "also watch CruiserRoute custom resources")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateConfigFiles, "tfstate-config-files", []string{}, "cruiser config files (.yaml, .json or .textproto) for the config tfstate source")
 	rootCmd.PersistentFlags().StringVar(&dynamodbEndpoint, "dynamodb-endpoint", "", "DynamoDB endpoint")
 	rootCmd.PersistentFlags().StringVar(&awsTfstateBucket, "aws-tfstate-bucket", "", "AWS tfstate bucket")
 	rootCmd.PersistentFlags().DurationVar(&healthCheckInterval, "health-check-interval", 0, "health check interval (0 to disable)")
 	rootCmd.PersistentFlags().IntVar(&healthCheckParallelism, "health-check-parallelism", 4, "health check parallelism")
+	rootCmd.PersistentFlags().BoolVar(&lambdaLogTail, "lambda-log-tail", false, "request the Lambda log tail on sampled invocations to record the billed duration")
 	rootCmd.PersistentFlags().StringVar(&awsS3AssumeRole, "aws-s3-assume-role", "", "AWS S3 assume role")
-
+	rootCmd.PersistentFlags().StringVar(&s3Endpoint, "s3-endpoint", "", "S3 endpoint")
+	rootCmd.PersistentFlags().StringVar(&sqsEndpoint, "sqs-endpoint", "", "SQS endpoint")
+	rootCmd.PersistentFlags().StringVar(&kmsEndpoint, "kms-endpoint", "", "KMS endpoint")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateAgeIdentityFiles, "tfstate-age-identity-files", []string{}, "age identity files used to decrypt tfstate, all identities are tried")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateKMS, "tfstate-kms", []string{}, "kms used to unwrap tfstate data keys, tried in order, valid values: aws, local")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateKMSLocalKeyFiles, "tfstate-kms-local-key-files", []string{}, "base64 encoded 32 byte keys for the local kms, the key id is the file name without extension")
+	rootCmd.PersistentFlags().StringSliceVar(&tfstateTrustedKeys, "tfstate-trusted-keys", []string{}, "PEM encoded ed25519 or ECDSA public keys trusted to sign tfstate, unsigned tfstate is rejected when set")
+	rootCmd.PersistentFlags().BoolVar(&tfstateRequireEncryption, "tfstate-require-encryption", false, "reject tfstate that is not encrypted")
+	rootCmd.PersistentFlags().StringVar(&awsTfstateQueueUrl, "aws-tfstate-queue-url", "", "SQS queue URL receiving S3 event notifications for the tfstate bucket (empty to poll)")
+	rootCmd.PersistentFlags().StringSliceVar(&awsTfstatePrefixes, "aws-tfstate-prefixes", []string{""}, "S3 key prefixes to scan for tfstate files")
+	rootCmd.PersistentFlags().StringSliceVar(&awsTfstateInclude, "aws-tfstate-include", []string{"*.tfstate"}, "glob patterns selecting tfstate object keys")
+	rootCmd.PersistentFlags().StringSliceVar(&awsTfstateExclude, "aws-tfstate-exclude", []string{}, "glob patterns excluding tfstate object keys")
+	rootCmd.PersistentFlags().StringSliceVar(&awsTfstateWorkspaces, "aws-tfstate-workspaces", []string{}, "Terraform workspaces to load (empty for all)")
+	rootCmd.PersistentFlags().StringVar(&awsTfstateWorkspaceKeyPrefix, "aws-tfstate-workspace-key-prefix", "env:", "Terraform S3 backend workspace key prefix")
+	rootCmd.PersistentFlags().IntVar(&awsTfstateDownloadConcurrency, "aws-tfstate-download-concurrency", 8, "maximum concurrent tfstate downloads")
+	rootCmd.PersistentFlags().DurationVar(&awsTfstateReconcileInterval, "aws-tfstate-reconcile-interval", 5*time.Minute, "full tfstate bucket reconciliation interval in watch mode")
+	rootCmd.PersistentFlags().StringVar(&requestIdHeader, "request-id-header", server.DefaultRequestIdHeader, "request ID header")
+	rootCmd.PersistentFlags().BoolVar(&trustRequestIdHeader, "trust-request-id-header", false, "accept request IDs from the inbound request ID header")
+	rootCmd.PersistentFlags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP gRPC endpoint for traces (empty to disable tracing)")
+	rootCmd.PersistentFlags().BoolVar(&otlpInsecure, "otlp-insecure", false, "disable TLS for the OTLP endpoint")
+	rootCmd.PersistentFlags().Float64Var(&traceSampleRatio, "trace-sample-ratio", 1, "trace sampling ratio")
+	rootCmd.PersistentFlags().StringVar(&webhookURL, "webhook-url", "", "webhook URL notified on configuration changes")
+	rootCmd.PersistentFlags().StringVar(&webhookSecret, "webhook-secret", "", "webhook HMAC signing secret")
+	rootCmd.PersistentFlags().IntVar(&webhookQueueSize, "webhook-queue-size", 64, "webhook delivery queue size")
+	rootCmd.PersistentFlags().IntVar(&webhookMaxRetries, "webhook-max-retries", 5, "webhook delivery max retries")
+
+	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("log_format", rootCmd.PersistentFlags().Lookup("log-format"))
+	viper.BindPFlag("log_sampling_initial", rootCmd.PersistentFlags().Lookup("log-sampling-initial"))
+	viper.BindPFlag("log_sampling_thereafter", rootCmd.PersistentFlags().Lookup("log-sampling-thereafter"))
 	viper.BindPFlag("enable_tls", rootCmd.PersistentFlags().Lookup("enable-tls"))
 	viper.BindPFlag("tls_certificate", rootCmd.PersistentFlags().Lookup("tls-certificate"))
 	viper.BindPFlag("tls_private_key", rootCmd.PersistentFlags().Lookup("tls-private-key"))
@@ -178,19 +551,76 @@ func init() {
 	viper.BindPFlag("listener_address", rootCmd.PersistentFlags().Lookup("listener-address"))
 	viper.BindPFlag("shutdown_timeout", rootCmd.PersistentFlags().Lookup("shutdown-timeout"))
 	viper.BindPFlag("period_sync_interval", rootCmd.PersistentFlags().Lookup("period-sync-interval"))
+	viper.BindPFlag("sync_retry_initial_backoff", rootCmd.PersistentFlags().Lookup("sync-retry-initial-backoff"))
+	viper.BindPFlag("sync_retry_max_backoff", rootCmd.PersistentFlags().Lookup("sync-retry-max-backoff"))
+	viper.BindPFlag("sync_max_consecutive_failures", rootCmd.PersistentFlags().Lookup("sync-max-consecutive-failures"))
 	viper.BindPFlag("tfstate_source", rootCmd.PersistentFlags().Lookup("tfstate-source"))
+	viper.BindPFlag("collision_policy", rootCmd.PersistentFlags().Lookup("collision-policy"))
+	viper.BindPFlag("tfstate_dir", rootCmd.PersistentFlags().Lookup("tfstate-dir"))
+	viper.BindPFlag("tfstate_file_patterns", rootCmd.PersistentFlags().Lookup("tfstate-file-patterns"))
+	viper.BindPFlag("tfstate_http_addresses", rootCmd.PersistentFlags().Lookup("tfstate-http-addresses"))
+	viper.BindPFlag("tfstate_http_username", rootCmd.PersistentFlags().Lookup("tfstate-http-username"))
+	viper.BindPFlag("tfstate_http_password", rootCmd.PersistentFlags().Lookup("tfstate-http-password"))
+	viper.BindPFlag("tfc_address", rootCmd.PersistentFlags().Lookup("tfc-address"))
+	viper.BindPFlag("tfc_token", rootCmd.PersistentFlags().Lookup("tfc-token"))
+	viper.BindPFlag("tfc_organization", rootCmd.PersistentFlags().Lookup("tfc-organization"))
+	viper.BindPFlag("tfc_workspaces", rootCmd.PersistentFlags().Lookup("tfc-workspaces"))
+	viper.BindPFlag("tfstate_git_repository", rootCmd.PersistentFlags().Lookup("tfstate-git-repository"))
+	viper.BindPFlag("tfstate_git_ref", rootCmd.PersistentFlags().Lookup("tfstate-git-ref"))
+	viper.BindPFlag("tfstate_git_dir", rootCmd.PersistentFlags().Lookup("tfstate-git-dir"))
+	viper.BindPFlag("tfstate_git_patterns", rootCmd.PersistentFlags().Lookup("tfstate-git-patterns"))
+	viper.BindPFlag("tfstate_git_clone_dir", rootCmd.PersistentFlags().Lookup("tfstate-git-clone-dir"))
+	viper.BindPFlag("tfstate_git_username", rootCmd.PersistentFlags().Lookup("tfstate-git-username"))
+	viper.BindPFlag("tfstate_git_password", rootCmd.PersistentFlags().Lookup("tfstate-git-password"))
+	viper.BindPFlag("tfstate_git_ssh_key", rootCmd.PersistentFlags().Lookup("tfstate-git-ssh-key"))
+	viper.BindPFlag("tfstate_config_files", rootCmd.PersistentFlags().Lookup("tfstate-config-files"))
+	viper.BindPFlag("kube_config", rootCmd.PersistentFlags().Lookup("kube-config"))
+	viper.BindPFlag("kube_namespace", rootCmd.PersistentFlags().Lookup("kube-namespace"))
+	viper.BindPFlag("kube_label_selector", rootCmd.PersistentFlags().Lookup("kube-label-selector"))
+	viper.BindPFlag("kube_cruiser_routes", rootCmd.PersistentFlags().Lookup("kube-cruiser-routes"))
 	viper.BindPFlag("dynamodb_endpoint", rootCmd.PersistentFlags().Lookup("dynamodb-endpoint"))
 	viper.BindPFlag("aws_tfstate_bucket", rootCmd.PersistentFlags().Lookup("aws-tfstate-bucket"))
 	viper.BindPFlag("health_check_interval", rootCmd.PersistentFlags().Lookup("health-check-interval"))
 	viper.BindPFlag("health_check_parallelism", rootCmd.PersistentFlags().Lookup("health-check-parallelism"))
+	viper.BindPFlag("lambda_log_tail", rootCmd.PersistentFlags().Lookup("lambda-log-tail"))
 	viper.BindPFlag("aws_s3_assume_role", rootCmd.PersistentFlags().Lookup("aws-s3-assume-role"))
-
+	viper.BindPFlag("s3_endpoint", rootCmd.PersistentFlags().Lookup("s3-endpoint"))
+	viper.BindPFlag("sqs_endpoint", rootCmd.PersistentFlags().Lookup("sqs-endpoint"))
+	viper.BindPFlag("kms_endpoint", rootCmd.PersistentFlags().Lookup("kms-endpoint"))
+	viper.BindPFlag("tfstate_age_identity_files", rootCmd.PersistentFlags().Lookup("tfstate-age-identity-files"))
+	viper.BindPFlag("tfstate_kms", rootCmd.PersistentFlags().Lookup("tfstate-kms"))
+	viper.BindPFlag("tfstate_kms_local_key_files", rootCmd.PersistentFlags().Lookup("tfstate-kms-local-key-files"))
+	viper.BindPFlag("tfstate_trusted_keys", rootCmd.PersistentFlags().Lookup("tfstate-trusted-keys"))
+	viper.BindPFlag("tfstate_require_encryption", rootCmd.PersistentFlags().Lookup("tfstate-require-encryption"))
+	viper.BindPFlag("aws_tfstate_queue_url", rootCmd.PersistentFlags().Lookup("aws-tfstate-queue-url"))
+	viper.BindPFlag("aws_tfstate_reconcile_interval", rootCmd.PersistentFlags().Lookup("aws-tfstate-reconcile-interval"))
+	viper.BindPFlag("aws_tfstate_prefixes", rootCmd.PersistentFlags().Lookup("aws-tfstate-prefixes"))
+	viper.BindPFlag("aws_tfstate_include", rootCmd.PersistentFlags().Lookup("aws-tfstate-include"))
+	viper.BindPFlag("aws_tfstate_exclude", rootCmd.PersistentFlags().Lookup("aws-tfstate-exclude"))
+	viper.BindPFlag("aws_tfstate_workspaces", rootCmd.PersistentFlags().Lookup("aws-tfstate-workspaces"))
+	viper.BindPFlag("aws_tfstate_workspace_key_prefix", rootCmd.PersistentFlags().Lookup("aws-tfstate-workspace-key-prefix"))
+	viper.BindPFlag("aws_tfstate_download_concurrency", rootCmd.PersistentFlags().Lookup("aws-tfstate-download-concurrency"))
+	viper.BindPFlag("request_id_header", rootCmd.PersistentFlags().Lookup("request-id-header"))
+	viper.BindPFlag("trust_request_id_header", rootCmd.PersistentFlags().Lookup("trust-request-id-header"))
+	viper.BindPFlag("otlp_endpoint", rootCmd.PersistentFlags().Lookup("otlp-endpoint"))
+	viper.BindPFlag("otlp_insecure", rootCmd.PersistentFlags().Lookup("otlp-insecure"))
+	viper.BindPFlag("trace_sample_ratio", rootCmd.PersistentFlags().Lookup("trace-sample-ratio"))
+	viper.BindPFlag("webhook_url", rootCmd.PersistentFlags().Lookup("webhook-url"))
+	viper.BindPFlag("webhook_secret", rootCmd.PersistentFlags().Lookup("webhook-secret"))
+	viper.BindPFlag("webhook_queue_size", rootCmd.PersistentFlags().Lookup("webhook-queue-size"))
+	viper.BindPFlag("webhook_max_retries", rootCmd.PersistentFlags().Lookup("webhook-max-retries"))
+
+	initAdmin()
+	initSnapshot()
+	initRouter()
 	initXds()
+	initState()
 
 	routerCmd.AddCommand(routerStartCmd)
 	rootCmd.AddCommand(routerCmd)
 	xdsCmd.AddCommand(xdsStartCmd)
 	rootCmd.AddCommand(xdsCmd)
+	rootCmd.AddCommand(stateCmd)
 }
 
 func initConfig() {
@@ -220,14 +650,20 @@ func initConfig() {
 
 	}
 
-	observability.InitializeLog()
-
-	if err := viper.ReadInConfig(); err == nil {
-		observability.Log.Info("Using config file:", viper.ConfigFileUsed())
-	}
+	configErr := viper.ReadInConfig()
 
 	postInitCommands([]*cobra.Command{rootCmd})
 
+	cobra.CheckErr(observability.InitializeLog(
+		observability.WithLogLevel(logLevel),
+		observability.WithLogFormat(logFormat),
+		observability.WithLogSampling(logSamplingInitial, logSamplingThereafter),
+	))
+
+	if configErr == nil {
+		observability.Log.Infow("using config file", "file", viper.ConfigFileUsed())
+	}
+
 }
 
 func postInitCommands(commands []*cobra.Command) {
@@ -257,3 +693,81 @@ func presetRequiredFlags(cmd *cobra.Command) {
 
 	})
 }
+
+func parseCollisionPolicy(policy string) (state.CollisionPolicy, error) {
+
+	switch policy {
+
+	case "error":
+		return state.RejectCollisions, nil
+
+	case "highest-wins":
+		return state.HighestPrecedenceWins, nil
+
+	case "warn":
+		return state.WarnOnCollision, nil
+
+	}
+
+	return state.RejectCollisions, ErrInvalidCollisionPolicy
+
+}
+
+func newKMS(selector string) (encryption.KMS, error) {
+
+	switch selector {
+
+	case "aws":
+		return awskms.NewKMS(awsProvider.GetKMSClient()), nil
+
+	case "local":
+		return encryption.NewLocalKMS(
+			encryption.WithLocalKeyFiles(tfstateKMSLocalKeyFiles...),
+		)
+
+	}
+
+	return nil, ErrInvalidKMSSelector
+
+}
+
+// closeWebhookNotifier delivers the queued change notifications, bounded by
+// the shutdown timeout.
+func closeWebhookNotifier() error {
+
+	if webhookNotifier == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return webhookNotifier.Close(ctx)
+
+}
+
+func newTfstateDecrypter() (state.TfstateDecrypter, error) {
+
+	if len(tfstateAgeIdentityFiles) == 0 && len(tfstateKMS) == 0 && !tfstateRequireEncryption {
+		return nil, nil
+	}
+
+	opts := []encryption.DecrypterOption{
+		encryption.WithAgeIdentityFiles(tfstateAgeIdentityFiles...),
+		encryption.WithRequireEncryption(tfstateRequireEncryption),
+	}
+
+	for _, selector := range tfstateKMS {
+
+		kms, err := newKMS(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, encryption.WithDecryptionKMS(kms))
+
+	}
+
+	return encryption.NewDecrypter(opts...)
+
+}
@@ -1,20 +1,45 @@
 package cmd
 
 import (
+	"net/http"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/ultraviolet-black/cruiser/pkg/accesslog"
+	"github.com/ultraviolet-black/cruiser/pkg/admin"
 	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
 	"github.com/ultraviolet-black/cruiser/pkg/server"
+	"github.com/ultraviolet-black/cruiser/pkg/snapshot"
 	"github.com/ultraviolet-black/cruiser/pkg/state"
 )
 
 var (
+	accessLogSink       string
+	accessLogFormat     string
+	accessLogFile       string
+	accessLogMaxSize    int64
+	accessLogMaxBackups int
+	accessLogBufferSize int
+
+	unavailableRetryAfter time.Duration
+	routerVersionHeader   string
+	maxStreamLifetime     time.Duration
+	invalidRoutePolicy    string
+
+	accessLogger accesslog.Logger
+
 	routerServer server.Server
 
 	routerHandler server.SwapHandler
 
+	// Set once a router built from a state sync, rather than from a restored
+	// snapshot, is installed.
+	routerSynced atomic.Bool
+
 	routesState state.RoutesState
 
 	routerCmd = &cobra.Command{
@@ -29,21 +54,65 @@ var (
 				return err
 			}
 
-			routerHandler = server.NewSwapHandler()
+			routerHandler = server.NewSwapHandler(
+				server.WithDefaultHandler(server.NewUnavailableHandler(unavailableRetryAfter)),
+				server.WithRouterVersionHeader(routerVersionHeader),
+				server.WithMaxStreamLifetime(maxStreamLifetime),
+				server.WithSwapHandlerLogger(observability.Log.With("component", "router")),
+			)
+
+			var handler http.Handler = server.NewMetricsHandler(
+				server.NewTracingHandler(routerHandler),
+			)
+
+			logger, err := newAccessLogger()
+			if err != nil {
+				return err
+			}
+
+			if logger != nil {
+				accessLogger = logger
+				handler = server.NewAccessLogHandler(handler, accessLogger)
+			}
 
 			routerServer = server.NewServer(
 				server.WithListenerAddress(listenerAddress),
 				server.WithShutdownTimeout(shutdownTimeout),
 				server.WithListenerProtocol(listenerProtocol),
-				server.WithHTTPHandler(routerHandler),
+				server.WithHTTPHandler(
+					server.NewRequestIdHandler(
+						handler,
+						server.WithRequestIdHeader(requestIdHeader),
+						server.WithTrustedRequestIdHeader(trustRequestIdHeader),
+					),
+				),
 				server.WithTLSConfig(tlsContext),
 			)
 
-			routesState = state.NewRoutesState()
+			routePolicy, err := parseInvalidRoutePolicy(invalidRoutePolicy)
+			if err != nil {
+				return err
+			}
+
+			policy, err := parseCollisionPolicy(collisionPolicy)
+			if err != nil {
+				return err
+			}
+
+			routesState = state.NewRoutesState(
+				state.WithRoutesChangeNotifiers(changeNotifiers...),
+				state.WithInvalidRoutePolicy(routePolicy),
+				state.WithRoutesCollisionPolicy(policy),
+				state.WithRoutesLogger(observability.Log.With("component", "routes")),
+			)
 
 			stateManager = state.NewStateManager(
-				state.WithTfstateSource(tfstateSource),
+				state.WithTfstateOrigins(tfstateOrigins...),
+				state.WithVariables(configVariables),
+				state.WithVariables(stateVariables),
 				state.WithPeriodicSyncInterval(periodSyncInterval),
+				state.WithRetryBackoff(syncRetryInitialBackoff, syncRetryMaxBackoff),
+				state.WithMaxConsecutiveFailures(syncMaxConsecutiveFailures),
 				state.WithManagers(routesState),
 			)
 
@@ -57,15 +126,38 @@ var (
 		Short: "Start the router server",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
-			go stateManager.Start(cmd.Context())
+			if webhookNotifier != nil {
+				webhookNotifier.Start(cmd.Context())
+			}
 
-			go func() {
+			routerOpts := []server.RouterOption{}
 
-				routerOpts := []server.RouterOption{}
+			for _, backendProvider := range backendProviders {
+				routerOpts = append(routerOpts, server.WithBackendProvider(backendProvider))
+			}
 
-				for _, backendProvider := range backendProviders {
-					routerOpts = append(routerOpts, server.WithBackendProvider(backendProvider))
-				}
+			if err := openSnapshotStore("router"); err != nil {
+				return err
+			}
+
+			if snap := loadSnapshot(); snap != nil && snap.Router != nil {
+
+				router := server.NewRouter(
+					append(
+						routerOpts,
+						server.WithRouterConfig(snap.Router),
+					)...,
+				)
+
+				router.DoHealthcheck(cmd.Context())
+
+				routerHandler.Swap(router)
+
+			}
+
+			go stateManager.Start(cmd.Context())
+
+			go func() {
 
 				for {
 					select {
@@ -88,9 +180,9 @@ var (
 
 						routes, err := routesState.GetRoutes()
 						if err != nil {
-							observability.Log.Error(err.Error())
-							signalCh <- os.Kill
-							return
+							observability.Log.Errorw("error getting routes, keeping current router", "error", err)
+							stateManager.MarkDegraded(err)
+							continue
 						}
 
 						routerConfig := &serverpb.Router{
@@ -108,6 +200,12 @@ var (
 
 						routerHandler.Swap(router)
 
+						routerSynced.Store(true)
+
+						saveSnapshot(&snapshot.Snapshot{
+							Router: routerConfig,
+						})
+
 					}
 				}
 			}()
@@ -116,6 +214,14 @@ var (
 				return err
 			}
 
+			if err := openAdminServer(
+				admin.WithRoutesState(routesState),
+				admin.WithStateManager(stateManager),
+				admin.WithReadinessCheck(routerReadinessCheck),
+			); err != nil {
+				return err
+			}
+
 			waitClose.Wait()
 
 			return nil
@@ -125,8 +231,138 @@ var (
 
 			routerHandler.Close()
 
-			return routerServer.Close()
+			if err := closeAdminServer(); err != nil {
+				return err
+			}
+
+			if err := closeWebhookNotifier(); err != nil {
+				return err
+			}
+
+			if err := routerServer.Close(); err != nil {
+				return err
+			}
+
+			if accessLogger != nil {
+				return accessLogger.Close()
+			}
+
+			return nil
 
 		},
 	}
 )
+
+func parseInvalidRoutePolicy(policy string) (state.InvalidRoutePolicy, error) {
+
+	switch policy {
+
+	case "reject":
+		return state.RejectInvalidRoutes, nil
+
+	case "skip":
+		return state.SkipInvalidRoutes, nil
+
+	}
+
+	return state.RejectInvalidRoutes, ErrInvalidRoutePolicy
+
+}
+
+func routerReadinessCheck() error {
+
+	if !routerHandler.Ready() {
+		return ErrRouterNotReady
+	}
+
+	if !routerSynced.Load() {
+
+		if restoredSnapshot != nil {
+			return ErrServingSnapshot
+		}
+
+		return ErrRouterNotReady
+
+	}
+
+	return configFreshnessCheck()
+
+}
+
+func newAccessLogger() (accesslog.Logger, error) {
+
+	var sink accesslog.Sink
+
+	switch accessLogSink {
+
+	case "":
+		return nil, nil
+
+	case "stdout":
+		sink = accesslog.NewStdoutSink()
+
+	case "file":
+
+		if len(accessLogFile) == 0 {
+			return nil, ErrEmptyAccessLogFile
+		}
+
+		fileSink, err := accesslog.NewRotatingFileSink(
+			accessLogFile,
+			accesslog.WithMaxSize(accessLogMaxSize*1024*1024),
+			accesslog.WithMaxBackups(accessLogMaxBackups),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sink = fileSink
+
+	default:
+		return nil, ErrInvalidAccessLogSink
+
+	}
+
+	format, err := accesslog.ParseFormat(accessLogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	if accessLogBufferSize > 0 {
+		sink = accesslog.NewAsyncSink(sink, accessLogBufferSize, accesslog.WithSinkErrorLog(observability.Log.With("component", "accesslog")))
+	}
+
+	return accesslog.NewLogger(
+		accesslog.WithFormat(format),
+		accesslog.WithSink(sink),
+		accesslog.WithErrorLog(observability.Log.With("component", "accesslog")),
+	), nil
+
+}
+
+func initRouter() {
+
+	routerCmd.PersistentFlags().StringVar(&accessLogSink, "access-log", "", "access log sink, valid values: stdout, file (empty to disable)")
+	routerCmd.PersistentFlags().StringVar(&accessLogFormat, "access-log-format", "json", "access log format, valid values: json, combined, logfmt")
+	routerCmd.PersistentFlags().StringVar(&accessLogFile, "access-log-file", "", "access log file path")
+	routerCmd.PersistentFlags().Int64Var(&accessLogMaxSize, "access-log-max-size", 100, "access log file size in megabytes before rotation")
+	routerCmd.PersistentFlags().IntVar(&accessLogMaxBackups, "access-log-max-backups", 5, "number of rotated access log files to keep")
+	routerCmd.PersistentFlags().IntVar(&accessLogBufferSize, "access-log-buffer-size", 0, "access log async buffer size (0 to write synchronously)")
+
+	routerCmd.PersistentFlags().DurationVar(&unavailableRetryAfter, "unavailable-retry-after", 5*time.Second, "Retry-After sent with 503 responses until the first router is installed")
+	routerCmd.PersistentFlags().StringVar(&invalidRoutePolicy, "invalid-route-policy", "reject", "how to handle routes with missing parents or cycles, valid values: reject, skip")
+	routerCmd.PersistentFlags().StringVar(&routerVersionHeader, "router-version-header", server.DefaultRouterVersionHeader, "response header carrying the router generation version (empty to disable)")
+	routerCmd.PersistentFlags().DurationVar(&maxStreamLifetime, "max-stream-lifetime", 10*time.Minute, "maximum time requests may keep running on a replaced router generation (0 to wait indefinitely)")
+
+	viper.BindPFlag("access_log", routerCmd.PersistentFlags().Lookup("access-log"))
+	viper.BindPFlag("access_log_format", routerCmd.PersistentFlags().Lookup("access-log-format"))
+	viper.BindPFlag("access_log_file", routerCmd.PersistentFlags().Lookup("access-log-file"))
+	viper.BindPFlag("access_log_max_size", routerCmd.PersistentFlags().Lookup("access-log-max-size"))
+	viper.BindPFlag("access_log_max_backups", routerCmd.PersistentFlags().Lookup("access-log-max-backups"))
+	viper.BindPFlag("access_log_buffer_size", routerCmd.PersistentFlags().Lookup("access-log-buffer-size"))
+	viper.BindPFlag("unavailable_retry_after", routerCmd.PersistentFlags().Lookup("unavailable-retry-after"))
+	viper.BindPFlag("invalid_route_policy", routerCmd.PersistentFlags().Lookup("invalid-route-policy"))
+	viper.BindPFlag("router_version_header", routerCmd.PersistentFlags().Lookup("router-version-header"))
+	viper.BindPFlag("max_stream_lifetime", routerCmd.PersistentFlags().Lookup("max-stream-lifetime"))
+
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/ultraviolet-black/cruiser/pkg/admin"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"github.com/ultraviolet-black/cruiser/pkg/snapshot"
+)
+
+var (
+	snapshotDirectory    string
+	snapshotMaxStaleness time.Duration
+	snapshotRetain       int
+
+	snapshotStore snapshot.Store
+
+	restoredSnapshot *snapshot.Snapshot
+)
+
+func openSnapshotStore(prefix string) error {
+
+	if len(snapshotDirectory) == 0 {
+		return nil
+	}
+
+	store, err := snapshot.NewStore(
+		snapshot.WithDirectory(snapshotDirectory),
+		snapshot.WithPrefix(prefix),
+		snapshot.WithMaxSnapshots(snapshotRetain),
+		snapshot.WithLogger(observability.Log.With("component", "snapshot")),
+	)
+	if err != nil {
+		return err
+	}
+
+	snapshotStore = store
+
+	return nil
+
+}
+
+func loadSnapshot() *snapshot.Snapshot {
+
+	if snapshotStore == nil {
+		return nil
+	}
+
+	snap, err := snapshotStore.Latest()
+	if err != nil {
+		if !errors.Is(err, snapshot.ErrNoSnapshot) {
+			observability.Log.Warnw("error loading snapshot", "error", err)
+		}
+		return nil
+	}
+
+	if snapshotMaxStaleness > 0 && snap.Age() > snapshotMaxStaleness {
+		observability.Log.Warnw("ignoring stale snapshot", "sequence", snap.Sequence, "age", snap.Age())
+		return nil
+	}
+
+	observability.Log.Infow("restoring snapshot", "sequence", snap.Sequence, "age", snap.Age())
+
+	observability.SetSnapshotTimestamp(snap.CreatedAt)
+
+	restoredSnapshot = snap
+
+	return snap
+
+}
+
+func saveSnapshot(snap *snapshot.Snapshot) {
+
+	if snapshotStore == nil {
+		return
+	}
+
+	if err := snapshotStore.Save(snap); err != nil {
+		observability.Log.Errorw("error saving snapshot", "error", err)
+	}
+
+}
+
+func configFreshnessCheck() error {
+
+	// A restored snapshot serves traffic but does not make the instance ready,
+	// it may be arbitrarily older than the sources.
+	lastUpdate := stateManager.Status().LastSuccessfulSyncTime
+
+	if lastUpdate.IsZero() {
+
+		if restoredSnapshot != nil {
+			return ErrServingSnapshot
+		}
+
+		return admin.ErrNoSuccessfulSync
+
+	}
+
+	if snapshotMaxStaleness > 0 && time.Since(lastUpdate) > snapshotMaxStaleness {
+		return ErrConfigTooStale
+	}
+
+	return nil
+
+}
+
+func initSnapshot() {
+
+	rootCmd.PersistentFlags().StringVar(&snapshotDirectory, "snapshot-dir", "", "directory where configuration snapshots are persisted (empty to disable)")
+	rootCmd.PersistentFlags().DurationVar(&snapshotMaxStaleness, "snapshot-max-staleness", 0, "maximum age of the served configuration before readiness fails and snapshots are ignored (0 to disable)")
+	rootCmd.PersistentFlags().IntVar(&snapshotRetain, "snapshot-retain", 5, "number of snapshots to keep on disk")
+
+	viper.BindPFlag("snapshot_dir", rootCmd.PersistentFlags().Lookup("snapshot-dir"))
+	viper.BindPFlag("snapshot_max_staleness", rootCmd.PersistentFlags().Lookup("snapshot-max-staleness"))
+	viper.BindPFlag("snapshot_retain", rootCmd.PersistentFlags().Lookup("snapshot-retain"))
+
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ultraviolet-black/cruiser/pkg/config"
+	"github.com/ultraviolet-black/cruiser/pkg/encryption"
+	"github.com/ultraviolet-black/cruiser/pkg/signature"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+var (
+	stateExportFormat string
+	stateExportOutput string
+
+	stateEncryptRecipients     []string
+	stateEncryptRecipientFiles []string
+	stateEncryptArmor          bool
+	stateEncryptKMS            string
+	stateEncryptKMSKeyId       string
+	stateEncryptOutput         string
+
+	stateSignKey    string
+	stateSignInline bool
+	stateSignOutput string
+
+	stateCmd = &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and manage configuration state",
+	}
+
+	stateExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the effective configuration of the tfstate source as a cruiser config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if len(tfstateOrigins) == 0 {
+				return ErrNoTfstateSource
+			}
+
+			format, err := config.ParseFormat(stateExportFormat)
+			if err != nil {
+				return err
+			}
+
+			tfstates, err := state.NewMergedTfstateSource(tfstateOrigins...).GetTfstate(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.FromTfstates(tfstates...)
+			if err != nil {
+				return err
+			}
+
+			out, err := config.Marshal(cfg, format)
+			if err != nil {
+				return err
+			}
+
+			return writeStateOutput(cmd, stateExportOutput, out)
+
+		},
+	}
+
+	stateEncryptCmd = &cobra.Command{
+		Use:   "encrypt [file]",
+		Short: "Encrypt a tfstate file for client-side decryption by the tfstate sources",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			recipients, err := encryption.ParseAgeRecipients(stateEncryptRecipients...)
+			if err != nil {
+				return err
+			}
+
+			opts := []encryption.EncrypterOption{
+				encryption.WithAgeRecipients(recipients...),
+				encryption.WithAgeRecipientFiles(stateEncryptRecipientFiles...),
+				encryption.WithArmor(stateEncryptArmor),
+			}
+
+			if len(stateEncryptKMSKeyId) > 0 {
+
+				kms, err := newKMS(stateEncryptKMS)
+				if err != nil {
+					return err
+				}
+
+				opts = append(opts, encryption.WithEncryptionKMS(kms, stateEncryptKMSKeyId))
+
+			}
+
+			encrypter, err := encryption.NewEncrypter(opts...)
+			if err != nil {
+				return err
+			}
+
+			var plaintext []byte
+
+			if len(args) == 0 || args[0] == "-" {
+				plaintext, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				plaintext, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+
+			tfstate := &state.Tfstate{}
+
+			if err := json.Unmarshal(plaintext, tfstate); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidTfstate, err)
+			}
+
+			if tfstate.Version == 0 {
+				return ErrInvalidTfstate
+			}
+
+			out, err := encrypter.Encrypt(cmd.Context(), plaintext)
+			if err != nil {
+				return err
+			}
+
+			return writeStateOutput(cmd, stateEncryptOutput, out)
+
+		},
+	}
+
+	stateSignCmd = &cobra.Command{
+		Use:   "sign [file]",
+		Short: "Sign a tfstate file, writing a sidecar .sig or an inline signed object",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			signer, err := signature.NewSigner(
+				signature.WithPrivateKeyFile(stateSignKey),
+			)
+			if err != nil {
+				return err
+			}
+
+			var payload []byte
+
+			if len(args) == 0 || args[0] == "-" {
+				payload, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				payload, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+
+			if stateSignInline {
+
+				out, err := signer.SignInline(payload)
+				if err != nil {
+					return err
+				}
+
+				return writeStateOutput(cmd, stateSignOutput, out)
+
+			}
+
+			out, err := signer.Sign(payload)
+			if err != nil {
+				return err
+			}
+
+			output := stateSignOutput
+
+			if len(output) == 0 && len(args) > 0 && args[0] != "-" {
+				output = args[0] + state.TfstateSignatureSuffix
+			}
+
+			return writeStateOutput(cmd, output, out)
+
+		},
+	}
+)
+
+func writeStateOutput(cmd *cobra.Command, output string, out []byte) error {
+
+	if len(output) == 0 || output == "-" {
+		_, err := cmd.OutOrStdout().Write(out)
+		return err
+	}
+
+	return os.WriteFile(output, out, 0644)
+
+}
+
+func initState() {
+
+	stateExportCmd.Flags().StringVar(&stateExportFormat, "format", "yaml", "output format, valid values: yaml, json, textproto")
+	stateExportCmd.Flags().StringVarP(&stateExportOutput, "output", "o", "", "output file (empty or - for stdout)")
+
+	stateEncryptCmd.Flags().StringSliceVar(&stateEncryptRecipients, "age-recipients", []string{}, "age X25519 recipients (age1...)")
+	stateEncryptCmd.Flags().StringSliceVar(&stateEncryptRecipientFiles, "age-recipient-files", []string{}, "files with age recipients, one per line")
+	stateEncryptCmd.Flags().BoolVar(&stateEncryptArmor, "armor", false, "PEM encode the age encrypted output")
+	stateEncryptCmd.Flags().StringVar(&stateEncryptKMS, "kms", "local", "kms used to wrap the data key, valid values: aws, local")
+	stateEncryptCmd.Flags().StringVar(&stateEncryptKMSKeyId, "kms-key-id", "", "kms key id used to wrap an AES-GCM data key instead of age encryption")
+	stateEncryptCmd.Flags().StringVarP(&stateEncryptOutput, "output", "o", "", "output file (empty or - for stdout)")
+
+	stateCmd.AddCommand(stateExportCmd)
+	stateSignCmd.Flags().StringVar(&stateSignKey, "key", "", "PEM encoded ed25519 or ECDSA private key")
+	stateSignCmd.Flags().BoolVar(&stateSignInline, "inline", false, "embed the tfstate in the signed object instead of writing a sidecar signature")
+	stateSignCmd.Flags().StringVarP(&stateSignOutput, "output", "o", "", "output file (defaults to <file>.sig for sidecar signatures, - for stdout)")
+
+	stateCmd.AddCommand(stateEncryptCmd)
+	stateCmd.AddCommand(stateSignCmd)
+
+}
@@ -5,9 +5,11 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/ultraviolet-black/cruiser/pkg/admin"
 	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	servicediscovery "github.com/ultraviolet-black/cruiser/pkg/providers/aws/service_discovery"
 	"github.com/ultraviolet-black/cruiser/pkg/server"
+	"github.com/ultraviolet-black/cruiser/pkg/snapshot"
 	"github.com/ultraviolet-black/cruiser/pkg/state"
 	"google.golang.org/grpc"
 )
@@ -45,11 +47,24 @@ var (
 				server.WithTLSConfig(tlsContext),
 			)
 
-			xdsState = state.NewXdsState()
+			policy, err := parseCollisionPolicy(collisionPolicy)
+			if err != nil {
+				return err
+			}
+
+			xdsState = state.NewXdsState(
+				state.WithXdsChangeNotifiers(changeNotifiers...),
+				state.WithXdsCollisionPolicy(policy),
+				state.WithXdsLogger(observability.Log.With("component", "xds")),
+			)
 
 			stateManager = state.NewStateManager(
-				state.WithTfstateSource(tfstateSource),
+				state.WithTfstateOrigins(tfstateOrigins...),
+				state.WithVariables(configVariables),
+				state.WithVariables(stateVariables),
 				state.WithPeriodicSyncInterval(periodSyncInterval),
+				state.WithRetryBackoff(syncRetryInitialBackoff, syncRetryMaxBackoff),
+				state.WithMaxConsecutiveFailures(syncMaxConsecutiveFailures),
 				state.WithManagers(xdsState),
 			)
 
@@ -92,6 +107,18 @@ var (
 
 			xdsState.Register(cmd.Context(), xdsGrpcServer)
 
+			if webhookNotifier != nil {
+				webhookNotifier.Start(cmd.Context())
+			}
+
+			if err := openSnapshotStore("xds"); err != nil {
+				return err
+			}
+
+			if snap := loadSnapshot(); snap != nil && len(snap.Xds) > 0 {
+				xdsState.Restore(snap.Xds)
+			}
+
 			go stateManager.Start(cmd.Context())
 
 			go func() {
@@ -110,10 +137,14 @@ var (
 						signalCh <- os.Kill
 						return
 
-					case <-xdsState.UpdateCh():
+					case xdsState := <-xdsState.UpdateCh():
 
 						observability.Log.Debug("xDS update received")
 
+						saveSnapshot(&snapshot.Snapshot{
+							Xds: xdsState.GetResources(),
+						})
+
 					}
 				}
 			}()
@@ -122,6 +153,14 @@ var (
 				return err
 			}
 
+			if err := openAdminServer(
+				admin.WithXdsState(xdsState),
+				admin.WithStateManager(stateManager),
+				admin.WithReadinessCheck(configFreshnessCheck),
+			); err != nil {
+				return err
+			}
+
 			waitClose.Wait()
 
 			return nil
@@ -130,6 +169,14 @@ var (
 
 			xdsGrpcServer.GracefulStop()
 
+			if err := closeAdminServer(); err != nil {
+				return err
+			}
+
+			if err := closeWebhookNotifier(); err != nil {
+				return err
+			}
+
 			return xdsServer.Close()
 
 		},
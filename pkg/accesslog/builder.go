@@ -0,0 +1,115 @@
+package accesslog
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+func NewStdoutSink() Sink {
+	return stdoutSink{}
+}
+
+type RotatingFileSinkOption func(*rotatingFileSink)
+
+func WithMaxSize(maxSize int64) RotatingFileSinkOption {
+	return func(s *rotatingFileSink) {
+		s.maxSize = maxSize
+	}
+}
+
+func WithMaxBackups(maxBackups int) RotatingFileSinkOption {
+	return func(s *rotatingFileSink) {
+		s.maxBackups = maxBackups
+	}
+}
+
+func NewRotatingFileSink(path string, opts ...RotatingFileSinkOption) (Sink, error) {
+
+	s := &rotatingFileSink{
+		path:       path,
+		maxSize:    100 * 1024 * 1024,
+		maxBackups: 5,
+		lock:       new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+
+}
+
+type AsyncSinkOption func(*asyncSink)
+
+func WithSinkErrorLog(log *zap.SugaredLogger) AsyncSinkOption {
+	return func(s *asyncSink) {
+		s.errorLog = log
+	}
+}
+
+func NewAsyncSink(sink Sink, bufferSize int, opts ...AsyncSinkOption) Sink {
+
+	s := &asyncSink{
+		sink:      sink,
+		entriesCh: make(chan []byte, bufferSize),
+		doneCh:    make(chan struct{}),
+		lock:      new(sync.RWMutex),
+		errorLog:  zap.NewNop().Sugar(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.run()
+
+	return s
+
+}
+
+type LoggerOption func(*logger)
+
+func WithFormat(format Format) LoggerOption {
+	return func(l *logger) {
+		l.format = format
+	}
+}
+
+func WithSink(sink Sink) LoggerOption {
+	return func(l *logger) {
+		l.sink = sink
+	}
+}
+
+func WithErrorLog(log *zap.SugaredLogger) LoggerOption {
+	return func(l *logger) {
+		l.errorLog = log
+	}
+}
+
+type Logger interface {
+	Log(*Entry)
+	Close() error
+}
+
+func NewLogger(opts ...LoggerOption) Logger {
+
+	l := &logger{
+		format:   JSON,
+		sink:     NewStdoutSink(),
+		errorLog: zap.NewNop().Sugar(),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+
+}
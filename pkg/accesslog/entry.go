@@ -0,0 +1,67 @@
+package accesslog
+
+import (
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+type Entry struct {
+	Timestamp         time.Time `json:"timestamp"`
+	RequestId         string    `json:"request_id"`
+	Route             string    `json:"route"`
+	Backend           string    `json:"backend"`
+	Method            string    `json:"method"`
+	Path              string    `json:"path"`
+	Query             string    `json:"query"`
+	Protocol          string    `json:"protocol"`
+	Status            int       `json:"status"`
+	GrpcCode          string    `json:"grpc_code,omitempty"`
+	BytesIn           int64     `json:"bytes_in"`
+	BytesOut          int64     `json:"bytes_out"`
+	Duration          float64   `json:"duration_ms"`
+	UpstreamLatency   float64   `json:"upstream_latency_ms"`
+	UpstreamRequestId string    `json:"lambda_request_id"`
+	ClientIp          string    `json:"client_ip"`
+	UserAgent         string    `json:"user_agent"`
+	Referer           string    `json:"referer"`
+}
+
+func (e *Entry) Redact(fields ...string) {
+
+	for _, field := range fields {
+
+		switch field {
+
+		case "request_id":
+			e.RequestId = redacted
+
+		case "route":
+			e.Route = redacted
+
+		case "backend":
+			e.Backend = redacted
+
+		case "path":
+			e.Path = redacted
+
+		case "query":
+			e.Query = redacted
+
+		case "lambda_request_id":
+			e.UpstreamRequestId = redacted
+
+		case "client_ip":
+			e.ClientIp = redacted
+
+		case "user_agent":
+			e.UserAgent = redacted
+
+		case "referer":
+			e.Referer = redacted
+
+		}
+
+	}
+
+}
@@ -0,0 +1,9 @@
+package accesslog
+
+import "errors"
+
+var (
+	ErrInvalidFormat = errors.New("invalid access log format")
+	ErrBufferFull    = errors.New("access log buffer full")
+	ErrSinkClosed    = errors.New("access log sink closed")
+)
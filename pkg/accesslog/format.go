@@ -0,0 +1,139 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Format int
+
+const (
+	JSON Format = iota
+	Combined
+	Logfmt
+)
+
+func ParseFormat(format string) (Format, error) {
+
+	switch format {
+
+	case "json":
+		return JSON, nil
+
+	case "combined":
+		return Combined, nil
+
+	case "logfmt":
+		return Logfmt, nil
+
+	}
+
+	return JSON, ErrInvalidFormat
+
+}
+
+func formatEntry(format Format, e *Entry) ([]byte, error) {
+
+	switch format {
+
+	case Combined:
+		return formatCombined(e), nil
+
+	case Logfmt:
+		return formatLogfmt(e), nil
+
+	}
+
+	out, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+
+}
+
+func dashIfEmpty(s string) string {
+
+	if len(s) == 0 {
+		return "-"
+	}
+
+	return s
+
+}
+
+func formatCombined(e *Entry) []byte {
+
+	target := e.Path
+	if len(e.Query) > 0 {
+		target = fmt.Sprintf("%s?%s", e.Path, e.Query)
+	}
+
+	return []byte(fmt.Sprintf(
+		"%s - - [%s] %q %d %d %q %q\n",
+		dashIfEmpty(e.ClientIp),
+		e.Timestamp.Format("02/Jan/2006:15:04:05 -0700"),
+		fmt.Sprintf("%s %s %s", e.Method, target, e.Protocol),
+		e.Status,
+		e.BytesOut,
+		dashIfEmpty(e.Referer),
+		dashIfEmpty(e.UserAgent),
+	))
+
+}
+
+func logfmtValue(value string) string {
+
+	if len(value) == 0 || strings.ContainsAny(value, " =\"\t\n") {
+		return strconv.Quote(value)
+	}
+
+	return value
+
+}
+
+func formatLogfmt(e *Entry) []byte {
+
+	b := &strings.Builder{}
+
+	pairs := [][2]string{
+		{"timestamp", e.Timestamp.Format("2006-01-02T15:04:05.000Z07:00")},
+		{"request_id", e.RequestId},
+		{"route", e.Route},
+		{"backend", e.Backend},
+		{"method", e.Method},
+		{"path", e.Path},
+		{"query", e.Query},
+		{"protocol", e.Protocol},
+		{"status", strconv.Itoa(e.Status)},
+		{"grpc_code", e.GrpcCode},
+		{"bytes_in", strconv.FormatInt(e.BytesIn, 10)},
+		{"bytes_out", strconv.FormatInt(e.BytesOut, 10)},
+		{"duration_ms", strconv.FormatFloat(e.Duration, 'f', 3, 64)},
+		{"upstream_latency_ms", strconv.FormatFloat(e.UpstreamLatency, 'f', 3, 64)},
+		{"lambda_request_id", e.UpstreamRequestId},
+		{"client_ip", e.ClientIp},
+		{"user_agent", e.UserAgent},
+		{"referer", e.Referer},
+	}
+
+	for i, pair := range pairs {
+
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(pair[0])
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(pair[1]))
+
+	}
+
+	b.WriteByte('\n')
+
+	return []byte(b.String())
+
+}
@@ -0,0 +1,30 @@
+package accesslog
+
+import (
+	"go.uber.org/zap"
+)
+
+type logger struct {
+	format Format
+	sink   Sink
+
+	errorLog *zap.SugaredLogger
+}
+
+func (l *logger) Log(e *Entry) {
+
+	out, err := formatEntry(l.format, e)
+	if err != nil {
+		l.errorLog.Errorw("error formatting access log", "error", err)
+		return
+	}
+
+	if _, err := l.sink.Write(out); err != nil {
+		l.errorLog.Errorw("error writing access log", "error", err)
+	}
+
+}
+
+func (l *logger) Close() error {
+	return l.sink.Close()
+}
@@ -0,0 +1,178 @@
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type Sink interface {
+	io.Writer
+	Close() error
+}
+
+type stdoutSink struct{}
+
+func (stdoutSink) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdoutSink) Close() error {
+	return nil
+}
+
+type rotatingFileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+
+	lock *sync.Mutex
+}
+
+func (s *rotatingFileSink) open() error {
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+
+}
+
+func (s *rotatingFileSink) rotate() error {
+
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.maxBackups > 0 {
+
+		os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+
+		for i := s.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		}
+
+		if err := os.Rename(s.path, fmt.Sprintf("%s.1", s.path)); err != nil {
+			return err
+		}
+
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+
+	return s.open()
+
+}
+
+func (s *rotatingFileSink) Write(p []byte) (int, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.maxSize > 0 && s.size+int64(len(p)) > s.maxSize && s.size > 0 {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.file.Write(p)
+
+	s.size += int64(n)
+
+	return n, err
+
+}
+
+func (s *rotatingFileSink) Close() error {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.file.Close()
+
+}
+
+type asyncSink struct {
+	sink Sink
+
+	entriesCh chan []byte
+	doneCh    chan struct{}
+
+	closed bool
+	lock   *sync.RWMutex
+
+	errorLog *zap.SugaredLogger
+}
+
+func (s *asyncSink) run() {
+
+	defer close(s.doneCh)
+
+	for p := range s.entriesCh {
+
+		if _, err := s.sink.Write(p); err != nil {
+			s.errorLog.Errorw("error writing access log", "error", err)
+		}
+
+	}
+
+}
+
+func (s *asyncSink) Write(p []byte) (int, error) {
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return 0, ErrSinkClosed
+	}
+
+	select {
+
+	case s.entriesCh <- p:
+		return len(p), nil
+
+	default:
+		return 0, ErrBufferFull
+
+	}
+
+}
+
+func (s *asyncSink) Close() error {
+
+	s.lock.Lock()
+
+	if s.closed {
+		s.lock.Unlock()
+		return ErrSinkClosed
+	}
+
+	s.closed = true
+
+	close(s.entriesCh)
+
+	s.lock.Unlock()
+
+	<-s.doneCh
+
+	return s.sink.Close()
+
+}
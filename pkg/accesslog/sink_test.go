@@ -0,0 +1,104 @@
+package accesslog
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type bufferSink struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+	closed bool
+}
+
+func (s *bufferSink) Write(p []byte) (int, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.buffer.Write(p)
+
+}
+
+func (s *bufferSink) Close() error {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+
+	return nil
+
+}
+
+func TestAsyncSinkFlushesOnClose(t *testing.T) {
+
+	sink := &bufferSink{}
+	async := NewAsyncSink(sink, 16)
+
+	for i := 0; i < 3; i++ {
+		if _, err := async.Write([]byte("entry\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := async.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sink.buffer.String(); got != "entry\nentry\nentry\n" {
+		t.Errorf("unexpected sink content %q", got)
+	}
+
+	if !sink.closed {
+		t.Error("underlying sink was not closed")
+	}
+
+}
+
+func TestAsyncSinkWriteAfterClose(t *testing.T) {
+
+	async := NewAsyncSink(&bufferSink{}, 1)
+
+	if err := async.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := async.Write([]byte("entry\n")); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("expected ErrSinkClosed, got %v", err)
+	}
+
+	if err := async.Close(); !errors.Is(err, ErrSinkClosed) {
+		t.Errorf("expected ErrSinkClosed on second close, got %v", err)
+	}
+
+}
+
+func TestAsyncSinkConcurrentWriteAndClose(t *testing.T) {
+
+	async := NewAsyncSink(&bufferSink{}, 4)
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if _, err := async.Write([]byte("entry\n")); err != nil && !errors.Is(err, ErrBufferFull) && !errors.Is(err, ErrSinkClosed) {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	if err := async.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+
+}
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+type HandlerOption func(*handler)
+
+func WithAuthToken(authToken string) HandlerOption {
+	return func(h *handler) {
+		h.authToken = authToken
+	}
+}
+
+func WithRoutesState(routesState state.RoutesState) HandlerOption {
+	return func(h *handler) {
+		h.routesState = routesState
+	}
+}
+
+func WithXdsState(xdsState state.XdsState) HandlerOption {
+	return func(h *handler) {
+		h.xdsState = xdsState
+	}
+}
+
+func WithStateManager(stateManager state.StateManager) HandlerOption {
+	return func(h *handler) {
+		h.stateManager = stateManager
+	}
+}
+
+func WithReadinessCheck(readinessCheck func() error) HandlerOption {
+	return func(h *handler) {
+		h.readinessCheck = readinessCheck
+	}
+}
+
+func WithHandler(pattern string, httpHandler http.Handler) HandlerOption {
+	return func(h *handler) {
+		h.mux.Handle(pattern, httpHandler)
+	}
+}
+
+// WithExposed marks the handler as served on a non-loopback address.
+func WithExposed(exposed bool) HandlerOption {
+	return func(h *handler) {
+		h.exposed = exposed
+	}
+}
+
+func WithLogger(log *zap.SugaredLogger) HandlerOption {
+	return func(h *handler) {
+		h.log = log
+	}
+}
+
+func NewHandler(opts ...HandlerOption) http.Handler {
+
+	h := &handler{
+		mux: http.NewServeMux(),
+		log: zap.NewNop().Sugar(),
+	}
+
+	h.registerRoutes()
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.readinessCheck == nil && h.stateManager != nil {
+		h.readinessCheck = func() error {
+			if h.stateManager.Status().LastSuccessfulSyncTime.IsZero() {
+				return ErrNoSuccessfulSync
+			}
+			return nil
+		}
+	}
+
+	return h
+
+}
@@ -0,0 +1,13 @@
+package admin
+
+import "errors"
+
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrAuthTokenRequired = errors.New("endpoint requires an admin auth token on a non-loopback listener")
+	ErrNoRoutesState     = errors.New("no routes state")
+	ErrNoXdsState        = errors.New("no xds state")
+	ErrNoStateManager    = errors.New("no state manager")
+	ErrNoSuccessfulSync  = errors.New("no successful state sync yet")
+	ErrNoProvenance      = errors.New("no routes or xds state")
+)
@@ -0,0 +1,236 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type handler struct {
+	mux *http.ServeMux
+
+	authToken string
+	exposed   bool
+
+	routesState  state.RoutesState
+	xdsState     state.XdsState
+	stateManager state.StateManager
+
+	readinessCheck func() error
+
+	log *zap.SugaredLogger
+}
+
+func (h *handler) writeJson(w http.ResponseWriter, statusCode int, v interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.log.Errorw("error writing admin response", "error", err)
+	}
+
+}
+
+func (h *handler) writeError(w http.ResponseWriter, statusCode int, err error) {
+
+	h.writeJson(w, statusCode, map[string]string{
+		"error": err.Error(),
+	})
+
+}
+
+func (h *handler) registerRoutes() {
+
+	h.mux.HandleFunc("/healthz", h.healthz)
+	h.mux.HandleFunc("/readyz", h.readyz)
+
+	h.mux.HandleFunc("/routes", h.routes)
+	h.mux.HandleFunc("/xds/resources", h.xdsResources)
+	h.mux.HandleFunc("/state", h.state)
+	h.mux.HandleFunc("/provenance", h.provenance)
+
+	h.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	h.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	h.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	h.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+}
+
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	h.writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *handler) readyz(w http.ResponseWriter, r *http.Request) {
+
+	if h.readinessCheck != nil {
+		if err := h.readinessCheck(); err != nil {
+			h.writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+	}
+
+	h.writeJson(w, http.StatusOK, map[string]string{"status": "ready"})
+
+}
+
+func (h *handler) routes(w http.ResponseWriter, r *http.Request) {
+
+	if h.routesState == nil {
+		h.writeError(w, http.StatusNotFound, ErrNoRoutesState)
+		return
+	}
+
+	routes, err := h.routesState.GetRoutes()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	out, err := protojson.Marshal(&serverpb.Router{Routes: routes})
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeJson(w, http.StatusOK, json.RawMessage(out))
+
+}
+
+func (h *handler) xdsResources(w http.ResponseWriter, r *http.Request) {
+
+	if h.xdsState == nil {
+		h.writeError(w, http.StatusNotFound, ErrNoXdsState)
+		return
+	}
+
+	typeUrlFilter := r.URL.Query().Get("type_url")
+
+	resources := make(map[string]map[string]json.RawMessage)
+
+	for typeUrl, typeResources := range h.xdsState.GetResources() {
+
+		if len(typeUrlFilter) > 0 && typeUrl != typeUrlFilter {
+			continue
+		}
+
+		resources[typeUrl] = make(map[string]json.RawMessage, len(typeResources))
+
+		for name, resource := range typeResources {
+
+			out, err := protojson.Marshal(resource)
+			if err != nil {
+				h.writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			resources[typeUrl][name] = out
+
+		}
+
+	}
+
+	h.writeJson(w, http.StatusOK, resources)
+
+}
+
+func (h *handler) state(w http.ResponseWriter, r *http.Request) {
+
+	if h.stateManager == nil {
+		h.writeError(w, http.StatusNotFound, ErrNoStateManager)
+		return
+	}
+
+	h.writeJson(w, http.StatusOK, h.stateManager.Status())
+
+}
+
+func (h *handler) provenance(w http.ResponseWriter, r *http.Request) {
+
+	if h.routesState == nil && h.xdsState == nil {
+		h.writeError(w, http.StatusNotFound, ErrNoProvenance)
+		return
+	}
+
+	all := []state.Provenance{}
+
+	if h.routesState != nil {
+		all = append(all, h.routesState.Provenance()...)
+	}
+
+	if h.xdsState != nil {
+		all = append(all, h.xdsState.Provenance()...)
+	}
+
+	query := r.URL.Query()
+
+	provenances := make([]state.Provenance, 0, len(all))
+
+	for _, provenance := range all {
+
+		if typeFilter := query.Get("type"); len(typeFilter) > 0 && provenance.Type != typeFilter {
+			continue
+		}
+
+		if nameFilter := query.Get("name"); len(nameFilter) > 0 && provenance.Name != nameFilter {
+			continue
+		}
+
+		if originFilter := query.Get("origin"); len(originFilter) > 0 && provenance.Origin != originFilter {
+			continue
+		}
+
+		provenances = append(provenances, provenance)
+
+	}
+
+	h.writeJson(w, http.StatusOK, provenances)
+
+}
+
+func (h *handler) authorized(r *http.Request) bool {
+
+	if len(h.authToken) == 0 {
+		return true
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.authToken)) == 1
+
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	switch r.URL.Path {
+
+	case "/healthz", "/readyz":
+
+	default:
+
+		if !h.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			h.writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+			return
+		}
+
+		// Without a token, an exposed listener only serves health and metrics;
+		// state, provenance, log level and pprof stay reachable on loopback only.
+		if h.exposed && len(h.authToken) == 0 && r.URL.Path != "/metrics" {
+			h.writeError(w, http.StatusForbidden, ErrAuthTokenRequired)
+			return
+		}
+
+	}
+
+	h.mux.ServeHTTP(w, r)
+
+}
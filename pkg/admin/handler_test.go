@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(h http.Handler, path, token string) int {
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	return rec.Code
+
+}
+
+func TestHandlerAccess(t *testing.T) {
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		opts   []HandlerOption
+		path   string
+		token  string
+		status int
+	}{
+		{name: "loopback without token serves state", path: "/state", status: http.StatusNotFound},
+		{name: "loopback without token serves pprof", path: "/debug/pprof/cmdline", status: http.StatusOK},
+		{name: "exposed without token serves health", opts: []HandlerOption{WithExposed(true)}, path: "/healthz", status: http.StatusOK},
+		{name: "exposed without token serves metrics", opts: []HandlerOption{WithExposed(true)}, path: "/metrics", status: http.StatusOK},
+		{name: "exposed without token refuses state", opts: []HandlerOption{WithExposed(true)}, path: "/state", status: http.StatusForbidden},
+		{name: "exposed without token refuses provenance", opts: []HandlerOption{WithExposed(true)}, path: "/provenance", status: http.StatusForbidden},
+		{name: "exposed without token refuses loglevel", opts: []HandlerOption{WithExposed(true), WithHandler("/loglevel", metrics)}, path: "/loglevel", status: http.StatusForbidden},
+		{name: "exposed without token refuses pprof", opts: []HandlerOption{WithExposed(true)}, path: "/debug/pprof/cmdline", status: http.StatusForbidden},
+		{name: "exposed with token rejects missing token", opts: []HandlerOption{WithExposed(true), WithAuthToken("secret")}, path: "/debug/pprof/cmdline", status: http.StatusUnauthorized},
+		{name: "exposed with token rejects wrong token", opts: []HandlerOption{WithExposed(true), WithAuthToken("secret")}, path: "/state", token: "wrong", status: http.StatusUnauthorized},
+		{name: "exposed with token serves pprof", opts: []HandlerOption{WithExposed(true), WithAuthToken("secret")}, path: "/debug/pprof/cmdline", token: "secret", status: http.StatusOK},
+		{name: "token not required for health", opts: []HandlerOption{WithExposed(true), WithAuthToken("secret")}, path: "/healthz", status: http.StatusOK},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			h := NewHandler(append([]HandlerOption{WithHandler("/metrics", metrics)}, c.opts...)...)
+
+			if status := serve(h, c.path, c.token); status != c.status {
+				t.Errorf("expected status %d, got %d", c.status, status)
+			}
+
+		})
+
+	}
+
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type TfstateSourceOption func(*tfstateSource)
+
+func WithFiles(files ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.files = append(t.files, files...)
+	}
+}
+
+func WithDecrypter(decrypter state.TfstateDecrypter) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.decrypter = decrypter
+	}
+}
+
+func WithVerifier(verifier state.TfstateVerifier) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.verifier = verifier
+	}
+}
+
+func NewTfstateSource(opts ...TfstateSourceOption) (state.TfstateSource, error) {
+
+	t := &tfstateSource{
+		files:       []string{},
+		configFiles: make(map[string]*configFile),
+		rwLock:      new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if len(t.files) == 0 {
+		return nil, ErrEmptyFiles
+	}
+
+	return t, nil
+
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"sort"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type Config struct {
+	Routes                 []*serverpb.Router_Route
+	Listeners              []*listenerv3.Listener
+	VirtualHosts           []*routev3.VirtualHost
+	RouteConfigurations    []*routev3.RouteConfiguration
+	Clusters               []*clusterv3.Cluster
+	ClusterLoadAssignments []*endpointv3.ClusterLoadAssignment
+}
+
+type section struct {
+	key          string
+	resourceType string
+
+	newMessage func() proto.Message
+	messages   func(*Config) []proto.Message
+	set        func(*Config, []proto.Message)
+	name       func(proto.Message) string
+}
+
+func newSection[T proto.Message](key, resourceType string, field func(*Config) *[]T, name func(T) string) *section {
+
+	return &section{
+		key:          key,
+		resourceType: resourceType,
+		newMessage: func() proto.Message {
+			var message T
+			return message.ProtoReflect().New().Interface()
+		},
+		messages: func(c *Config) []proto.Message {
+			messages := make([]proto.Message, 0, len(*field(c)))
+			for _, message := range *field(c) {
+				messages = append(messages, message)
+			}
+			return messages
+		},
+		set: func(c *Config, messages []proto.Message) {
+			typed := make([]T, 0, len(messages))
+			for _, message := range messages {
+				typed = append(typed, message.(T))
+			}
+			*field(c) = typed
+		},
+		name: func(message proto.Message) string {
+			return name(message.(T))
+		},
+	}
+
+}
+
+func resourceName[T proto.Message](message T) string {
+	return cache.GetResourceName(message)
+}
+
+var sections = []*section{
+	newSection("routes", "cruiser_route", func(c *Config) *[]*serverpb.Router_Route {
+		return &c.Routes
+	}, func(route *serverpb.Router_Route) string {
+		return route.Name
+	}),
+	newSection("listeners", "cruiser_envoy_listener", func(c *Config) *[]*listenerv3.Listener {
+		return &c.Listeners
+	}, resourceName[*listenerv3.Listener]),
+	newSection("virtual_hosts", "cruiser_envoy_virtual_host", func(c *Config) *[]*routev3.VirtualHost {
+		return &c.VirtualHosts
+	}, resourceName[*routev3.VirtualHost]),
+	newSection("route_configurations", "cruiser_envoy_route_configuration", func(c *Config) *[]*routev3.RouteConfiguration {
+		return &c.RouteConfigurations
+	}, resourceName[*routev3.RouteConfiguration]),
+	newSection("clusters", "cruiser_envoy_cluster", func(c *Config) *[]*clusterv3.Cluster {
+		return &c.Clusters
+	}, resourceName[*clusterv3.Cluster]),
+	newSection("cluster_load_assignments", "cruiser_envoy_cluster_load_assignment", func(c *Config) *[]*endpointv3.ClusterLoadAssignment {
+		return &c.ClusterLoadAssignments
+	}, resourceName[*endpointv3.ClusterLoadAssignment]),
+}
+
+func sectionByKey(key string) *section {
+
+	for _, s := range sections {
+		if s.key == key {
+			return s
+		}
+	}
+
+	return nil
+
+}
+
+func sectionByResourceType(resourceType string) *section {
+
+	for _, s := range sections {
+		if s.resourceType == resourceType {
+			return s
+		}
+	}
+
+	return nil
+
+}
+
+func (c *Config) ToTfstate() (*state.Tfstate, error) {
+
+	tfstate := &state.Tfstate{
+		Version:   4,
+		Resources: []*state.TfstateResource{},
+	}
+
+	for _, s := range sections {
+
+		messages := s.messages(c)
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		resource := &state.TfstateResource{
+			Mode:      "managed",
+			Type:      s.resourceType,
+			Name:      s.key,
+			Instances: make([]*state.TfstateResourceInstance, 0, len(messages)),
+		}
+
+		for _, message := range messages {
+
+			protoJson, err := protojson.Marshal(message)
+			if err != nil {
+				return nil, err
+			}
+
+			resource.Instances = append(resource.Instances, &state.TfstateResourceInstance{
+				IndexKey: s.name(message),
+				Attributes: map[string]interface{}{
+					"proto_json": string(protoJson),
+				},
+			})
+
+		}
+
+		tfstate.Resources = append(tfstate.Resources, resource)
+
+	}
+
+	return tfstate, nil
+
+}
+
+func FromTfstates(tfstates ...*state.Tfstate) (*Config, error) {
+
+	byName := make(map[*section]map[string]proto.Message)
+
+	for _, tfstate := range tfstates {
+
+		for _, resource := range tfstate.Resources {
+
+			s := sectionByResourceType(resource.Type)
+			if s == nil {
+				continue
+			}
+
+			if _, ok := byName[s]; !ok {
+				byName[s] = make(map[string]proto.Message)
+			}
+
+			for _, instance := range resource.Instances {
+
+				message := s.newMessage()
+
+				if err := protojson.Unmarshal([]byte(instance.GetProtoJson()), message); err != nil {
+					return nil, &state.ResourceError{Address: resource.Address(instance), Err: err}
+				}
+
+				byName[s][s.name(message)] = message
+
+			}
+
+		}
+
+	}
+
+	c := &Config{}
+
+	for s, messages := range byName {
+
+		names := make([]string, 0, len(messages))
+
+		for name := range messages {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		sorted := make([]proto.Message, 0, len(names))
+
+		for _, name := range names {
+			sorted = append(sorted, messages[name])
+		}
+
+		s.set(c, sorted)
+
+	}
+
+	return c, nil
+
+}
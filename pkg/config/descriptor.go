@@ -0,0 +1,153 @@
+package config
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	configDescriptor     protoreflect.MessageDescriptor
+	configDescriptorErr  error
+	configDescriptorOnce sync.Once
+)
+
+func buildConfigDescriptor() (protoreflect.MessageDescriptor, error) {
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("cruiser/config.proto"),
+		Package: proto.String("cruiser.config"),
+		Syntax:  proto.String("proto3"),
+	}
+
+	message := &descriptorpb.DescriptorProto{
+		Name: proto.String("Config"),
+	}
+
+	dependencies := make(map[string]struct{})
+
+	for i, s := range sections {
+
+		descriptor := s.newMessage().ProtoReflect().Descriptor()
+
+		path := descriptor.ParentFile().Path()
+
+		if _, ok := dependencies[path]; !ok {
+			dependencies[path] = struct{}{}
+			file.Dependency = append(file.Dependency, path)
+		}
+
+		message.Field = append(message.Field, &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(s.key),
+			Number:   proto.Int32(int32(i + 1)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String("." + string(descriptor.FullName())),
+		})
+
+	}
+
+	file.MessageType = []*descriptorpb.DescriptorProto{message}
+
+	fileDescriptor, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileDescriptor.Messages().Get(0), nil
+
+}
+
+func newConfigMessage() (*dynamicpb.Message, error) {
+
+	configDescriptorOnce.Do(func() {
+		configDescriptor, configDescriptorErr = buildConfigDescriptor()
+	})
+
+	if configDescriptorErr != nil {
+		return nil, configDescriptorErr
+	}
+
+	return dynamicpb.NewMessage(configDescriptor), nil
+
+}
+
+func (c *Config) toMessage() (*dynamicpb.Message, error) {
+
+	message, err := newConfigMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := message.Descriptor().Fields()
+
+	for _, s := range sections {
+
+		field := fields.ByName(protoreflect.Name(s.key))
+
+		list := message.Mutable(field).List()
+
+		for _, m := range s.messages(c) {
+
+			out, err := proto.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+
+			element := list.NewElement()
+
+			if err := proto.Unmarshal(out, element.Message().Interface()); err != nil {
+				return nil, err
+			}
+
+			list.Append(element)
+
+		}
+
+	}
+
+	return message, nil
+
+}
+
+func fromMessage(message *dynamicpb.Message) (*Config, error) {
+
+	c := &Config{}
+
+	fields := message.Descriptor().Fields()
+
+	for _, s := range sections {
+
+		list := message.Get(fields.ByName(protoreflect.Name(s.key))).List()
+
+		messages := make([]proto.Message, 0, list.Len())
+
+		for i := 0; i < list.Len(); i++ {
+
+			out, err := proto.Marshal(list.Get(i).Message().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			m := s.newMessage()
+
+			if err := proto.Unmarshal(out, m); err != nil {
+				return nil, err
+			}
+
+			messages = append(messages, m)
+
+		}
+
+		s.set(c, messages)
+
+	}
+
+	return c, nil
+
+}
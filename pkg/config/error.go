@@ -0,0 +1,27 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported config format")
+	ErrInvalidDocument   = errors.New("config document must be a mapping")
+	ErrInvalidKey        = errors.New("config keys must be scalars")
+	ErrEmptyFiles        = errors.New("empty config files")
+)
+
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	YAML      Format = "yaml"
+	JSON      Format = "json"
+	Textproto Format = "textproto"
+)
+
+func ParseFormat(format string) (Format, error) {
+
+	switch strings.ToLower(format) {
+
+	case "yaml", "yml":
+		return YAML, nil
+
+	case "json":
+		return JSON, nil
+
+	case "textproto", "txtpb", "pbtxt", "prototext":
+		return Textproto, nil
+
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+
+}
+
+func FormatFromPath(path string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+func Unmarshal(data []byte, format Format) (*Config, error) {
+
+	switch format {
+
+	case YAML, JSON:
+
+		if format == YAML {
+
+			out, err := yamlToJson(data)
+			if err != nil {
+				return nil, err
+			}
+
+			data = out
+
+		}
+
+		message, err := newConfigMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := protojson.Unmarshal(data, message); err != nil {
+			return nil, err
+		}
+
+		return fromMessage(message)
+
+	case Textproto:
+
+		message, err := newConfigMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := prototext.Unmarshal(data, message); err != nil {
+			return nil, err
+		}
+
+		return fromMessage(message)
+
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+
+}
+
+type yamlJsonEncoder struct {
+	buf  *bytes.Buffer
+	line int
+}
+
+func (e *yamlJsonEncoder) advance(line int) {
+
+	for ; e.line < line; e.line++ {
+		e.buf.WriteByte('\n')
+	}
+
+}
+
+func (e *yamlJsonEncoder) encode(node *yaml.Node) error {
+
+	e.advance(node.Line)
+
+	switch node.Kind {
+
+	case yaml.DocumentNode:
+
+		if len(node.Content) == 0 {
+			e.buf.WriteString("{}")
+			return nil
+		}
+
+		return e.encode(node.Content[0])
+
+	case yaml.AliasNode:
+
+		return e.encode(node.Alias)
+
+	case yaml.MappingNode:
+
+		e.buf.WriteByte('{')
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+
+			key, value := node.Content[i], node.Content[i+1]
+
+			if key.Kind != yaml.ScalarNode {
+				return &ParseError{Line: key.Line, Column: key.Column, Err: ErrInvalidKey}
+			}
+
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+
+			e.advance(key.Line)
+
+			out, err := json.Marshal(key.Value)
+			if err != nil {
+				return &ParseError{Line: key.Line, Column: key.Column, Err: err}
+			}
+
+			e.buf.Write(out)
+			e.buf.WriteByte(':')
+
+			if err := e.encode(value); err != nil {
+				return err
+			}
+
+		}
+
+		e.buf.WriteByte('}')
+
+	case yaml.SequenceNode:
+
+		e.buf.WriteByte('[')
+
+		for i, item := range node.Content {
+
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+
+			if err := e.encode(item); err != nil {
+				return err
+			}
+
+		}
+
+		e.buf.WriteByte(']')
+
+	case yaml.ScalarNode:
+
+		var v interface{}
+
+		if err := node.Decode(&v); err != nil {
+			return &ParseError{Line: node.Line, Column: node.Column, Err: err}
+		}
+
+		out, err := json.Marshal(v)
+		if err != nil {
+			return &ParseError{Line: node.Line, Column: node.Column, Err: err}
+		}
+
+		e.buf.Write(out)
+
+	}
+
+	return nil
+
+}
+
+// yamlToJson keeps every value on the line it had in the YAML document, so
+// positions reported by protojson point back into the original file.
+func yamlToJson(data []byte) ([]byte, error) {
+
+	root := &yaml.Node{}
+
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return nil, err
+	}
+
+	if root.Kind == 0 {
+		return []byte("{}"), nil
+	}
+
+	if len(root.Content) > 0 && root.Content[0].Kind != yaml.MappingNode {
+		return nil, &ParseError{Line: root.Content[0].Line, Column: root.Content[0].Column, Err: ErrInvalidDocument}
+	}
+
+	e := &yamlJsonEncoder{
+		buf:  new(bytes.Buffer),
+		line: 1,
+	}
+
+	if err := e.encode(root); err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+
+}
+
+func clearYamlStyle(node *yaml.Node) {
+
+	node.Style = 0
+
+	for _, child := range node.Content {
+		clearYamlStyle(child)
+	}
+
+}
+
+func Marshal(c *Config, format Format) ([]byte, error) {
+
+	message, err := c.toMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+
+	case YAML:
+
+		out, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+
+		node := &yaml.Node{}
+
+		if err := yaml.Unmarshal(out, node); err != nil {
+			return nil, err
+		}
+
+		clearYamlStyle(node)
+
+		buf := new(bytes.Buffer)
+
+		encoder := yaml.NewEncoder(buf)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	case JSON:
+
+		return protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}.Marshal(message)
+
+	case Textproto:
+
+		return prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(message)
+
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+
+}
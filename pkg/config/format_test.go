@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func testConfig() *Config {
+	return &Config{
+		Routes: []*serverpb.Router_Route{
+			{Name: "admin", ParentName: "api"},
+			{Name: "api"},
+		},
+		VirtualHosts: []*routev3.VirtualHost{
+			{Name: "default", Domains: []string{"*"}},
+		},
+		Clusters: []*clusterv3.Cluster{
+			{Name: "backend", ConnectTimeout: durationpb.New(5 * time.Second)},
+		},
+	}
+}
+
+func equalConfig(t *testing.T, expected, got *Config) {
+
+	t.Helper()
+
+	for _, s := range sections {
+
+		expectedMessages, gotMessages := s.messages(expected), s.messages(got)
+
+		if len(expectedMessages) != len(gotMessages) {
+			t.Fatalf("%s: expected %d resources, got %d", s.key, len(expectedMessages), len(gotMessages))
+		}
+
+		for i := range expectedMessages {
+			if !proto.Equal(expectedMessages[i], gotMessages[i]) {
+				t.Fatalf("%s: expected %v, got %v", s.key, expectedMessages[i], gotMessages[i])
+			}
+		}
+
+	}
+
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+
+	for _, format := range []Format{YAML, JSON, Textproto} {
+
+		t.Run(string(format), func(t *testing.T) {
+
+			out, err := Marshal(testConfig(), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Unmarshal(out, format)
+			if err != nil {
+				t.Fatalf("%v:\n%s", err, out)
+			}
+
+			equalConfig(t, testConfig(), c)
+
+		})
+
+	}
+
+}
+
+// TestExport follows state export, which merges the tfstates of the sources
+// and marshals the result back into a config file.
+func TestExport(t *testing.T) {
+
+	routes, err := (&Config{Routes: testConfig().Routes}).ToTfstate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xds, err := (&Config{VirtualHosts: testConfig().VirtualHosts, Clusters: testConfig().Clusters}).ToTfstate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resources without a config section are not exported.
+	xds.Resources = append(xds.Resources, &state.TfstateResource{Mode: "managed", Type: "aws_lambda_function", Name: "handler"})
+
+	c, err := FromTfstates(routes, xds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []Format{YAML, JSON, Textproto} {
+
+		out, err := Marshal(c, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exported, err := Unmarshal(out, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		equalConfig(t, testConfig(), exported)
+
+	}
+
+	invalid := &state.Tfstate{Resources: []*state.TfstateResource{{
+		Mode: "managed",
+		Type: "cruiser_route",
+		Name: "routes",
+		Instances: []*state.TfstateResourceInstance{{
+			IndexKey:   "api",
+			Attributes: map[string]interface{}{"proto_json": "{"},
+		}},
+	}}}
+
+	resourceErr := &state.ResourceError{}
+
+	if _, err = FromTfstates(invalid); !errors.As(err, &resourceErr) {
+		t.Fatalf("expected a resource error, got %v", err)
+	}
+
+}
+
+func TestUnmarshalErrorLines(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		format   Format
+		data     string
+		line     string
+		expected error
+	}{
+		{name: "yaml unknown field", format: YAML, data: "routes:\n- name: api\n  bogus: 1\n", line: "line 3"},
+		{name: "yaml syntax", format: YAML, data: "routes:\n- name: [\n", line: "line 2"},
+		{name: "yaml complex key", format: YAML, data: "routes:\n- name: api\n  ? [a]\n  : b\n", line: "line 3", expected: ErrInvalidKey},
+		{name: "yaml sequence document", format: YAML, data: "- api\n", line: "line 1", expected: ErrInvalidDocument},
+		{name: "json unknown field", format: JSON, data: "{\n\"routes\": [\n{\"name\": \"api\",\n\"bogus\": 1}]}", line: "line 4"},
+		{name: "textproto unknown field", format: Textproto, data: "routes {\n  name: \"api\"\n  bogus: 1\n}\n", line: "line 3"},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			_, err := Unmarshal([]byte(c.data), c.format)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if !regexp.MustCompile(`\b` + c.line + `\b`).MatchString(err.Error()) {
+				t.Errorf("expected %s in %q", c.line, err)
+			}
+
+			if c.expected == nil {
+				return
+			}
+
+			parseErr := &ParseError{}
+
+			if !errors.Is(err, c.expected) || !errors.As(err, &parseErr) {
+				t.Errorf("expected a parse error wrapping %v, got %v", c.expected, err)
+			}
+
+		})
+
+	}
+
+	if _, err := Unmarshal([]byte("{}"), Format("toml")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedFormat, err)
+	}
+
+}
+
+func TestFormatFromPath(t *testing.T) {
+
+	cases := map[string]Format{
+		"routes.yaml":      YAML,
+		"routes.YML":       YAML,
+		"routes.json":      JSON,
+		"routes.txtpb":     Textproto,
+		"routes.textproto": Textproto,
+	}
+
+	for path, expected := range cases {
+		if format, err := FormatFromPath(path); err != nil || format != expected {
+			t.Errorf("%s: expected %s, got %s, %v", path, expected, format, err)
+		}
+	}
+
+	if _, err := FormatFromPath("routes.tfstate"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedFormat, err)
+	}
+
+}
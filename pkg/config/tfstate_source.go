@@ -0,0 +1,157 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type configFile struct {
+	tfstate *state.Tfstate
+	hash    string
+}
+
+type tfstateSource struct {
+	files []string
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	configFiles map[string]*configFile
+
+	invalidated atomic.Bool
+
+	rwLock *sync.RWMutex
+}
+
+func (t *tfstateSource) readSignature(path string) ([]byte, error) {
+
+	if t.verifier == nil {
+		return nil, nil
+	}
+
+	signature, err := os.ReadFile(path + state.TfstateSignatureSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return signature, err
+
+}
+
+func (t *tfstateSource) TfstateObjects() map[string]string {
+
+	t.rwLock.RLock()
+	defer t.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(t.configFiles))
+
+	for path, file := range t.configFiles {
+		objects[path] = file.hash
+	}
+
+	return objects
+
+}
+
+func (t *tfstateSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+
+	tfstates := make([]*state.Tfstate, 0, len(t.files))
+
+	// Parsed files are committed only once every file succeeded, so a failed
+	// poll reports the changes it already saw again on the next poll.
+	configFiles := make(map[string]*configFile, len(t.files))
+
+	needUpdate := false
+
+	for _, path := range t.files {
+
+		format, err := FormatFromPath(path)
+		if err != nil {
+			return nil, state.Permanent(err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		signature, err := t.readSignature(path)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.New()
+		sum.Write(content)
+		sum.Write(signature)
+
+		hash := hex.EncodeToString(sum.Sum(nil))
+
+		file, ok := t.configFiles[path]
+		if !ok || file.hash != hash {
+
+			if t.verifier != nil {
+				content, err = t.verifier.Verify(content, signature)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+
+			if t.decrypter != nil {
+				content, err = t.decrypter.Decrypt(ctx, content)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+
+			c, err := Unmarshal(content, format)
+			if err != nil {
+				return nil, state.Permanent(fmt.Errorf("%s: %w", path, err))
+			}
+
+			tfstate, err := c.ToTfstate()
+			if err != nil {
+				return nil, state.Permanent(fmt.Errorf("%s: %w", path, err))
+			}
+
+			tfstate.Name = path
+
+			file = &configFile{
+				tfstate: tfstate,
+				hash:    hash,
+			}
+
+			needUpdate = true
+
+		}
+
+		configFiles[path] = file
+
+		tfstates = append(tfstates, file.tfstate)
+
+	}
+
+	t.configFiles = configFiles
+
+	if !t.invalidated.Swap(false) && !needUpdate {
+		return nil, nil
+	}
+
+	return tfstates, nil
+
+}
+
+func (t *tfstateSource) Invalidate() {
+	t.invalidated.Store(true)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+func writeFile(t *testing.T, path, content string) {
+
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+}
+
+func TestTfstateSource(t *testing.T) {
+
+	dir := t.TempDir()
+
+	routes := filepath.Join(dir, "routes.yaml")
+	clusters := filepath.Join(dir, "clusters.json")
+
+	writeFile(t, routes, "routes:\n- name: api\n")
+	writeFile(t, clusters, `{"clusters":[{"name":"backend"}]}`)
+
+	source, err := NewTfstateSource(WithFiles(routes, clusters))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tfstates) != 2 || tfstates[0].Name != routes || tfstates[1].Name != clusters {
+		t.Fatalf("unexpected tfstates %+v", tfstates)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	// The routes change but the clusters file is broken, the routes change
+	// must still be reported once the clusters file is fixed.
+	writeFile(t, routes, "routes:\n- name: api\n- name: admin\n")
+	writeFile(t, clusters, `{"clusters":[`)
+
+	if _, err = source.GetTfstate(ctx); err == nil || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+
+	writeFile(t, clusters, `{"clusters":[{"name":"backend"}]}`)
+
+	tfstates, err = source.GetTfstate(ctx)
+	if err != nil || len(tfstates) != 2 {
+		t.Fatalf("expected the routes change after a failed poll, got %v, %v", tfstates, err)
+	}
+
+	if instances := len(tfstates[0].Resources[0].Instances); instances != 2 {
+		t.Fatalf("expected 2 routes, got %d", instances)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	// An invalidated source reports the current files again.
+	source.(state.TfstateInvalidator).Invalidate()
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 2 {
+		t.Fatalf("expected the current tfstates after invalidation, got %v, %v", tfstates, err)
+	}
+
+}
@@ -0,0 +1,205 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type DecrypterOption func(*decrypter)
+
+func WithAgeIdentityFiles(paths ...string) DecrypterOption {
+	return func(d *decrypter) {
+		d.identityFiles = append(d.identityFiles, paths...)
+	}
+}
+
+func WithAgeIdentities(identities ...age.Identity) DecrypterOption {
+	return func(d *decrypter) {
+		d.identities = append(d.identities, identities...)
+	}
+}
+
+func WithDecryptionKMS(kms ...KMS) DecrypterOption {
+	return func(d *decrypter) {
+		for _, k := range kms {
+			if k != nil {
+				d.kms = append(d.kms, k)
+			}
+		}
+	}
+}
+
+func WithRequireEncryption(requireEncryption bool) DecrypterOption {
+	return func(d *decrypter) {
+		d.requireEncryption = requireEncryption
+	}
+}
+
+func NewDecrypter(opts ...DecrypterOption) (state.TfstateDecrypter, error) {
+
+	d := &decrypter{}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	for _, path := range d.identityFiles {
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		identities, err := age.ParseIdentities(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		d.identities = append(d.identities, identities...)
+
+	}
+
+	return d, nil
+
+}
+
+type EncrypterOption func(*encrypter)
+
+func WithAgeRecipientFiles(paths ...string) EncrypterOption {
+	return func(e *encrypter) {
+		e.recipientFiles = append(e.recipientFiles, paths...)
+	}
+}
+
+func WithAgeRecipients(recipients ...age.Recipient) EncrypterOption {
+	return func(e *encrypter) {
+		e.recipients = append(e.recipients, recipients...)
+	}
+}
+
+func WithArmor(armor bool) EncrypterOption {
+	return func(e *encrypter) {
+		e.armor = armor
+	}
+}
+
+func WithEncryptionKMS(kms KMS, keyId string) EncrypterOption {
+	return func(e *encrypter) {
+		e.kms = kms
+		e.keyId = keyId
+	}
+}
+
+func NewEncrypter(opts ...EncrypterOption) (Encrypter, error) {
+
+	e := &encrypter{}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for _, path := range e.recipientFiles {
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients, err := age.ParseRecipients(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		e.recipients = append(e.recipients, recipients...)
+
+	}
+
+	switch {
+
+	case e.kms != nil && len(e.recipients) > 0:
+		return nil, ErrMultipleEnvelopes
+
+	case e.kms == nil && len(e.recipients) == 0:
+		return nil, ErrNoRecipients
+
+	}
+
+	return e, nil
+
+}
+
+func ParseAgeRecipients(recipients ...string) ([]age.Recipient, error) {
+
+	parsed := make([]age.Recipient, 0, len(recipients))
+
+	for _, recipient := range recipients {
+
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, r)
+
+	}
+
+	return parsed, nil
+
+}
+
+type LocalKMSOption func(*localKMS)
+
+func WithLocalKeyFiles(paths ...string) LocalKMSOption {
+	return func(l *localKMS) {
+		l.keyFiles = append(l.keyFiles, paths...)
+	}
+}
+
+func WithLocalKey(keyId string, key []byte) LocalKMSOption {
+	return func(l *localKMS) {
+		l.keys[keyId] = key
+	}
+}
+
+func NewLocalKMS(opts ...LocalKMSOption) (KMS, error) {
+
+	l := &localKMS{
+		keys: make(map[string][]byte),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	for _, path := range l.keyFiles {
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		l.keys[strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))] = key
+
+	}
+
+	for keyId, key := range l.keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%s: %w", keyId, ErrInvalidKeySize)
+		}
+	}
+
+	return l, nil
+
+}
@@ -0,0 +1,88 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type decrypter struct {
+	identityFiles []string
+	identities    []age.Identity
+
+	kms []KMS
+
+	requireEncryption bool
+}
+
+func (d *decrypter) Decrypt(ctx context.Context, content []byte) ([]byte, error) {
+
+	if isAge(content) {
+		return d.decryptAge(content)
+	}
+
+	if e, ok := parseEnvelope(content); ok {
+		return d.decryptEnvelope(ctx, e)
+	}
+
+	if d.requireEncryption {
+		return nil, state.Permanent(ErrUnencryptedTfstate)
+	}
+
+	return content, nil
+
+}
+
+func (d *decrypter) decryptAge(content []byte) ([]byte, error) {
+
+	if len(d.identities) == 0 {
+		return nil, state.Permanent(ErrNoAgeIdentities)
+	}
+
+	var reader io.Reader = bytes.NewReader(content)
+
+	if isArmored(content) {
+		reader = armor.NewReader(bytes.NewReader(bytes.TrimLeft(content, " \t\r\n")))
+	}
+
+	plaintext, err := age.Decrypt(reader, d.identities...)
+	if err != nil {
+		return nil, state.Permanent(fmt.Errorf("%w: %w", ErrDecryptionFailed, err))
+	}
+
+	return io.ReadAll(plaintext)
+
+}
+
+func (d *decrypter) decryptEnvelope(ctx context.Context, e *envelope) ([]byte, error) {
+
+	if e.Encryption != EnvelopeAesGcmV1 {
+		return nil, state.Permanent(fmt.Errorf("%w: %s", ErrUnsupportedEnvelope, e.Encryption))
+	}
+
+	if len(d.kms) == 0 {
+		return nil, state.Permanent(ErrNoKMS)
+	}
+
+	errs := make([]error, 0, len(d.kms))
+
+	for _, kms := range d.kms {
+
+		plaintext, err := e.open(ctx, kms)
+		if err == nil {
+			return plaintext, nil
+		}
+
+		errs = append(errs, err)
+
+	}
+
+	return nil, fmt.Errorf("%w: key %s: %w", ErrDecryptionFailed, e.KeyId, errors.Join(errs...))
+
+}
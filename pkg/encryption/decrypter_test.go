@@ -0,0 +1,241 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+var tfstate = []byte(`{"version":4,"serial":1,"lineage":"lineage","resources":[]}`)
+
+func newKey(t *testing.T) []byte {
+
+	key := make([]byte, 32)
+
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+
+}
+
+func newIdentity(t *testing.T) *age.X25519Identity {
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return identity
+
+}
+
+func newLocalKMS(t *testing.T, opts ...LocalKMSOption) KMS {
+
+	kms, err := NewLocalKMS(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return kms
+
+}
+
+func encrypt(t *testing.T, opts ...EncrypterOption) []byte {
+
+	encrypter, err := NewEncrypter(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := encrypter.Encrypt(context.Background(), tfstate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(ciphertext, []byte("lineage")) {
+		t.Fatal("plaintext found in ciphertext")
+	}
+
+	return ciphertext
+
+}
+
+func TestDecryptAge(t *testing.T) {
+
+	previous, current := newIdentity(t), newIdentity(t)
+
+	for _, armor := range []bool{false, true} {
+
+		ciphertext := encrypt(t, WithAgeRecipients(current.Recipient()), WithArmor(armor))
+
+		// Identities are tried in turn, so a rotated key can be added next
+		// to the previous one.
+		decrypter, err := NewDecrypter(WithAgeIdentities(previous, current), WithRequireEncryption(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		plaintext, err := decrypter.Decrypt(context.Background(), ciphertext)
+		if err != nil {
+			t.Fatalf("armor %v: %v", armor, err)
+		}
+
+		if !bytes.Equal(plaintext, tfstate) {
+			t.Fatalf("armor %v: unexpected plaintext %q", armor, plaintext)
+		}
+
+	}
+
+	ciphertext := encrypt(t, WithAgeRecipients(current.Recipient()))
+
+	cases := []struct {
+		name     string
+		opts     []DecrypterOption
+		expected error
+	}{
+		{name: "unknown identity", opts: []DecrypterOption{WithAgeIdentities(previous)}, expected: ErrDecryptionFailed},
+		{name: "no identities", expected: ErrNoAgeIdentities},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			decrypter, err := NewDecrypter(c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = decrypter.Decrypt(context.Background(), ciphertext)
+			if !errors.Is(err, c.expected) || state.ClassifyError(err) != state.PermanentError {
+				t.Fatalf("expected a permanent %v, got %v", c.expected, err)
+			}
+
+		})
+
+	}
+
+}
+
+func TestDecryptKMSEnvelope(t *testing.T) {
+
+	previousKey, currentKey := newKey(t), newKey(t)
+
+	previous := newLocalKMS(t, WithLocalKey("previous", previousKey))
+	current := newLocalKMS(t, WithLocalKey("current", currentKey))
+
+	ciphertext := encrypt(t, WithEncryptionKMS(current, "current"))
+
+	e, ok := parseEnvelope(ciphertext)
+	if !ok || e.Encryption != EnvelopeAesGcmV1 || e.KeyId != "current" {
+		t.Fatalf("unexpected envelope %s", ciphertext)
+	}
+
+	// Each kms is tried in turn until one can unwrap the data key.
+	decrypter, err := NewDecrypter(WithDecryptionKMS(previous, current), WithRequireEncryption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := decrypter.Decrypt(context.Background(), ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(plaintext, tfstate) {
+		t.Fatalf("unexpected plaintext %q", plaintext)
+	}
+
+	tampered := *e
+	tampered.Ciphertext = append([]byte{}, e.Ciphertext...)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 0xff
+
+	unsupported := *e
+	unsupported.Encryption = "cruiser.aes-gcm.v0"
+
+	cases := []struct {
+		name     string
+		envelope *envelope
+		opts     []DecrypterOption
+		expected error
+	}{
+		{name: "tampered ciphertext", envelope: &tampered, opts: []DecrypterOption{WithDecryptionKMS(current)}, expected: ErrInvalidCiphertext},
+		{name: "unknown key", envelope: e, opts: []DecrypterOption{WithDecryptionKMS(previous)}, expected: ErrUnknownKey},
+		{name: "rotated away key", envelope: e, opts: []DecrypterOption{WithDecryptionKMS(newLocalKMS(t, WithLocalKey("current", previousKey)))}, expected: ErrInvalidCiphertext},
+		{name: "unsupported envelope", envelope: &unsupported, opts: []DecrypterOption{WithDecryptionKMS(current)}, expected: ErrUnsupportedEnvelope},
+		{name: "no kms", envelope: e, expected: ErrNoKMS},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			content, err := json.Marshal(c.envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decrypter, err := NewDecrypter(c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = decrypter.Decrypt(context.Background(), content)
+			if !errors.Is(err, c.expected) || state.ClassifyError(err) != state.PermanentError {
+				t.Fatalf("expected a permanent %v, got %v", c.expected, err)
+			}
+
+		})
+
+	}
+
+}
+
+func TestDecryptPlaintext(t *testing.T) {
+
+	decrypter, err := NewDecrypter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := decrypter.Decrypt(context.Background(), tfstate)
+	if err != nil || !bytes.Equal(plaintext, tfstate) {
+		t.Fatalf("plaintext not passed through: %q, %v", plaintext, err)
+	}
+
+	decrypter, err = NewDecrypter(WithRequireEncryption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = decrypter.Decrypt(context.Background(), tfstate); !errors.Is(err, ErrUnencryptedTfstate) || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent %v, got %v", ErrUnencryptedTfstate, err)
+	}
+
+}
+
+func TestBuilderErrors(t *testing.T) {
+
+	if _, err := NewLocalKMS(WithLocalKey("short", []byte("short"))); !errors.Is(err, ErrInvalidKeySize) {
+		t.Errorf("expected %v, got %v", ErrInvalidKeySize, err)
+	}
+
+	if _, err := NewEncrypter(); !errors.Is(err, ErrNoRecipients) {
+		t.Errorf("expected %v, got %v", ErrNoRecipients, err)
+	}
+
+	kms := newLocalKMS(t, WithLocalKey("current", newKey(t)))
+
+	if _, err := NewEncrypter(WithEncryptionKMS(kms, "current"), WithAgeRecipients(newIdentity(t).Recipient())); !errors.Is(err, ErrMultipleEnvelopes) {
+		t.Errorf("expected %v, got %v", ErrMultipleEnvelopes, err)
+	}
+
+}
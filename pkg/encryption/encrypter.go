@@ -0,0 +1,66 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+type Encrypter interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+}
+
+type encrypter struct {
+	recipientFiles []string
+	recipients     []age.Recipient
+	armor          bool
+
+	kms   KMS
+	keyId string
+}
+
+func (e *encrypter) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+
+	if e.kms != nil {
+		return sealEnvelope(ctx, e.kms, e.keyId, plaintext)
+	}
+
+	out := &bytes.Buffer{}
+
+	var dst io.WriteCloser = nopWriteCloser{out}
+
+	if e.armor {
+		dst = armor.NewWriter(out)
+	}
+
+	w, err := age.Encrypt(dst, e.recipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := dst.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+
+	"filippo.io/age/armor"
+)
+
+const EnvelopeAesGcmV1 = "cruiser.aes-gcm.v1"
+
+var ageHeader = []byte("age-encryption.org/")
+
+type envelope struct {
+	Encryption   string `json:"cruiser_encryption"`
+	KeyId        string `json:"key_id"`
+	EncryptedKey []byte `json:"encrypted_key"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+func isAge(content []byte) bool {
+	return bytes.HasPrefix(content, ageHeader) || isArmored(content)
+}
+
+func isArmored(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte(armor.Header))
+}
+
+func parseEnvelope(content []byte) (*envelope, bool) {
+
+	trimmed := bytes.TrimLeft(content, " \t\r\n")
+
+	if !bytes.HasPrefix(trimmed, []byte("{")) || !bytes.Contains(trimmed, []byte(`"cruiser_encryption"`)) {
+		return nil, false
+	}
+
+	e := &envelope{}
+
+	if err := json.Unmarshal(trimmed, e); err != nil || len(e.Encryption) == 0 {
+		return nil, false
+	}
+
+	return e, true
+
+}
+
+func sealEnvelope(ctx context.Context, kms KMS, keyId string, plaintext []byte) ([]byte, error) {
+
+	dataKey := make([]byte, 32)
+
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := kms.Encrypt(ctx, keyId, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(dataKey, plaintext, []byte(EnvelopeAesGcmV1))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(&envelope{
+		Encryption:   EnvelopeAesGcmV1,
+		KeyId:        keyId,
+		EncryptedKey: encryptedKey,
+		Ciphertext:   ciphertext,
+	}, "", "  ")
+
+}
+
+func (e *envelope) open(ctx context.Context, kms KMS) ([]byte, error) {
+
+	dataKey, err := kms.Decrypt(ctx, e.KeyId, e.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(dataKey, e.Ciphertext, []byte(EnvelopeAesGcmV1))
+
+}
@@ -0,0 +1,16 @@
+package encryption
+
+import "errors"
+
+var (
+	ErrNoRecipients        = errors.New("no age recipients or kms key configured")
+	ErrMultipleEnvelopes   = errors.New("age recipients and kms key are mutually exclusive")
+	ErrNoAgeIdentities     = errors.New("age encrypted tfstate but no age identities configured")
+	ErrNoKMS               = errors.New("kms encrypted tfstate but no kms configured")
+	ErrUnsupportedEnvelope = errors.New("unsupported encryption envelope")
+	ErrDecryptionFailed    = errors.New("tfstate decryption failed")
+	ErrUnencryptedTfstate  = errors.New("tfstate is not encrypted")
+	ErrUnknownKey          = errors.New("unknown kms key")
+	ErrInvalidKeySize      = errors.New("invalid key size, expected 32 bytes")
+	ErrInvalidCiphertext   = errors.New("invalid ciphertext")
+)
@@ -0,0 +1,109 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type KMS interface {
+	Encrypt(ctx context.Context, keyId string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyId string, ciphertext []byte) ([]byte, error)
+}
+
+type localKMS struct {
+	keyFiles []string
+
+	keys map[string][]byte
+}
+
+func (l *localKMS) key(keyId string) ([]byte, error) {
+
+	key, ok := l.keys[keyId]
+	if !ok {
+		return nil, state.Permanent(fmt.Errorf("%w: %s", ErrUnknownKey, keyId))
+	}
+
+	return key, nil
+
+}
+
+func (l *localKMS) Encrypt(ctx context.Context, keyId string, plaintext []byte) ([]byte, error) {
+
+	key, err := l.key(keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(key, plaintext, []byte(keyId))
+
+}
+
+func (l *localKMS) Decrypt(ctx context.Context, keyId string, ciphertext []byte) ([]byte, error) {
+
+	key, err := l.key(keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(key, ciphertext, []byte(keyId))
+
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+
+}
+
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, state.Permanent(ErrInvalidCiphertext)
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, state.Permanent(fmt.Errorf("%w: %w", ErrInvalidCiphertext, err))
+	}
+
+	return plaintext, nil
+
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+
+	if len(key) != 32 {
+		return nil, state.Permanent(ErrInvalidKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+type WebhookOption func(*webhook)
+
+func WithWebhookURL(url string) WebhookOption {
+	return func(w *webhook) {
+		w.url = url
+	}
+}
+
+func WithWebhookSecret(secret string) WebhookOption {
+	return func(w *webhook) {
+		w.secret = []byte(secret)
+	}
+}
+
+func WithWebhookQueueSize(queueSize int) WebhookOption {
+	return func(w *webhook) {
+		w.queueSize = queueSize
+	}
+}
+
+func WithWebhookMaxRetries(maxRetries int) WebhookOption {
+	return func(w *webhook) {
+		w.maxRetries = maxRetries
+	}
+}
+
+func WithWebhookBackoff(initial, max time.Duration) WebhookOption {
+	return func(w *webhook) {
+		w.initialBackoff = initial
+		w.maxBackoff = max
+	}
+}
+
+func WithWebhookHTTPClient(httpClient *http.Client) WebhookOption {
+	return func(w *webhook) {
+		w.httpClient = httpClient
+	}
+}
+
+func WithWebhookLogger(log *zap.SugaredLogger) WebhookOption {
+	return func(w *webhook) {
+		w.log = log
+	}
+}
+
+type Webhook interface {
+	state.ChangeNotifier
+	Start(context.Context)
+	Close(context.Context) error
+}
+
+func NewWebhook(opts ...WebhookOption) Webhook {
+
+	w := &webhook{
+		queueSize:      64,
+		maxRetries:     5,
+		initialBackoff: 500 * time.Millisecond,
+		maxBackoff:     30 * time.Second,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		log:    zap.NewNop().Sugar(),
+		doneCh: make(chan struct{}),
+		lock:   new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	w.queue = make(chan *webhookPayload, w.queueSize)
+
+	return w
+
+}
@@ -0,0 +1,9 @@
+package hooks
+
+import "errors"
+
+var (
+	ErrWebhookQueueFull    = errors.New("webhook queue full")
+	ErrWebhookDeliveryFail = errors.New("webhook delivery failed")
+	ErrWebhookClosed       = errors.New("webhook closed")
+)
@@ -0,0 +1,29 @@
+package hooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	SignatureHeader = "X-Cruiser-Signature"
+	EventHeader     = "X-Cruiser-Event"
+	DeliveryHeader  = "X-Cruiser-Delivery"
+)
+
+func Sign(secret, body []byte) string {
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+
+}
+
+func VerifySignature(secret, body []byte, signature string) bool {
+
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+
+}
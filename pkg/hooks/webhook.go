@@ -0,0 +1,223 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+type webhookPayload struct {
+	Id        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+
+	*state.ChangeSet
+}
+
+type webhook struct {
+	url    string
+	secret []byte
+
+	queueSize int
+	queue     chan *webhookPayload
+
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	httpClient *http.Client
+
+	doneCh chan struct{}
+
+	started bool
+	closed  bool
+	lock    *sync.RWMutex
+
+	log *zap.SugaredLogger
+}
+
+func (w *webhook) Notify(changeSet *state.ChangeSet) {
+
+	payload := &webhookPayload{
+		Id:        uuid.NewString(),
+		Timestamp: time.Now().UTC(),
+		ChangeSet: changeSet,
+	}
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		w.log.Warnw(ErrWebhookClosed.Error(), "deliveryId", payload.Id, "source", changeSet.Source)
+		return
+	}
+
+	select {
+
+	case w.queue <- payload:
+
+	default:
+		w.log.Warnw(ErrWebhookQueueFull.Error(), "deliveryId", payload.Id, "source", changeSet.Source)
+
+	}
+
+}
+
+func (w *webhook) deliver(ctx context.Context, payload *webhookPayload) error {
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.initialBackoff
+
+	for attempt := 0; ; attempt++ {
+
+		err := w.post(ctx, payload, body)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= w.maxRetries {
+			return fmt.Errorf("%w: %s: %s", ErrWebhookDeliveryFail, payload.Id, err.Error())
+		}
+
+		w.log.Warnw("webhook delivery attempt failed", "error", err, "deliveryId", payload.Id, "attempt", attempt+1)
+
+		select {
+
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(backoff):
+
+		}
+
+		backoff *= 2
+		if backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+
+	}
+
+}
+
+func (w *webhook) post(ctx context.Context, payload *webhookPayload, body []byte) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, payload.Source)
+	req.Header.Set(DeliveryHeader, payload.Id)
+
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+
+	res, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	return nil
+
+}
+
+func (w *webhook) send(ctx context.Context, payload *webhookPayload) {
+
+	if err := w.deliver(ctx, payload); err != nil {
+		w.log.Errorw("webhook delivery failed", "error", err, "deliveryId", payload.Id)
+	}
+
+}
+
+func (w *webhook) run(ctx context.Context) {
+
+	defer close(w.doneCh)
+
+	for {
+
+		select {
+
+		case <-ctx.Done():
+			return
+
+		case payload, ok := <-w.queue:
+
+			if !ok {
+				return
+			}
+
+			w.send(ctx, payload)
+
+		}
+
+	}
+
+}
+
+func (w *webhook) Start(ctx context.Context) {
+
+	w.lock.Lock()
+	w.started = true
+	w.lock.Unlock()
+
+	go w.run(ctx)
+
+}
+
+// Close stops accepting changes and delivers the queued ones within ctx, the
+// context given to Start is usually cancelled by then.
+func (w *webhook) Close(ctx context.Context) error {
+
+	w.lock.Lock()
+
+	if w.closed {
+		w.lock.Unlock()
+		return ErrWebhookClosed
+	}
+
+	w.closed = true
+
+	close(w.queue)
+
+	started := w.started
+
+	w.lock.Unlock()
+
+	if started {
+
+		select {
+
+		case <-w.doneCh:
+
+		case <-ctx.Done():
+			return ctx.Err()
+
+		}
+
+	}
+
+	for payload := range w.queue {
+		w.send(ctx, payload)
+	}
+
+	return ctx.Err()
+
+}
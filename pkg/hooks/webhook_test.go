@@ -0,0 +1,297 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+const testSecret = "secret"
+
+type delivery struct {
+	id        string
+	event     string
+	signature string
+	body      []byte
+	at        time.Time
+}
+
+// receiver records every delivery attempt and fails the first ones with the
+// configured status.
+type receiver struct {
+	mu sync.Mutex
+
+	srv *httptest.Server
+
+	failures int
+	status   int
+
+	deliveries []*delivery
+	received   chan struct{}
+}
+
+func newReceiver(t *testing.T, failures, status int) *receiver {
+
+	r := &receiver{
+		failures: failures,
+		status:   status,
+		received: make(chan struct{}, 64),
+	}
+
+	r.srv = httptest.NewServer(r)
+	t.Cleanup(r.srv.Close)
+
+	return r
+
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries = append(r.deliveries, &delivery{
+		id:        req.Header.Get(DeliveryHeader),
+		event:     req.Header.Get(EventHeader),
+		signature: req.Header.Get(SignatureHeader),
+		body:      body,
+		at:        time.Now(),
+	})
+
+	r.received <- struct{}{}
+
+	if r.failures != 0 {
+		r.failures--
+		w.WriteHeader(r.status)
+	}
+
+}
+
+func (r *receiver) wait(t *testing.T, attempts int) []*delivery {
+
+	t.Helper()
+
+	for i := 0; i < attempts; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d delivery attempts, got %d", attempts, i)
+		}
+	}
+
+	return r.list()
+
+}
+
+func (r *receiver) list() []*delivery {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*delivery{}, r.deliveries...)
+
+}
+
+func newTestWebhook(r *receiver, opts ...WebhookOption) Webhook {
+	return NewWebhook(append([]WebhookOption{
+		WithWebhookURL(r.srv.URL),
+		WithWebhookSecret(testSecret),
+		WithWebhookBackoff(20*time.Millisecond, 40*time.Millisecond),
+		WithWebhookHTTPClient(r.srv.Client()),
+	}, opts...)...)
+}
+
+func changeSet(source string) *state.ChangeSet {
+	return &state.ChangeSet{
+		Source: source,
+		Added:  []*state.ResourceChange{{Type: "cruiser_route", Name: "api"}},
+	}
+}
+
+func startWebhook(t *testing.T, w Webhook) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.Start(ctx)
+
+	t.Cleanup(func() {
+		cancel()
+		w.Close(context.Background())
+	})
+
+}
+
+func TestWebhookSignature(t *testing.T) {
+
+	r := newReceiver(t, 0, 0)
+
+	w := newTestWebhook(r)
+	startWebhook(t, w)
+
+	w.Notify(changeSet("routes"))
+
+	d := r.wait(t, 1)[0]
+
+	if !VerifySignature([]byte(testSecret), d.body, d.signature) {
+		t.Fatalf("signature %q not verified", d.signature)
+	}
+
+	if VerifySignature([]byte("other"), d.body, d.signature) {
+		t.Error("signature verified with another secret")
+	}
+
+	if VerifySignature([]byte(testSecret), append(d.body, ' '), d.signature) {
+		t.Error("signature verified for a tampered body")
+	}
+
+	payload := &webhookPayload{}
+
+	if err := json.Unmarshal(d.body, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.event != "routes" || payload.Id != d.id || payload.Source != "routes" || len(payload.Added) != 1 {
+		t.Errorf("unexpected delivery %+v: %s", d, d.body)
+	}
+
+}
+
+func TestWebhookRetries(t *testing.T) {
+
+	r := newReceiver(t, 2, http.StatusServiceUnavailable)
+
+	w := newTestWebhook(r, WithWebhookMaxRetries(3))
+	startWebhook(t, w)
+
+	w.Notify(changeSet("routes"))
+
+	deliveries := r.wait(t, 3)
+
+	for _, d := range deliveries[1:] {
+		if d.id != deliveries[0].id {
+			t.Fatalf("retry delivered as %s, expected %s", d.id, deliveries[0].id)
+		}
+	}
+
+	// The backoff doubles between attempts.
+	if gap := deliveries[1].at.Sub(deliveries[0].at); gap < 20*time.Millisecond {
+		t.Errorf("first retry after %s, expected at least 20ms", gap)
+	}
+
+	if gap := deliveries[2].at.Sub(deliveries[1].at); gap < 40*time.Millisecond {
+		t.Errorf("second retry after %s, expected at least 40ms", gap)
+	}
+
+	select {
+	case <-r.received:
+		t.Fatal("delivered again after a success")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+
+	r := newReceiver(t, 3, http.StatusInternalServerError)
+
+	w := newTestWebhook(r, WithWebhookMaxRetries(2))
+	startWebhook(t, w)
+
+	w.Notify(changeSet("routes"))
+	w.Notify(changeSet("xds"))
+
+	// The first change is given up after the initial attempt and 2 retries,
+	// the next change is then delivered.
+	deliveries := r.wait(t, 4)
+
+	for _, d := range deliveries[:3] {
+		if d.event != "routes" {
+			t.Fatalf("unexpected attempt for %s", d.event)
+		}
+	}
+
+	if deliveries[3].event != "xds" {
+		t.Fatalf("expected the next change after giving up, got %s", deliveries[3].event)
+	}
+
+}
+
+func TestWebhookQueueFullAndClose(t *testing.T) {
+
+	r := newReceiver(t, 0, 0)
+
+	w := newTestWebhook(r, WithWebhookQueueSize(2))
+
+	// Not started, so changes beyond the queue size are dropped.
+	for _, source := range []string{"routes", "xds", "dropped"} {
+		w.Notify(changeSet(source))
+	}
+
+	// Close delivers the queued changes before returning.
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := r.list()
+
+	if len(deliveries) != 2 || deliveries[0].event != "routes" || deliveries[1].event != "xds" {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+
+	// Changes after close are dropped.
+	w.Notify(changeSet("closed"))
+
+	if err := w.Close(context.Background()); !errors.Is(err, ErrWebhookClosed) {
+		t.Errorf("expected %v, got %v", ErrWebhookClosed, err)
+	}
+
+	if deliveries = r.list(); len(deliveries) != 2 {
+		t.Fatalf("change delivered after close: %+v", deliveries)
+	}
+
+}
+
+func TestWebhookCloseDrains(t *testing.T) {
+
+	r := newReceiver(t, 0, 0)
+
+	w := newTestWebhook(r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.Start(ctx)
+
+	w.Notify(changeSet("routes"))
+	r.wait(t, 1)
+
+	// The start context is cancelled on shutdown, before the webhook is
+	// closed, the changes queued in between are still delivered.
+	cancel()
+
+	time.Sleep(10 * time.Millisecond)
+
+	w.Notify(changeSet("xds"))
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if deliveries := r.list(); len(deliveries) != 2 || deliveries[1].event != "xds" {
+		t.Fatalf("queued change not delivered on close: %+v", deliveries)
+	}
+
+}
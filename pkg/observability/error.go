@@ -0,0 +1,7 @@
+package observability
+
+import "errors"
+
+var (
+	ErrInvalidLogFormat = errors.New("invalid log format")
+)
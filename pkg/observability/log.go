@@ -1,14 +1,107 @@
 package observability
 
-import "go.uber.org/zap"
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
 
 var (
 	Log    *zap.SugaredLogger
 	RawLog *zap.Logger
+
+	LogLevel = zap.NewAtomicLevel()
 )
 
-func InitializeLog() {
-	RawLog, _ = zap.NewProduction()
+type logConfig struct {
+	level  string
+	format string
+
+	sampling           bool
+	samplingInitial    int
+	samplingThereafter int
+}
+
+type LogOption func(*logConfig)
+
+func WithLogLevel(level string) LogOption {
+	return func(c *logConfig) {
+		c.level = level
+	}
+}
+
+func WithLogFormat(format string) LogOption {
+	return func(c *logConfig) {
+		c.format = format
+	}
+}
+
+func WithLogSampling(initial, thereafter int) LogOption {
+	return func(c *logConfig) {
+		c.sampling = initial > 0
+		c.samplingInitial = initial
+		c.samplingThereafter = thereafter
+	}
+}
+
+func InitializeLog(opts ...LogOption) error {
+
+	c := &logConfig{
+		level:              "info",
+		format:             "json",
+		sampling:           true,
+		samplingInitial:    100,
+		samplingThereafter: 100,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := LogLevel.UnmarshalText([]byte(c.level)); err != nil {
+		return err
+	}
+
+	cfg := zap.NewProductionConfig()
+
+	switch c.format {
+
+	case "json":
+
+	case "console":
+		cfg.Encoding = "console"
+		cfg.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	default:
+		return ErrInvalidLogFormat
+
+	}
+
+	cfg.Level = LogLevel
+	cfg.Sampling = nil
+
+	if c.sampling {
+		cfg.Sampling = &zap.SamplingConfig{
+			Initial:    c.samplingInitial,
+			Thereafter: c.samplingThereafter,
+		}
+	}
+
+	logger, err := cfg.Build()
+	if err != nil {
+		return err
+	}
+
+	RawLog = logger
 
 	Log = RawLog.Sugar()
+
+	return nil
+
+}
+
+func LogLevelHandler() http.Handler {
+	return LogLevel
 }
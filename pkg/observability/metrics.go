@@ -0,0 +1,189 @@
+package observability
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "cruiser"
+
+var (
+	MetricsRegistry = prometheus.NewRegistry()
+
+	HttpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "requests_total",
+		Help:      "Total number of requests handled by the router.",
+	}, []string{"route", "method", "status"})
+
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests handled by the router.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	HttpRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "requests_in_flight",
+		Help:      "Number of requests currently being handled by the router.",
+	})
+
+	RouterSwapsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "swaps_total",
+		Help:      "Total number of router configurations installed.",
+	})
+
+	RouterGeneration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "generation",
+		Help:      "Version of the router generation currently serving new requests.",
+	})
+
+	RouterGenerationRequestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "generation_requests_in_flight",
+		Help:      "Number of requests in flight per router generation.",
+	}, []string{"generation"})
+
+	RouterDrainingGenerations = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "router",
+		Name:      "draining_generations",
+		Help:      "Number of replaced router generations still draining requests.",
+	})
+
+	LambdaInvokeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "lambda",
+		Name:      "invoke_duration_seconds",
+		Help:      "Latency of Lambda invocations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"function"})
+
+	LambdaInvokeErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "lambda",
+		Name:      "invoke_errors_total",
+		Help:      "Total number of failed Lambda invocations.",
+	}, []string{"function"})
+
+	LambdaInvokeThrottlesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "lambda",
+		Name:      "invoke_throttles_total",
+		Help:      "Total number of throttled Lambda invocations.",
+	}, []string{"function"})
+
+	StateSyncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "state",
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of state synchronizations.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	StateLastSuccessfulSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "state",
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful state synchronization.",
+	})
+
+	StateSyncErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "state",
+		Name:      "sync_errors_total",
+		Help:      "Total number of state synchronization errors.",
+	}, []string{"source"})
+
+	StateConsecutiveFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "state",
+		Name:      "consecutive_failures",
+		Help:      "Number of consecutive failed state synchronizations.",
+	})
+
+	XdsResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "xds",
+		Name:      "resources",
+		Help:      "Number of xDS resources served per type.",
+	}, []string{"type"})
+
+	XdsConnectedStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "xds",
+		Name:      "connected_streams",
+		Help:      "Number of connected Envoy xDS streams.",
+	})
+
+	snapshotTimestamp atomic.Int64
+
+	SnapshotAge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "snapshot",
+		Name:      "age_seconds",
+		Help:      "Age of the configuration snapshot currently being served.",
+	}, func() float64 {
+
+		timestamp := snapshotTimestamp.Load()
+		if timestamp == 0 {
+			return 0
+		}
+
+		return time.Since(time.Unix(0, timestamp)).Seconds()
+
+	})
+)
+
+func init() {
+
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+
+		HttpRequestsTotal,
+		HttpRequestDuration,
+		HttpRequestsInFlight,
+		RouterSwapsTotal,
+		RouterGeneration,
+		RouterGenerationRequestsInFlight,
+		RouterDrainingGenerations,
+
+		LambdaInvokeDuration,
+		LambdaInvokeErrorsTotal,
+		LambdaInvokeThrottlesTotal,
+
+		StateSyncDuration,
+		StateLastSuccessfulSync,
+		StateSyncErrorsTotal,
+		StateConsecutiveFailures,
+
+		XdsResources,
+		XdsConnectedStreams,
+
+		SnapshotAge,
+	)
+
+}
+
+func SetSnapshotTimestamp(t time.Time) {
+	snapshotTimestamp.Store(t.UnixNano())
+}
+
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,37 @@
+package observability
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type requestIdKey struct{}
+
+type logKey struct{}
+
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	return ContextWithLogFields(context.WithValue(ctx, requestIdKey{}, requestId), "requestId", requestId)
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+
+	return requestId
+
+}
+
+func ContextWithLogFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return context.WithValue(ctx, logKey{}, LogFromContext(ctx).With(keysAndValues...))
+}
+
+func LogFromContext(ctx context.Context) *zap.SugaredLogger {
+
+	if log, ok := ctx.Value(logKey{}).(*zap.SugaredLogger); ok {
+		return log
+	}
+
+	return Log
+
+}
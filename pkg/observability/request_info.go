@@ -0,0 +1,31 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+)
+
+type RequestInfo struct {
+	RouteName string
+	Backend   string
+	AccessLog *serverpb.Router_Route_AccessLog
+
+	UpstreamLatency   time.Duration
+	UpstreamRequestId string
+}
+
+type requestInfoKey struct{}
+
+func ContextWithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+
+	return info
+
+}
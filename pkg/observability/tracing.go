@@ -0,0 +1,123 @@
+package observability
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ultraviolet-black/cruiser"
+
+type tracing struct {
+	serviceName  string
+	otlpEndpoint string
+	otlpInsecure bool
+	sampleRatio  float64
+
+	exporter sdktrace.SpanExporter
+	syncer   bool
+}
+
+type TracingOption func(*tracing)
+
+func WithTracingServiceName(serviceName string) TracingOption {
+	return func(t *tracing) {
+		t.serviceName = serviceName
+	}
+}
+
+func WithOtlpEndpoint(endpoint string, insecure bool) TracingOption {
+	return func(t *tracing) {
+		t.otlpEndpoint = endpoint
+		t.otlpInsecure = insecure
+	}
+}
+
+func WithTracingSampleRatio(sampleRatio float64) TracingOption {
+	return func(t *tracing) {
+		t.sampleRatio = sampleRatio
+	}
+}
+
+func WithSpanExporter(exporter sdktrace.SpanExporter) TracingOption {
+	return func(t *tracing) {
+		t.exporter = exporter
+		t.syncer = true
+	}
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+func InitializeTracing(ctx context.Context, opts ...TracingOption) (func(context.Context) error, error) {
+
+	t := &tracing{
+		serviceName: "cruiser",
+		sampleRatio: 1,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if t.exporter == nil && len(t.otlpEndpoint) == 0 {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if t.exporter == nil {
+
+		exporterOpts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(t.otlpEndpoint),
+		}
+
+		if t.otlpInsecure {
+			exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		t.exporter = exporter
+
+	}
+
+	res := resource.NewWithAttributes(
+		"",
+		attribute.String("service.name", t.serviceName),
+	)
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(t.sampleRatio))),
+		// Time prefixed trace ids stay valid W3C ids and are accepted by X-Ray.
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+	}
+
+	if t.syncer {
+		providerOpts = append(providerOpts, sdktrace.WithSyncer(t.exporter))
+	} else {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(t.exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+
+}
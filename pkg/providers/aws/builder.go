@@ -9,13 +9,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
 	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	awsservicediscovery "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
 	awssts "github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
 	"github.com/ultraviolet-black/cruiser/pkg/server"
+	"go.uber.org/zap"
 )
 
 type ProviderOption func(*awsProvider)
@@ -26,6 +28,24 @@ func WithDynamoDBEndpoint(endpoint string) ProviderOption {
 	}
 }
 
+func WithS3Endpoint(endpoint string) ProviderOption {
+	return func(p *awsProvider) {
+		p.s3Endpoint = endpoint
+	}
+}
+
+func WithSQSEndpoint(endpoint string) ProviderOption {
+	return func(p *awsProvider) {
+		p.sqsEndpoint = endpoint
+	}
+}
+
+func WithKMSEndpoint(endpoint string) ProviderOption {
+	return func(p *awsProvider) {
+		p.kmsEndpoint = endpoint
+	}
+}
+
 func WithHealthCheckInterval(interval time.Duration) ProviderOption {
 	return func(p *awsProvider) {
 		p.healthCheckInterval = interval
@@ -38,11 +58,25 @@ func WithHealthCheckParallelism(parallelism int) ProviderOption {
 	}
 }
 
+func WithLambdaLogTail(logTail bool) ProviderOption {
+	return func(p *awsProvider) {
+		p.lambdaLogTail = logTail
+	}
+}
+
+func WithLogger(log *zap.SugaredLogger) ProviderOption {
+	return func(p *awsProvider) {
+		p.log = log
+	}
+}
+
 type Provider interface {
+	GetKMSClient() *awskms.Client
 	GetLambdaClient() *awslambda.Client
 	GetS3Client() *awss3.Client
 	GetS3ClientWithRole(roleArn string) func() *awss3.Client
 	GetServiceDiscoveryClient() *awsservicediscovery.Client
+	GetSQSClient() *awssqs.Client
 
 	HealthCheckHandlers(context.Context, ...*serverpb.Router_Handler)
 
@@ -59,6 +93,7 @@ func NewProvider(opts ...ProviderOption) Provider {
 		healthCheckInterval:    0,
 		healthCheckParallelism: 4,
 		healthCheckWg:          &sync.WaitGroup{},
+		log:                    zap.NewNop().Sugar(),
 	}
 
 	for _, opt := range opts {
@@ -73,6 +108,28 @@ func NewProvider(opts ...ProviderOption) Provider {
 				SigningRegion: "us-east-1",
 			}, nil
 		}
+		if len(p.s3Endpoint) > 0 && service == awss3.ServiceID {
+			return aws.Endpoint{
+				PartitionID:       "aws",
+				URL:               p.s3Endpoint,
+				SigningRegion:     region,
+				HostnameImmutable: true,
+			}, nil
+		}
+		if len(p.sqsEndpoint) > 0 && service == awssqs.ServiceID {
+			return aws.Endpoint{
+				PartitionID:   "aws",
+				URL:           p.sqsEndpoint,
+				SigningRegion: region,
+			}, nil
+		}
+		if len(p.kmsEndpoint) > 0 && service == awskms.ServiceID {
+			return aws.Endpoint{
+				PartitionID:   "aws",
+				URL:           p.kmsEndpoint,
+				SigningRegion: region,
+			}, nil
+		}
 		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
 	})
 
@@ -82,16 +139,18 @@ func NewProvider(opts ...ProviderOption) Provider {
 
 	cfg, err := config.LoadDefaultConfig(context.TODO(), cfgOpts...)
 	if err != nil {
-		observability.Log.Panic(err.Error())
+		p.log.Panic(err.Error())
 	}
 
 	p.healthCheckCh = make(chan *serverpb.Router_Handler, p.healthCheckParallelism)
 
 	p.config = cfg
 	p.stsClient = awssts.NewFromConfig(cfg)
+	p.kmsClient = awskms.NewFromConfig(cfg)
 	p.lambdaClient = awslambda.NewFromConfig(cfg)
 	p.s3Client = awss3.NewFromConfig(cfg)
 	p.serviceDiscoveryClient = awsservicediscovery.NewFromConfig(cfg)
+	p.sqsClient = awssqs.NewFromConfig(cfg)
 
 	return p
 
@@ -0,0 +1,47 @@
+package kms
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/ultraviolet-black/cruiser/pkg/encryption"
+)
+
+type kms struct {
+	client *awskms.Client
+}
+
+func NewKMS(client *awskms.Client) encryption.KMS {
+	return &kms{
+		client: client,
+	}
+}
+
+func (k *kms) Encrypt(ctx context.Context, keyId string, plaintext []byte) ([]byte, error) {
+
+	out, err := k.client.Encrypt(ctx, &awskms.EncryptInput{
+		KeyId:     aws.String(keyId),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.CiphertextBlob, nil
+
+}
+
+func (k *kms) Decrypt(ctx context.Context, keyId string, ciphertext []byte) ([]byte, error) {
+
+	out, err := k.client.Decrypt(ctx, &awskms.DecryptInput{
+		KeyId:          aws.String(keyId),
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Plaintext, nil
+
+}
@@ -0,0 +1,27 @@
+package lambda
+
+type backendOptions struct {
+	logTail bool
+}
+
+type BackendOption func(*backendOptions)
+
+// WithLogTail requests the last 4 KB of the execution log on sampled invocations
+// to record the billed duration. It adds latency and payload to every invoke.
+func WithLogTail(logTail bool) BackendOption {
+	return func(o *backendOptions) {
+		o.logTail = logTail
+	}
+}
+
+func newBackendOptions(opts ...BackendOption) *backendOptions {
+
+	o := &backendOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+
+}
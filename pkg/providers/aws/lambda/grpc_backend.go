@@ -4,15 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
-	"github.com/google/uuid"
 	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	awspb "github.com/ultraviolet-black/cruiser/pkg/proto/providers/aws"
 	"github.com/ultraviolet-black/cruiser/pkg/server"
@@ -30,7 +26,9 @@ type grpcBackend struct {
 	backend *awspb.LambdaBackend
 }
 
-func NewGrpcBackend(lambdaCli *lambda.Client, backend *awspb.LambdaBackend) http.Handler {
+func NewGrpcBackend(lambdaCli *lambda.Client, backend *awspb.LambdaBackend, opts ...BackendOption) http.Handler {
+
+	o := newBackendOptions(opts...)
 
 	backendFactory := func(stream grpc.ServerStream) (server.GrpcMethodBackend, error) {
 
@@ -38,6 +36,7 @@ func NewGrpcBackend(lambdaCli *lambda.Client, backend *awspb.LambdaBackend) http
 			lambdaCli:    lambdaCli,
 			functionName: backend.FunctionName,
 			qualifier:    backend.Qualifier,
+			logTail:      o.logTail,
 			stream:       stream,
 			transport:    grpc.ServerTransportStreamFromContext(stream.Context()),
 		}, nil
@@ -58,6 +57,7 @@ type grpcMethodBackend struct {
 
 	functionName string
 	qualifier    string
+	logTail      bool
 
 	stream    grpc.ServerStream
 	transport grpc.ServerTransportStream
@@ -69,13 +69,11 @@ type grpcMethodBackend struct {
 	responseBody   []byte
 }
 
-func wrapGrpcError(err error) error {
-
-	errorId := uuid.NewString()
+func wrapGrpcError(ctx context.Context, err error) error {
 
-	observability.Log.Errorw("error handling request", "error", err, "errorId", errorId)
+	observability.LogFromContext(ctx).Errorw("error handling request", "error", err)
 
-	return grpc.Errorf(codes.Internal, "internal server error: %s", errorId)
+	return status.Errorf(codes.Internal, "internal server error: %s", observability.RequestIdFromContext(ctx))
 
 }
 
@@ -96,37 +94,26 @@ func (g *grpcMethodBackend) Request(ctx context.Context, payload []byte) error {
 		MultiValueHeaders: g.incomingMetadata,
 		Body:              body,
 		IsBase64Encoded:   true,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: observability.RequestIdFromContext(ctx),
+		},
 	}
 
-	payload, err := json.Marshal(req)
+	result, err := invoke(ctx, g.lambdaCli, g.functionName, g.qualifier, req, g.logTail)
 	if err != nil {
-		return wrapGrpcError(err)
-	}
-
-	result, err := g.lambdaCli.Invoke(ctx, &lambda.InvokeInput{
-		FunctionName:   aws.String(g.functionName),
-		Qualifier:      aws.String(g.qualifier),
-		InvocationType: types.InvocationTypeRequestResponse,
-		Payload:        payload,
-	})
-
-	if result.FunctionError != nil {
-		return wrapGrpcError(fmt.Errorf("function error: %s", *result.FunctionError))
-	}
-	if err != nil {
-		return wrapGrpcError(err)
+		return wrapGrpcError(ctx, err)
 	}
 
 	g.lambdaResponse = &events.APIGatewayProxyResponse{}
 	if err := json.Unmarshal(result.Payload, g.lambdaResponse); err != nil {
-		return wrapGrpcError(err)
+		return wrapGrpcError(ctx, err)
 	}
 
 	if g.lambdaResponse.IsBase64Encoded {
 
 		responseBody, err := base64.StdEncoding.DecodeString(g.lambdaResponse.Body)
 		if err != nil {
-			return wrapGrpcError(err)
+			return wrapGrpcError(ctx, err)
 		}
 
 		g.responseBody = responseBody
@@ -191,10 +178,6 @@ func (g *grpcMethodBackend) Response(context.Context) (metadata.MD, []byte, erro
 
 	}
 
-	errId := uuid.NewString()
-
-	g.outgoingMetadata.Set("x-error-id", errId)
-
 	return g.outgoingMetadata, nil, st.Err()
 
 }
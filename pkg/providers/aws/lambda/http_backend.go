@@ -1,6 +1,7 @@
 package lambda
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -8,10 +9,7 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
-	"github.com/google/uuid"
 	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	awspb "github.com/ultraviolet-black/cruiser/pkg/proto/providers/aws"
 )
@@ -20,24 +18,28 @@ type httpBackend struct {
 	lambdaCli *lambda.Client
 
 	backend *awspb.LambdaBackend
+
+	logTail bool
 }
 
-func NewHttpBackend(lambdaCli *lambda.Client, backend *awspb.LambdaBackend) http.Handler {
+func NewHttpBackend(lambdaCli *lambda.Client, backend *awspb.LambdaBackend, opts ...BackendOption) http.Handler {
+
+	o := newBackendOptions(opts...)
+
 	return &httpBackend{
 		lambdaCli: lambdaCli,
 		backend:   backend,
+		logTail:   o.logTail,
 	}
-}
 
-func wrapHttpError(w http.ResponseWriter, err error) {
+}
 
-	errorId := uuid.NewString()
+func wrapHttpError(ctx context.Context, w http.ResponseWriter, err error) {
 
-	observability.Log.Errorw("error handling request", "error", err, "errorId", errorId)
+	observability.LogFromContext(ctx).Errorw("error handling request", "error", err)
 
-	w.Header().Add("x-error-id", errorId)
 	w.WriteHeader(http.StatusInternalServerError)
-	w.Write([]byte(fmt.Sprintf("internal server error: %s", errorId)))
+	w.Write([]byte(fmt.Sprintf("internal server error: %s", observability.RequestIdFromContext(ctx))))
 
 }
 
@@ -45,7 +47,7 @@ func (h *httpBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		wrapHttpError(w, err)
+		wrapHttpError(r.Context(), w, err)
 		return
 	}
 
@@ -56,27 +58,14 @@ func (h *httpBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		MultiValueQueryStringParameters: r.URL.Query(),
 		Body:                            string(body[:]),
 		IsBase64Encoded:                 false,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: observability.RequestIdFromContext(r.Context()),
+		},
 	}
 
-	payload, err := json.Marshal(req)
-	if err != nil {
-		wrapHttpError(w, err)
-		return
-	}
-
-	result, err := h.lambdaCli.Invoke(r.Context(), &lambda.InvokeInput{
-		FunctionName:   aws.String(h.backend.FunctionName),
-		Qualifier:      aws.String(h.backend.Qualifier),
-		InvocationType: types.InvocationTypeRequestResponse,
-		Payload:        payload,
-	})
-
-	if result.FunctionError != nil {
-		wrapHttpError(w, fmt.Errorf("function error: %s", *result.FunctionError))
-		return
-	}
+	result, err := invoke(r.Context(), h.lambdaCli, h.backend.FunctionName, h.backend.Qualifier, req, h.logTail)
 	if err != nil {
-		wrapHttpError(w, err)
+		wrapHttpError(r.Context(), w, err)
 		return
 	}
 
@@ -85,7 +74,7 @@ func (h *httpBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Headers:           make(map[string]string),
 	}
 	if err := json.Unmarshal(result.Payload, response); err != nil {
-		wrapHttpError(w, err)
+		wrapHttpError(r.Context(), w, err)
 		return
 	}
 
@@ -108,15 +97,14 @@ func (h *httpBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if response.IsBase64Encoded {
 		bodyBytes, err := base64.StdEncoding.DecodeString(response.Body)
 		if err != nil {
-			wrapHttpError(w, err)
+			wrapHttpError(r.Context(), w, err)
 			return
 		}
 		responseBody = bodyBytes
 	}
 
 	if _, err := w.Write(responseBody); err != nil {
-		errorId := uuid.NewString()
-		observability.Log.Errorw("error writing response", "error", err, "errorId", errorId)
+		observability.LogFromContext(r.Context()).Errorw("error writing response", "error", err)
 	}
 
 }
@@ -0,0 +1,244 @@
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	xrayTraceHeader  = "X-Amzn-Trace-Id"
+	xrayMaxTraceAge  = 30 * 24 * time.Hour
+	xrayMaxClockSkew = 5 * time.Minute
+)
+
+var billedDurationRegexp = regexp.MustCompile(`Billed Duration: (\d+) ms`)
+
+type multiValueCarrier map[string][]string
+
+func (c multiValueCarrier) Get(key string) string {
+
+	if vals := c[strings.ToLower(key)]; len(vals) > 0 {
+		return vals[0]
+	}
+
+	return ""
+
+}
+
+func (c multiValueCarrier) Set(key, value string) {
+	c[strings.ToLower(key)] = []string{value}
+}
+
+func (c multiValueCarrier) Keys() []string {
+
+	keys := make([]string, 0, len(c))
+
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+
+}
+
+// X-Ray rejects trace ids whose epoch is older than 30 days, which happens when
+// the trace was started upstream by a non X-Ray id generator.
+func isXrayTraceId(traceId trace.TraceID, now time.Time) bool {
+
+	epoch := time.Unix(int64(binary.BigEndian.Uint32(traceId[:4])), 0)
+
+	return epoch.After(now.Add(-xrayMaxTraceAge)) && epoch.Before(now.Add(xrayMaxClockSkew))
+
+}
+
+func xrayTraceHeaderValue(ctx context.Context) (string, bool) {
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+
+	if !spanCtx.IsValid() || !isXrayTraceId(spanCtx.TraceID(), time.Now()) {
+		return "", false
+	}
+
+	carrier := propagation.MapCarrier{}
+
+	xray.Propagator{}.Inject(ctx, carrier)
+
+	value := carrier.Get(xrayTraceHeader)
+
+	return value, len(value) > 0
+
+}
+
+func billedDuration(logResult *string) (int64, bool) {
+
+	if logResult == nil {
+		return 0, false
+	}
+
+	logs, err := base64.StdEncoding.DecodeString(aws.ToString(logResult))
+	if err != nil {
+		return 0, false
+	}
+
+	match := billedDurationRegexp.FindSubmatch(logs)
+	if match == nil {
+		return 0, false
+	}
+
+	duration, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return duration, true
+
+}
+
+func newInvokeInput(functionName, qualifier string, req *events.APIGatewayProxyRequest) (*lambda.InvokeInput, error) {
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		Qualifier:      aws.String(qualifier),
+		InvocationType: types.InvocationTypeRequestResponse,
+		Payload:        payload,
+	}, nil
+
+}
+
+func invoke(ctx context.Context, lambdaCli *lambda.Client, functionName, qualifier string, req *events.APIGatewayProxyRequest, logTail bool) (*lambda.InvokeOutput, error) {
+
+	ctx, span := observability.Tracer().Start(
+		ctx,
+		fmt.Sprintf("Lambda Invoke %s", functionName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("faas.invoked_provider", "aws"),
+			attribute.String("faas.invoked_name", functionName),
+			attribute.String("aws.lambda.qualifier", qualifier),
+		),
+	)
+	defer span.End()
+
+	headers := make(multiValueCarrier, len(req.MultiValueHeaders)+1)
+
+	for key, vals := range req.MultiValueHeaders {
+		headers[key] = vals
+	}
+
+	propagator := otel.GetTextMapPropagator()
+
+	// Inbound headers keep their casing, drop any propagation header so the
+	// injected ones are not duplicated, e.g. Traceparent next to traceparent.
+	for _, field := range propagator.Fields() {
+		for key := range headers {
+			if strings.EqualFold(key, field) {
+				delete(headers, key)
+			}
+		}
+	}
+
+	propagator.Inject(ctx, headers)
+
+	req.MultiValueHeaders = headers
+
+	input, err := newInvokeInput(functionName, qualifier, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if logTail && span.IsRecording() {
+		input.LogType = types.LogTypeTail
+	}
+
+	optFns := []func(*lambda.Options){}
+
+	if traceHeader, ok := xrayTraceHeaderValue(ctx); ok {
+		optFns = append(optFns, func(o *lambda.Options) {
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(xrayTraceHeader, traceHeader))
+		})
+	}
+
+	start := time.Now()
+
+	result, err := lambdaCli.Invoke(ctx, input, optFns...)
+
+	elapsed := time.Since(start)
+
+	observability.LambdaInvokeDuration.WithLabelValues(functionName).Observe(elapsed.Seconds())
+
+	info := observability.RequestInfoFromContext(ctx)
+
+	if info != nil {
+		info.UpstreamLatency += elapsed
+	}
+
+	if err != nil {
+
+		var throttleErr *types.TooManyRequestsException
+		if errors.As(err, &throttleErr) {
+			observability.LambdaInvokeThrottlesTotal.WithLabelValues(functionName).Inc()
+		}
+
+		observability.LambdaInvokeErrorsTotal.WithLabelValues(functionName).Inc()
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("aws.lambda.status_code", int(result.StatusCode)))
+
+	if requestId, ok := awsmiddleware.GetRequestIDMetadata(result.ResultMetadata); ok {
+
+		span.SetAttributes(attribute.String("aws.request_id", requestId))
+
+		if info != nil {
+			info.UpstreamRequestId = requestId
+		}
+
+	}
+
+	if duration, ok := billedDuration(result.LogResult); ok {
+		span.SetAttributes(attribute.Int64("aws.lambda.billed_duration_ms", duration))
+	}
+
+	if result.FunctionError != nil {
+		err := fmt.Errorf("function error: %s", aws.ToString(result.FunctionError))
+		observability.LambdaInvokeErrorsTotal.WithLabelValues(functionName).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result, err
+	}
+
+	return result, nil
+
+}
@@ -0,0 +1,324 @@
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	awspb "github.com/ultraviolet-black/cruiser/pkg/proto/providers/aws"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	spanExporter    = tracetest.NewInMemoryExporter()
+	initTracingOnce sync.Once
+)
+
+type lambdaInvocation struct {
+	functionName string
+	logType      string
+	traceHeader  string
+	request      events.APIGatewayProxyRequest
+}
+
+type fakeLambda struct {
+	mu          sync.Mutex
+	invocations []lambdaInvocation
+
+	functionError string
+}
+
+func (f *fakeLambda) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	// POST /2015-03-31/functions/{name}/invocations
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	invocation := lambdaInvocation{
+		functionName: parts[2],
+		logType:      r.Header.Get("X-Amz-Log-Type"),
+		traceHeader:  r.Header.Get(xrayTraceHeader),
+	}
+
+	json.NewDecoder(r.Body).Decode(&invocation.request)
+
+	f.mu.Lock()
+	f.invocations = append(f.invocations, invocation)
+	f.mu.Unlock()
+
+	w.Header().Set("x-amzn-RequestId", "request-1")
+
+	if invocation.logType == "Tail" {
+		w.Header().Set("X-Amz-Log-Result", base64.StdEncoding.EncodeToString([]byte("REPORT RequestId: request-1\tDuration: 11.2 ms\tBilled Duration: 12 ms")))
+	}
+
+	if len(f.functionError) > 0 {
+		w.Header().Set("X-Amz-Function-Error", f.functionError)
+	}
+
+	json.NewEncoder(w).Encode(&events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "ok"})
+
+}
+
+func (f *fakeLambda) last(t *testing.T) lambdaInvocation {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.invocations) == 0 {
+		t.Fatal("lambda was not invoked")
+	}
+
+	return f.invocations[len(f.invocations)-1]
+
+}
+
+func newTestClient(t *testing.T, handler http.Handler) *lambda.Client {
+
+	initTracingOnce.Do(func() {
+		observability.InitializeLog()
+		if _, err := observability.InitializeTracing(context.Background(), observability.WithSpanExporter(spanExporter)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	spanExporter.Reset()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return lambda.New(lambda.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(srv.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		HTTPClient:       srv.Client(),
+		RetryMaxAttempts: 1,
+	})
+
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+
+}
+
+func invokeSpans() []tracetest.SpanStub {
+
+	spans := []tracetest.SpanStub{}
+
+	for _, span := range spanExporter.GetSpans() {
+		if strings.HasPrefix(span.Name, "Lambda Invoke") {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+
+}
+
+func TestInvokeInstrumentation(t *testing.T) {
+
+	fake := &fakeLambda{}
+	client := newTestClient(t, fake)
+
+	info := &observability.RequestInfo{}
+	ctx := observability.ContextWithRequestInfo(context.Background(), info)
+
+	req := &events.APIGatewayProxyRequest{
+		MultiValueHeaders: map[string][]string{
+			"x-custom":    {"value"},
+			"Traceparent": {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+			"TRACESTATE":  {"vendor=value"},
+		},
+	}
+
+	if _, err := invoke(ctx, client, "instrumented", "live", req, false); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := invokeSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 invoke span, got %d", len(spans))
+	}
+
+	span := spans[0]
+
+	if span.SpanKind != trace.SpanKindClient {
+		t.Errorf("expected client span, got %s", span.SpanKind)
+	}
+
+	if value, ok := spanAttribute(span, "faas.invoked_name"); !ok || value.AsString() != "instrumented" {
+		t.Errorf("unexpected faas.invoked_name %v", value)
+	}
+
+	if value, ok := spanAttribute(span, "aws.request_id"); !ok || value.AsString() != "request-1" {
+		t.Errorf("unexpected aws.request_id %v", value)
+	}
+
+	if _, ok := spanAttribute(span, "aws.lambda.billed_duration_ms"); ok {
+		t.Error("billed duration recorded without log tail")
+	}
+
+	if info.UpstreamRequestId != "request-1" || info.UpstreamLatency <= 0 {
+		t.Errorf("request info not updated: %+v", info)
+	}
+
+	invocation := fake.last(t)
+
+	if len(invocation.logType) > 0 {
+		t.Errorf("log tail requested while disabled: %q", invocation.logType)
+	}
+
+	traceparent := invocation.request.MultiValueHeaders["traceparent"]
+	if len(traceparent) != 1 || !strings.Contains(traceparent[0], span.SpanContext.TraceID().String()) {
+		t.Errorf("traceparent not propagated: %v", traceparent)
+	}
+
+	for _, key := range []string{"Traceparent", "TRACESTATE"} {
+		if _, ok := invocation.request.MultiValueHeaders[key]; ok {
+			t.Errorf("inbound %s header not replaced", key)
+		}
+	}
+
+	if invocation.request.MultiValueHeaders["x-custom"][0] != "value" {
+		t.Error("request headers were not preserved")
+	}
+
+	traceId := span.SpanContext.TraceID().String()
+
+	expectedHeader := "Root=1-" + traceId[:8] + "-" + traceId[8:] + ";Parent=" + span.SpanContext.SpanID().String() + ";Sampled=1"
+	if invocation.traceHeader != expectedHeader {
+		t.Errorf("expected X-Ray header %q, got %q", expectedHeader, invocation.traceHeader)
+	}
+
+	epoch, err := strconv.ParseInt(traceId[:8], 16, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if age := time.Since(time.Unix(epoch, 0)); age < -time.Minute || age > time.Minute {
+		t.Errorf("trace id epoch is not the current time: %s", time.Unix(epoch, 0))
+	}
+
+	if !observability.LambdaInvokeDuration.DeleteLabelValues("instrumented") {
+		t.Error("invoke duration was not observed")
+	}
+
+}
+
+func TestInvokeLogTail(t *testing.T) {
+
+	fake := &fakeLambda{}
+	client := newTestClient(t, fake)
+
+	if _, err := invoke(context.Background(), client, "log-tail", "", &events.APIGatewayProxyRequest{}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if invocation := fake.last(t); invocation.logType != "Tail" {
+		t.Errorf("expected log tail, got %q", invocation.logType)
+	}
+
+	spans := invokeSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 invoke span, got %d", len(spans))
+	}
+
+	if value, ok := spanAttribute(spans[0], "aws.lambda.billed_duration_ms"); !ok || value.AsInt64() != 12 {
+		t.Errorf("unexpected billed duration %v", value)
+	}
+
+}
+
+func TestInvokeFunctionError(t *testing.T) {
+
+	fake := &fakeLambda{functionError: "Unhandled"}
+	client := newTestClient(t, fake)
+
+	before := testutil.ToFloat64(observability.LambdaInvokeErrorsTotal.WithLabelValues("failing"))
+
+	if _, err := invoke(context.Background(), client, "failing", "", &events.APIGatewayProxyRequest{}, false); err == nil {
+		t.Fatal("expected function error")
+	}
+
+	if after := testutil.ToFloat64(observability.LambdaInvokeErrorsTotal.WithLabelValues("failing")); after != before+1 {
+		t.Errorf("expected errors total %v, got %v", before+1, after)
+	}
+
+	spans := invokeSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 invoke span, got %d", len(spans))
+	}
+
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("expected error status, got %v", spans[0].Status)
+	}
+
+}
+
+func TestInvokeRemoteParentWithoutXrayEpoch(t *testing.T) {
+
+	fake := &fakeLambda{}
+	client := newTestClient(t, fake)
+
+	traceId, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanId, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+
+	if _, err := invoke(ctx, client, "remote-parent", "", &events.APIGatewayProxyRequest{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if invocation := fake.last(t); len(invocation.traceHeader) > 0 {
+		t.Errorf("X-Ray header sent for a trace id X-Ray would reject: %q", invocation.traceHeader)
+	}
+
+}
+
+func TestHealthcheckIsNotInstrumented(t *testing.T) {
+
+	fake := &fakeLambda{}
+	client := newTestClient(t, fake)
+
+	DoHealthcheck(context.Background(), client, &awspb.LambdaBackend{FunctionName: "healthcheck"})
+
+	if invocation := fake.last(t); invocation.functionName != "healthcheck" {
+		t.Errorf("unexpected function %q", invocation.functionName)
+	}
+
+	if spans := invokeSpans(); len(spans) != 0 {
+		t.Errorf("health check produced %d invoke spans", len(spans))
+	}
+
+	if observability.LambdaInvokeDuration.DeleteLabelValues("healthcheck") {
+		t.Error("health check observed as an invoke")
+	}
+
+}
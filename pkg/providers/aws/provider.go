@@ -9,16 +9,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
 
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
 	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	awsservicediscovery "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	awssts "github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/ultraviolet-black/cruiser/pkg/providers/aws/lambda"
 	"github.com/ultraviolet-black/cruiser/pkg/server"
+	"go.uber.org/zap"
 )
 
 type awsProvider struct {
@@ -27,9 +29,11 @@ type awsProvider struct {
 
 	config aws.Config
 
+	kmsClient              *awskms.Client
 	lambdaClient           *awslambda.Client
 	s3Client               *awss3.Client
 	serviceDiscoveryClient *awsservicediscovery.Client
+	sqsClient              *awssqs.Client
 	stsClient              *awssts.Client
 
 	healthCheckInterval    time.Duration
@@ -38,7 +42,18 @@ type awsProvider struct {
 	healthCheckParallelism int
 	healthCheckWg          *sync.WaitGroup
 
+	lambdaLogTail bool
+
+	log *zap.SugaredLogger
+
 	dynamodbEndpoint string
+	s3Endpoint       string
+	sqsEndpoint      string
+	kmsEndpoint      string
+}
+
+func (p *awsProvider) GetKMSClient() *awskms.Client {
+	return p.kmsClient
 }
 
 func (p *awsProvider) GetLambdaClient() *awslambda.Client {
@@ -59,7 +74,7 @@ func (p *awsProvider) GetS3ClientWithRole(roleArn string) func() *awss3.Client {
 			DurationSeconds: aws.Int32(900),
 		})
 		if err != nil {
-			observability.Log.Panicw("error assuming role", "error", err, "roleArn", roleArn)
+			p.log.Panicw("error assuming role", "error", err, "roleArn", roleArn)
 		}
 
 		assumeRoleConfig, err := config.LoadDefaultConfig(context.TODO(),
@@ -70,7 +85,7 @@ func (p *awsProvider) GetS3ClientWithRole(roleArn string) func() *awss3.Client {
 			),
 		)
 		if err != nil {
-			observability.Log.Panicw("error loading config", "error", err)
+			p.log.Panicw("error loading config", "error", err)
 		}
 
 		return awss3.NewFromConfig(assumeRoleConfig)
@@ -83,6 +98,10 @@ func (p *awsProvider) GetServiceDiscoveryClient() *awsservicediscovery.Client {
 	return p.serviceDiscoveryClient
 }
 
+func (p *awsProvider) GetSQSClient() *awssqs.Client {
+	return p.sqsClient
+}
+
 func (p *awsProvider) BackendProviderKey() server.BackendProviderKey {
 	return server.AWSBackendProvider
 }
@@ -109,7 +128,10 @@ func (p *awsProvider) HealthCheckHandlers(ctx context.Context, handlers ...*serv
 	}
 
 	if p.stopHealthCheckCh != nil {
-		p.stopHealthCheckCh <- struct{}{}
+		select {
+		case p.stopHealthCheckCh <- struct{}{}:
+		default:
+		}
 	} else {
 		p.stopHealthCheckCh = make(chan struct{})
 	}
@@ -143,11 +165,11 @@ func (p *awsProvider) ToGrpcBackend(h *serverpb.Router_Handler) http.Handler {
 	switch backend := h.Backend.(type) {
 
 	case *serverpb.Router_Handler_AwsLambda:
-		return lambda.NewGrpcBackend(p.lambdaClient, backend.AwsLambda)
+		return lambda.NewGrpcBackend(p.lambdaClient, backend.AwsLambda, lambda.WithLogTail(p.lambdaLogTail))
 
 	}
 
-	observability.Log.Panicw(server.ErrNoBackendFound.Error())
+	p.log.Panicw(server.ErrNoBackendFound.Error())
 
 	return nil
 
@@ -158,11 +180,11 @@ func (p *awsProvider) ToHttpBackend(h *serverpb.Router_Handler) http.Handler {
 	switch backend := h.Backend.(type) {
 
 	case *serverpb.Router_Handler_AwsLambda:
-		return lambda.NewHttpBackend(p.lambdaClient, backend.AwsLambda)
+		return lambda.NewHttpBackend(p.lambdaClient, backend.AwsLambda, lambda.WithLogTail(p.lambdaLogTail))
 
 	}
 
-	observability.Log.Panicw(server.ErrNoBackendFound.Error())
+	p.log.Panicw(server.ErrNoBackendFound.Error())
 
 	return nil
 
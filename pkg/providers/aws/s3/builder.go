@@ -1,8 +1,13 @@
 package s3
 
 import (
+	"sync"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
 )
 
 type TfstateSourceOption func(*tfstateSource)
@@ -19,16 +24,101 @@ func WithS3ClientFactory(s3ClientFactory func() *s3.Client) TfstateSourceOption
 	}
 }
 
+func WithDecrypter(decrypter state.TfstateDecrypter) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.decrypter = decrypter
+	}
+}
+
+func WithVerifier(verifier state.TfstateVerifier) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.verifier = verifier
+	}
+}
+
+func WithPrefixes(prefixes ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.prefixes = prefixes
+	}
+}
+
+func WithIncludePatterns(patterns ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.includePatterns = patterns
+	}
+}
+
+func WithExcludePatterns(patterns ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.excludePatterns = patterns
+	}
+}
+
+func WithWorkspaces(workspaces ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.workspaces = workspaces
+	}
+}
+
+func WithWorkspaceKeyPrefix(workspaceKeyPrefix string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.workspaceKeyPrefix = workspaceKeyPrefix
+	}
+}
+
+func WithDownloadConcurrency(downloadConcurrency int) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.downloadConcurrency = downloadConcurrency
+	}
+}
+
+func WithNotificationQueue(sqsClient *sqs.Client, queueUrl string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.sqsClient = sqsClient
+		t.queueUrl = queueUrl
+	}
+}
+
+func WithReconcileInterval(reconcileInterval time.Duration) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.reconcileInterval = reconcileInterval
+	}
+}
+
+func WithLogger(log *zap.SugaredLogger) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.log = log
+	}
+}
+
 func NewTfstateSource(opts ...TfstateSourceOption) state.TfstateSource {
 
 	t := &tfstateSource{
-		tfstateObjects: make(map[string]*tfstateObject),
+		prefixes:            []string{""},
+		includePatterns:     []string{"*.tfstate"},
+		excludePatterns:     []string{},
+		workspaces:          []string{},
+		workspaceKeyPrefix:  "env:",
+		downloadConcurrency: 8,
+		tfstateObjects:      make(map[string]*tfstateObject),
+		reconcileInterval:   5 * time.Minute,
+		pendingKeys:         make(map[string]struct{}),
+		log:                 zap.NewNop().Sugar(),
+		rwLock:              new(sync.RWMutex),
 	}
 
 	for _, opt := range opts {
 		opt(t)
 	}
 
+	if len(t.prefixes) == 0 {
+		t.prefixes = []string{""}
+	}
+
+	if t.downloadConcurrency <= 0 {
+		t.downloadConcurrency = 1
+	}
+
 	return t
 
 }
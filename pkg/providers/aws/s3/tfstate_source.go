@@ -2,16 +2,22 @@ package s3
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	s3manager "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
 )
 
+const defaultWorkspace = "default"
+
 type tfstateObject struct {
 	tfstate *state.Tfstate
 	etag    string
@@ -22,107 +28,316 @@ type tfstateSource struct {
 
 	bucket string
 
+	prefixes            []string
+	includePatterns     []string
+	excludePatterns     []string
+	workspaces          []string
+	workspaceKeyPrefix  string
+	downloadConcurrency int
+
 	tfstateObjects map[string]*tfstateObject
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	sqsClient         *sqs.Client
+	queueUrl          string
+	reconcileInterval time.Duration
+
+	watching      bool
+	lastReconcile time.Time
+	pendingKeys   map[string]struct{}
+
+	log *zap.SugaredLogger
+
+	invalidated atomic.Bool
+
+	rwLock *sync.RWMutex
 }
 
-func (t *tfstateSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+func matchesAny(patterns []string, key string) bool {
 
-	tfstates := []*state.Tfstate{}
+	for _, pattern := range patterns {
 
-	s3Client := t.s3Client()
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(key)); ok {
+			return true
+		}
+
+	}
+
+	return false
 
-	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
-		Bucket: aws.String(t.bucket),
-	})
+}
+
+func (t *tfstateSource) workspace(key string) string {
+
+	workspacePrefix := t.workspaceKeyPrefix + "/"
+
+	if !strings.HasPrefix(key, workspacePrefix) {
+		return defaultWorkspace
+	}
+
+	workspace, _, found := strings.Cut(strings.TrimPrefix(key, workspacePrefix), "/")
+	if !found {
+		return defaultWorkspace
+	}
 
-	downloader := s3manager.NewDownloader(s3Client)
+	return workspace
 
-	existingObjects := make(map[string]struct{})
+}
 
-	needUpdate := false
+func (t *tfstateSource) matchesKey(key string) bool {
 
-	for paginator.HasMorePages() {
+	hasPrefix := false
 
-		object, err := paginator.NextPage(ctx)
-		if err != nil {
-			return nil, err
+	for _, prefix := range t.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			hasPrefix = true
+			break
 		}
+	}
+
+	if !hasPrefix {
+		return false
+	}
 
-		objectName := aws.ToString(object.Name)
+	if len(t.workspaces) > 0 {
 
-		if !strings.HasSuffix(objectName, ".tfstate") {
-			continue
+		selected := false
+
+		for _, workspace := range t.workspaces {
+			if t.workspace(key) == workspace {
+				selected = true
+				break
+			}
 		}
 
-		head, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
-			Bucket: aws.String(t.bucket),
-			Key:    object.Name,
-		})
-		if err != nil {
-			return nil, err
+		if !selected {
+			return false
 		}
 
-		etag := aws.ToString(head.ETag)
+	}
 
-		objectFullName := fmt.Sprintf("%s/%s", t.bucket, objectName)
+	return matchesAny(t.includePatterns, key) && !matchesAny(t.excludePatterns, key)
 
-		tfstateObj, ok := t.tfstateObjects[objectFullName]
-		if !ok {
-			tfstateObj = &tfstateObject{}
-		}
+}
+
+func (t *tfstateSource) TfstateObjects() map[string]string {
+
+	t.rwLock.RLock()
+	defer t.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(t.tfstateObjects))
+
+	for objectFullName, tfstateObj := range t.tfstateObjects {
+		objects[objectFullName] = tfstateObj.etag
+	}
 
-		if tfstateObj.etag != etag {
-			buf := make([]byte, head.ContentLength)
+	return objects
+
+}
+
+func (t *tfstateSource) listObjects(ctx context.Context, s3Client *s3.Client) (map[string]string, error) {
+
+	objects := make(map[string]string)
+	signatures := make(map[string]string)
+
+	for _, prefix := range t.prefixes {
+
+		paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(t.bucket),
+			Prefix: aws.String(prefix),
+		})
 
-			writeAtBuf := s3manager.NewWriteAtBuffer(buf)
+		for paginator.HasMorePages() {
 
-			_, err := downloader.Download(ctx, writeAtBuf, &s3.GetObjectInput{
-				Bucket: aws.String(t.bucket),
-				Key:    object.Name,
-			})
+			page, err := paginator.NextPage(ctx)
 			if err != nil {
 				return nil, err
 			}
 
-			tfstate := &state.Tfstate{}
+			for _, object := range page.Contents {
+
+				key := aws.ToString(object.Key)
+
+				if t.verifier != nil && strings.HasSuffix(key, state.TfstateSignatureSuffix) {
+					signatures[strings.TrimSuffix(key, state.TfstateSignatureSuffix)] = aws.ToString(object.ETag)
+					continue
+				}
+
+				if !t.matchesKey(key) {
+					continue
+				}
+
+				objects[key] = aws.ToString(object.ETag)
 
-			if err := json.Unmarshal(writeAtBuf.Bytes(), tfstate); err != nil {
-				return nil, err
 			}
 
-			tfstateObj.tfstate = tfstate
+		}
+
+	}
 
-			needUpdate = true
+	for key, signatureEtag := range signatures {
+		if etag, ok := objects[key]; ok {
+			objects[key] = objectEtag(etag, signatureEtag)
 		}
+	}
 
-		tfstateObj.etag = etag
+	return objects, nil
 
-		tfstates = append(tfstates, tfstateObj.tfstate)
+}
+
+func (t *tfstateSource) downloadObjects(ctx context.Context, s3Client *s3.Client, keys []string) (map[string]*tfstateObject, error) {
+
+	downloaded := make(map[string]*tfstateObject, len(keys))
+
+	lock := new(sync.Mutex)
+	wg := new(sync.WaitGroup)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var firstErr error
 
-		existingObjects[objectFullName] = struct{}{}
+	sem := make(chan struct{}, t.downloadConcurrency)
 
-		t.tfstateObjects[objectFullName] = tfstateObj
+	for _, key := range keys {
+
+		sem <- struct{}{}
+
+		wg.Add(1)
+
+		go func(key string) {
+
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			tfstateObj, err := t.fetchObject(ctx, s3Client, key)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+
+			downloaded[key] = tfstateObj
+
+		}(key)
 
 	}
 
-	if !needUpdate {
-		return nil, nil
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
 	}
 
-	toDelete := []string{}
+	return downloaded, nil
 
-	for objectFullName := range t.tfstateObjects {
-		if _, ok := existingObjects[objectFullName]; ok {
+}
+
+func (t *tfstateSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+
+	invalidated := t.invalidated.Swap(false)
+
+	tfstates, err := t.getTfstate(ctx, invalidated)
+	if err != nil && invalidated {
+		// The current state was not reported, keep it pending for the next poll.
+		t.invalidated.Store(true)
+	}
+
+	return tfstates, err
+
+}
+
+func (t *tfstateSource) getTfstate(ctx context.Context, invalidated bool) ([]*state.Tfstate, error) {
+
+	if t.watching && time.Since(t.lastReconcile) < t.reconcileInterval {
+		return t.getChangedTfstate(ctx, invalidated)
+	}
+
+	s3Client := t.s3Client()
+
+	objects, err := t.listObjects(ctx, s3Client)
+	if err != nil {
+		return nil, err
+	}
+
+	changedKeys := []string{}
+
+	for key, etag := range objects {
+
+		tfstateObj, ok := t.tfstateObjects[fmt.Sprintf("%s/%s", t.bucket, key)]
+		if ok && tfstateObj.etag == etag {
 			continue
 		}
 
-		toDelete = append(toDelete, objectFullName)
+		changedKeys = append(changedKeys, key)
+
+	}
+
+	downloaded, err := t.downloadObjects(ctx, s3Client, changedKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	needUpdate := invalidated
+
+	for key, tfstateObj := range downloaded {
+
+		objectFullName := fmt.Sprintf("%s/%s", t.bucket, key)
+
+		if tfstateObj == nil {
+			delete(t.tfstateObjects, objectFullName)
+		} else {
+			t.tfstateObjects[objectFullName] = tfstateObj
+		}
+
+		needUpdate = true
+
 	}
 
-	for _, objectFullName := range toDelete {
+	for objectFullName := range t.tfstateObjects {
+
+		if _, ok := objects[strings.TrimPrefix(objectFullName, t.bucket+"/")]; ok {
+			continue
+		}
+
 		delete(t.tfstateObjects, objectFullName)
+
+		needUpdate = true
+
+	}
+
+	t.lastReconcile = time.Now()
+	t.pendingKeys = make(map[string]struct{})
+
+	if !needUpdate {
+		return nil, nil
+	}
+
+	tfstates := make([]*state.Tfstate, 0, len(t.tfstateObjects))
+
+	for _, tfstateObj := range t.tfstateObjects {
+		tfstates = append(tfstates, tfstateObj.tfstate)
 	}
 
 	return tfstates, nil
 
 }
+
+func (t *tfstateSource) Invalidate() {
+	t.invalidated.Store(true)
+}
@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetTfstateInvalidation(t *testing.T) {
+
+	source, s3Fake, _ := newTestTfstateSource(t, map[string]string{
+		"routes.tfstate": tfstateContent(1),
+	})
+
+	ctx := context.Background()
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || len(tfstates) != 1 {
+		t.Fatalf("unexpected tfstates %v, %v", tfstates, err)
+	}
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	source.Invalidate()
+
+	// A failing poll keeps the invalidation for the next one.
+	s3Fake.update(func(f *fakeS3) {
+		f.status = http.StatusInternalServerError
+	})
+
+	if _, err := source.GetTfstate(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	s3Fake.update(func(f *fakeS3) {
+		f.status = 0
+	})
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil || len(tfstates) != 1 {
+		t.Fatalf("expected the current tfstates after a failed poll, got %v, %v", tfstates, err)
+	}
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+}
@@ -0,0 +1,304 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type s3EventNotification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+func (t *tfstateSource) handleNotification(body string) bool {
+
+	notification := &s3EventNotification{}
+
+	if err := json.Unmarshal([]byte(body), notification); err != nil {
+		t.log.Warnw("ignoring malformed S3 event notification", "error", err)
+		return false
+	}
+
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+
+	changed := false
+
+	for _, record := range notification.Records {
+
+		if record.S3.Bucket.Name != t.bucket {
+			continue
+		}
+
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") && !strings.HasPrefix(record.EventName, "ObjectRemoved:") {
+			continue
+		}
+
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			key = record.S3.Object.Key
+		}
+
+		if t.verifier != nil {
+			key = strings.TrimSuffix(key, state.TfstateSignatureSuffix)
+		}
+
+		if !t.matchesKey(key) {
+			continue
+		}
+
+		t.pendingKeys[key] = struct{}{}
+
+		changed = true
+
+	}
+
+	return changed
+
+}
+
+func (t *tfstateSource) receiveNotifications(ctx context.Context) (bool, error) {
+
+	out, err := t.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(t.queueUrl),
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     20,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+
+	for _, message := range out.Messages {
+
+		if t.handleNotification(aws.ToString(message.Body)) {
+			changed = true
+		}
+
+		if _, err := t.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(t.queueUrl),
+			ReceiptHandle: message.ReceiptHandle,
+		}); err != nil {
+			t.log.Warnw("error deleting S3 event notification", "error", err)
+		}
+
+	}
+
+	return changed, nil
+
+}
+
+func (t *tfstateSource) Watch(ctx context.Context) <-chan struct{} {
+
+	if t.sqsClient == nil || len(t.queueUrl) == 0 {
+		return nil
+	}
+
+	log := t.log.With("bucket", t.bucket, "queue", t.queueUrl)
+
+	t.rwLock.Lock()
+	t.watching = true
+	t.rwLock.Unlock()
+
+	changes := make(chan struct{}, 1)
+
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+
+		defer close(changes)
+
+		reconcile := time.NewTicker(t.reconcileInterval)
+		defer reconcile.Stop()
+
+		for {
+
+			select {
+
+			case <-ctx.Done():
+				return
+
+			case <-reconcile.C:
+				notify()
+				continue
+
+			default:
+
+			}
+
+			changed, err := t.receiveNotifications(ctx)
+			if err != nil {
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				log.Warnw("error receiving S3 event notifications", "error", err)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+
+				continue
+
+			}
+
+			if changed {
+				notify()
+			}
+
+		}
+
+	}()
+
+	return changes
+
+}
+
+func objectEtag(etag, signatureEtag string) string {
+
+	if len(signatureEtag) == 0 {
+		return etag
+	}
+
+	return fmt.Sprintf("%s+%s", etag, signatureEtag)
+
+}
+
+func (t *tfstateSource) getObject(ctx context.Context, s3Client *s3.Client, key string) ([]byte, string, error) {
+
+	out, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+
+		var noSuchKey *s3types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, "", nil
+		}
+
+		return nil, "", err
+
+	}
+	defer out.Body.Close()
+
+	content, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return content, aws.ToString(out.ETag), nil
+
+}
+
+func (t *tfstateSource) fetchObject(ctx context.Context, s3Client *s3.Client, key string) (*tfstateObject, error) {
+
+	content, etag, err := t.getObject(ctx, s3Client, key)
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	if t.verifier != nil {
+
+		signature, signatureEtag, err := t.getObject(ctx, s3Client, key+state.TfstateSignatureSuffix)
+		if err != nil {
+			return nil, err
+		}
+
+		etag = objectEtag(etag, signatureEtag)
+
+		content, err = t.verifier.Verify(content, signature)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", t.bucket, key, err)
+		}
+
+	}
+
+	if t.decrypter != nil {
+		content, err = t.decrypter.Decrypt(ctx, content)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", t.bucket, key, err)
+		}
+	}
+
+	tfstate := &state.Tfstate{}
+
+	if err := json.Unmarshal(content, tfstate); err != nil {
+		return nil, state.Permanent(fmt.Errorf("%s/%s: %w", t.bucket, key, err))
+	}
+
+	tfstate.Name = key
+
+	return &tfstateObject{
+		tfstate: tfstate,
+		etag:    etag,
+	}, nil
+
+}
+
+func (t *tfstateSource) getChangedTfstate(ctx context.Context, invalidated bool) ([]*state.Tfstate, error) {
+
+	if len(t.pendingKeys) == 0 && !invalidated {
+		return nil, nil
+	}
+
+	s3Client := t.s3Client()
+
+	for key := range t.pendingKeys {
+
+		objectFullName := fmt.Sprintf("%s/%s", t.bucket, key)
+
+		tfstateObj, err := t.fetchObject(ctx, s3Client, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if tfstateObj == nil {
+			delete(t.tfstateObjects, objectFullName)
+		} else {
+			t.tfstateObjects[objectFullName] = tfstateObj
+		}
+
+		delete(t.pendingKeys, key)
+
+	}
+
+	tfstates := make([]*state.Tfstate, 0, len(t.tfstateObjects))
+
+	for _, tfstateObj := range t.tfstateObjects {
+		tfstates = append(tfstates, tfstateObj.tfstate)
+	}
+
+	return tfstates, nil
+
+}
@@ -0,0 +1,419 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+const testBucket = "tfstates"
+
+func tfstateContent(serial int) string {
+	return fmt.Sprintf(`{"version":4,"serial":%d,"lineage":"lineage","resources":[]}`, serial)
+}
+
+func contentEtag(content string) string {
+	sum := md5.Sum([]byte(content))
+	return fmt.Sprintf("%q", hex.EncodeToString(sum[:]))
+}
+
+// fakeS3 serves path style ListObjectsV2 and GetObject requests for a single
+// bucket.
+type fakeS3 struct {
+	mu sync.Mutex
+
+	objects map[string]string
+
+	lists int
+	gets  map[string]int
+
+	status int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != testBucket {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(key) == 0 {
+
+		f.lists++
+
+		keys := make([]string, 0, len(f.objects))
+
+		for key := range f.objects {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		buffer := &bytes.Buffer{}
+
+		fmt.Fprintf(buffer, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>%s</Name><KeyCount>%d</KeyCount><IsTruncated>false</IsTruncated>`, testBucket, len(keys))
+
+		for _, key := range keys {
+			buffer.WriteString("<Contents><Key>")
+			xml.EscapeText(buffer, []byte(key))
+			buffer.WriteString("</Key><ETag>")
+			xml.EscapeText(buffer, []byte(contentEtag(f.objects[key])))
+			fmt.Fprintf(buffer, "</ETag><Size>%d</Size></Contents>", len(f.objects[key]))
+		}
+
+		buffer.WriteString("</ListBucketResult>")
+
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write(buffer.Bytes())
+
+		return
+
+	}
+
+	f.gets[key]++
+
+	content, ok := f.objects[key]
+	if !ok {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+		return
+	}
+
+	w.Header().Set("ETag", contentEtag(content))
+	fmt.Fprint(w, content)
+
+}
+
+func (f *fakeS3) update(fn func(*fakeS3)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+// fakeSqs answers ReceiveMessage with at most one queued notification and
+// records deleted receipt handles.
+type fakeSqs struct {
+	messages chan string
+
+	mu      sync.Mutex
+	deleted int
+}
+
+func (f *fakeSqs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+
+	switch r.Form.Get("Action") {
+
+	case "ReceiveMessage":
+
+		buffer := &bytes.Buffer{}
+
+		buffer.WriteString("<ReceiveMessageResponse><ReceiveMessageResult>")
+
+		select {
+
+		case body := <-f.messages:
+
+			sum := md5.Sum([]byte(body))
+
+			buffer.WriteString("<Message><MessageId>message</MessageId><ReceiptHandle>receipt</ReceiptHandle><MD5OfBody>")
+			buffer.WriteString(hex.EncodeToString(sum[:]))
+			buffer.WriteString("</MD5OfBody><Body>")
+			xml.EscapeText(buffer, []byte(body))
+			buffer.WriteString("</Body></Message>")
+
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Millisecond):
+
+		}
+
+		buffer.WriteString("</ReceiveMessageResult><ResponseMetadata><RequestId>request</RequestId></ResponseMetadata></ReceiveMessageResponse>")
+
+		w.Write(buffer.Bytes())
+
+	case "DeleteMessage":
+
+		f.mu.Lock()
+		f.deleted++
+		f.mu.Unlock()
+
+		fmt.Fprint(w, "<DeleteMessageResponse><ResponseMetadata><RequestId>request</RequestId></ResponseMetadata></DeleteMessageResponse>")
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+
+	}
+
+}
+
+func notification(bucket, eventName, key string) string {
+	return fmt.Sprintf(`{"Records":[{"eventName":%q,"s3":{"bucket":{"name":%q},"object":{"key":%q}}}]}`, eventName, bucket, key)
+}
+
+func newTestTfstateSource(t *testing.T, objects map[string]string, opts ...TfstateSourceOption) (*tfstateSource, *fakeS3, *fakeSqs) {
+
+	s3Fake := &fakeS3{objects: objects, gets: make(map[string]int)}
+	sqsFake := &fakeSqs{messages: make(chan string, 10)}
+
+	s3Srv := httptest.NewServer(s3Fake)
+	t.Cleanup(s3Srv.Close)
+
+	sqsSrv := httptest.NewServer(sqsFake)
+	t.Cleanup(sqsSrv.Close)
+
+	s3Client := s3.New(s3.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(s3Srv.URL),
+		UsePathStyle:     true,
+		Credentials:      aws.AnonymousCredentials{},
+		HTTPClient:       s3Srv.Client(),
+		RetryMaxAttempts: 1,
+	})
+
+	sqsClient := sqs.New(sqs.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(sqsSrv.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		HTTPClient:       sqsSrv.Client(),
+		RetryMaxAttempts: 1,
+	})
+
+	source := NewTfstateSource(append([]TfstateSourceOption{
+		WithTfstateBucket(testBucket),
+		WithS3ClientFactory(func() *s3.Client { return s3Client }),
+		WithNotificationQueue(sqsClient, sqsSrv.URL+"/queue/tfstates"),
+	}, opts...)...).(*tfstateSource)
+
+	return source, s3Fake, sqsFake
+
+}
+
+func waitChange(t *testing.T, changes <-chan struct{}) {
+
+	t.Helper()
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notified")
+	}
+
+}
+
+func serials(tfstates []*state.Tfstate) map[string]int64 {
+
+	serials := make(map[string]int64, len(tfstates))
+
+	for _, tfstate := range tfstates {
+		serials[tfstate.Name] = tfstate.Serial
+	}
+
+	return serials
+
+}
+
+func TestHandleNotification(t *testing.T) {
+
+	source := NewTfstateSource(WithTfstateBucket(testBucket)).(*tfstateSource)
+
+	cases := []struct {
+		name    string
+		body    string
+		changed bool
+		pending string
+	}{
+		{name: "created", body: notification(testBucket, "ObjectCreated:Put", "routes.tfstate"), changed: true, pending: "routes.tfstate"},
+		{name: "removed", body: notification(testBucket, "ObjectRemoved:Delete", "network.tfstate"), changed: true, pending: "network.tfstate"},
+		{name: "url encoded key", body: notification(testBucket, "ObjectCreated:Put", "env%3A/prod/routes+v2.tfstate"), changed: true, pending: "env:/prod/routes v2.tfstate"},
+		{name: "other bucket", body: notification("other", "ObjectCreated:Put", "routes.tfstate")},
+		{name: "other event", body: notification(testBucket, "ObjectTagging:Put", "routes.tfstate")},
+		{name: "excluded key", body: notification(testBucket, "ObjectCreated:Put", "notes.txt")},
+		{name: "test event", body: `{"Service":"Amazon S3","Event":"s3:TestEvent"}`},
+		{name: "malformed", body: "not json"},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			source.pendingKeys = make(map[string]struct{})
+
+			if changed := source.handleNotification(c.body); changed != c.changed {
+				t.Fatalf("expected changed %v, got %v", c.changed, changed)
+			}
+
+			if _, ok := source.pendingKeys[c.pending]; len(c.pending) > 0 && !ok {
+				t.Errorf("key %q not pending: %v", c.pending, source.pendingKeys)
+			}
+
+			if !c.changed && len(source.pendingKeys) > 0 {
+				t.Errorf("unexpected pending keys %v", source.pendingKeys)
+			}
+
+		})
+
+	}
+
+}
+
+func TestWatchRefetchesNotifiedObjects(t *testing.T) {
+
+	source, s3Fake, sqsFake := newTestTfstateSource(t, map[string]string{
+		"network.tfstate": tfstateContent(1),
+		"routes.tfstate":  tfstateContent(1),
+		"notes.txt":       "notes",
+	}, WithReconcileInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := source.Watch(ctx)
+	if changes == nil {
+		t.Fatal("watch disabled with a notification queue")
+	}
+
+	// The first sync is a full reconciliation.
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(tfstates); len(got) != 2 || got["network.tfstate"] != 1 || got["routes.tfstate"] != 1 {
+		t.Fatalf("unexpected tfstates %v", got)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected no change without notifications, got %v, %v", tfstates, err)
+	}
+
+	s3Fake.update(func(f *fakeS3) {
+		f.objects["routes.tfstate"] = tfstateContent(2)
+	})
+
+	sqsFake.messages <- notification("other", "ObjectCreated:Put", "routes.tfstate")
+	sqsFake.messages <- notification(testBucket, "ObjectCreated:Put", "routes.tfstate")
+
+	waitChange(t, changes)
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(tfstates); len(got) != 2 || got["network.tfstate"] != 1 || got["routes.tfstate"] != 2 {
+		t.Fatalf("unexpected tfstates %v", got)
+	}
+
+	s3Fake.update(func(f *fakeS3) {
+		delete(f.objects, "network.tfstate")
+	})
+
+	sqsFake.messages <- notification(testBucket, "ObjectRemoved:Delete", "network.tfstate")
+
+	waitChange(t, changes)
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(tfstates); len(got) != 1 || got["routes.tfstate"] != 2 {
+		t.Fatalf("unexpected tfstates %v", got)
+	}
+
+	// Only the notified objects were fetched again, without listing the bucket.
+	s3Fake.update(func(f *fakeS3) {
+		if f.lists != 1 || f.gets["routes.tfstate"] != 2 || f.gets["network.tfstate"] != 2 {
+			t.Errorf("unexpected requests: %d lists, gets %v", f.lists, f.gets)
+		}
+	})
+
+	sqsFake.mu.Lock()
+	defer sqsFake.mu.Unlock()
+
+	if sqsFake.deleted != 3 {
+		t.Errorf("expected 3 deleted notifications, got %d", sqsFake.deleted)
+	}
+
+}
+
+func TestWatchReconciles(t *testing.T) {
+
+	source, s3Fake, _ := newTestTfstateSource(t, map[string]string{
+		"routes.tfstate": tfstateContent(1),
+	}, WithReconcileInterval(50*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := source.Watch(ctx)
+
+	if _, err := source.GetTfstate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missed notification is picked up by the next reconciliation.
+	s3Fake.update(func(f *fakeS3) {
+		f.objects["routes.tfstate"] = tfstateContent(2)
+	})
+
+	waitChange(t, changes)
+	waitChange(t, changes)
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(tfstates); got["routes.tfstate"] != 2 {
+		t.Fatalf("unexpected tfstates %v", got)
+	}
+
+	s3Fake.update(func(f *fakeS3) {
+		if f.lists != 2 {
+			t.Errorf("expected 2 lists, got %d", f.lists)
+		}
+	})
+
+}
+
+func TestWatchDisabledWithoutQueue(t *testing.T) {
+
+	source := NewTfstateSource(WithTfstateBucket(testBucket)).(state.TfstateWatcher)
+
+	if changes := source.Watch(context.Background()); changes != nil {
+		t.Error("watch enabled without a notification queue")
+	}
+
+}
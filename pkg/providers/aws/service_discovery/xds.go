@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	"github.com/ultraviolet-black/cruiser/pkg/state"
 )
 
@@ -220,6 +221,7 @@ func (x *xds) periodicSync(ctx context.Context) {
 	for {
 
 		if err := x.sync(ctx); err != nil {
+			observability.LogFromContext(ctx).Errorw("error synchronizing service discovery", "error", err)
 			x.errCh <- err
 			return
 		}
@@ -243,7 +245,7 @@ func (x *xds) periodicSync(ctx context.Context) {
 }
 
 func (x *xds) Start(ctx context.Context) {
-	go x.periodicSync(ctx)
+	go x.periodicSync(observability.ContextWithLogFields(ctx, "component", "servicediscovery", "namespaces", x.namespacesNames))
 }
 
 func (x *xds) ErrorCh() <-chan error {
@@ -0,0 +1,65 @@
+package file
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+type TfstateSourceOption func(*tfstateSource)
+
+func WithDirectory(directory string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.directory = directory
+	}
+}
+
+func WithPatterns(patterns ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.patterns = patterns
+	}
+}
+
+func WithDecrypter(decrypter state.TfstateDecrypter) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.decrypter = decrypter
+	}
+}
+
+func WithVerifier(verifier state.TfstateVerifier) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.verifier = verifier
+	}
+}
+
+func WithDebounce(debounce time.Duration) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.debounce = debounce
+	}
+}
+
+func WithLogger(log *zap.SugaredLogger) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.log = log
+	}
+}
+
+func NewTfstateSource(opts ...TfstateSourceOption) state.TfstateSource {
+
+	t := &tfstateSource{
+		patterns:     []string{"*.tfstate"},
+		debounce:     100 * time.Millisecond,
+		tfstateFiles: make(map[string]*tfstateFile),
+		log:          zap.NewNop().Sugar(),
+		rwLock:       new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+
+}
@@ -0,0 +1,7 @@
+package file
+
+import "errors"
+
+var (
+	ErrEmptyDirectory = errors.New("empty tfstate directory")
+)
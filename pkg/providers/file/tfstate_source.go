@@ -0,0 +1,324 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+type tfstateFile struct {
+	tfstate *state.Tfstate
+	hash    string
+}
+
+type tfstateSource struct {
+	directory string
+	patterns  []string
+	debounce  time.Duration
+
+	tfstateFiles map[string]*tfstateFile
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	invalidated atomic.Bool
+
+	log *zap.SugaredLogger
+
+	rwLock *sync.RWMutex
+}
+
+func (t *tfstateSource) matches(path string) bool {
+
+	rel, err := filepath.Rel(t.directory, path)
+	if err != nil {
+		return false
+	}
+
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range t.patterns {
+
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+func (t *tfstateSource) isSignature(path string) bool {
+	return t.verifier != nil && strings.HasSuffix(path, state.TfstateSignatureSuffix)
+}
+
+func (t *tfstateSource) readSignature(path string) ([]byte, error) {
+
+	if t.verifier == nil {
+		return nil, nil
+	}
+
+	signature, err := os.ReadFile(path + state.TfstateSignatureSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return signature, err
+
+}
+
+func (t *tfstateSource) TfstateObjects() map[string]string {
+
+	t.rwLock.RLock()
+	defer t.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(t.tfstateFiles))
+
+	for path, file := range t.tfstateFiles {
+		objects[path] = file.hash
+	}
+
+	return objects
+
+}
+
+func (t *tfstateSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+
+	if len(t.directory) == 0 {
+		return nil, state.Permanent(ErrEmptyDirectory)
+	}
+
+	tfstates := []*state.Tfstate{}
+
+	existingFiles := make(map[string]struct{})
+
+	needUpdate := t.invalidated.Swap(false)
+
+	err := filepath.WalkDir(t.directory, func(path string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || t.isSignature(path) || !t.matches(path) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		signature, err := t.readSignature(path)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.New()
+		sum.Write(content)
+		sum.Write(signature)
+
+		hash := hex.EncodeToString(sum.Sum(nil))
+
+		file, ok := t.tfstateFiles[path]
+		if !ok {
+			file = &tfstateFile{}
+		}
+
+		if file.hash != hash {
+
+			if t.verifier != nil {
+				content, err = t.verifier.Verify(content, signature)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			}
+
+			if t.decrypter != nil {
+				content, err = t.decrypter.Decrypt(ctx, content)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			}
+
+			tfstate := &state.Tfstate{}
+
+			if err := json.Unmarshal(content, tfstate); err != nil {
+				return state.Permanent(fmt.Errorf("%s: %w", path, err))
+			}
+
+			if rel, err := filepath.Rel(t.directory, path); err == nil {
+				tfstate.Name = filepath.ToSlash(rel)
+			}
+
+			file.tfstate = tfstate
+			file.hash = hash
+
+			needUpdate = true
+
+		}
+
+		tfstates = append(tfstates, file.tfstate)
+
+		existingFiles[path] = struct{}{}
+
+		t.tfstateFiles[path] = file
+
+		return nil
+
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	toDelete := []string{}
+
+	for path := range t.tfstateFiles {
+		if _, ok := existingFiles[path]; ok {
+			continue
+		}
+
+		toDelete = append(toDelete, path)
+	}
+
+	for _, path := range toDelete {
+		delete(t.tfstateFiles, path)
+	}
+
+	if !needUpdate && len(toDelete) == 0 {
+		return nil, nil
+	}
+
+	return tfstates, nil
+
+}
+
+func (t *tfstateSource) Invalidate() {
+	t.invalidated.Store(true)
+}
+
+func (t *tfstateSource) addWatches(watcher *fsnotify.Watcher, root string) error {
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		return watcher.Add(path)
+
+	})
+
+}
+
+func (t *tfstateSource) Watch(ctx context.Context) <-chan struct{} {
+
+	log := t.log
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorw("error creating file watcher, falling back to polling", "error", err)
+		return nil
+	}
+
+	if err := t.addWatches(watcher, t.directory); err != nil {
+		log.Errorw("error watching tfstate directory, falling back to polling", "error", err)
+		watcher.Close()
+		return nil
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+
+		defer close(changes)
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+
+		for {
+			select {
+
+			case <-ctx.Done():
+				return
+
+			case event, ok := <-watcher.Events:
+
+				if !ok {
+					return
+				}
+
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := t.addWatches(watcher, event.Name); err != nil {
+							log.Warnw("error watching new directory", "path", event.Name, "error", err)
+						}
+						continue
+					}
+				}
+
+				name := event.Name
+
+				if t.isSignature(name) {
+					name = strings.TrimSuffix(name, state.TfstateSignatureSuffix)
+				}
+
+				if !t.matches(name) {
+					continue
+				}
+
+				if debounce == nil {
+					debounce = time.After(t.debounce)
+				}
+
+			case <-debounce:
+
+				debounce = nil
+
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+
+			case err, ok := <-watcher.Errors:
+
+				if !ok {
+					return
+				}
+
+				log.Warnw("file watcher error", "error", err)
+
+			}
+		}
+
+	}()
+
+	return changes
+
+}
@@ -0,0 +1,227 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+func tfstateContent(serial int) string {
+	return fmt.Sprintf(`{"version":4,"serial":%d,"lineage":"lineage","resources":[]}`, serial)
+}
+
+func writeFile(t *testing.T, path, content string) {
+
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+}
+
+func names(tfstates []*state.Tfstate) []string {
+
+	names := make([]string, 0, len(tfstates))
+
+	for _, tfstate := range tfstates {
+		names = append(names, tfstate.Name)
+	}
+
+	sort.Strings(names)
+
+	return names
+
+}
+
+func TestTfstateSourcePatterns(t *testing.T) {
+
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "routes.tfstate"), tfstateContent(1))
+	writeFile(t, filepath.Join(dir, "envs", "prod", "network.tfstate"), tfstateContent(1))
+	writeFile(t, filepath.Join(dir, "envs", "prod", "notes.txt"), "not a tfstate")
+	writeFile(t, filepath.Join(dir, "routes.tfstate.backup"), tfstateContent(0))
+
+	cases := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{name: "default", expected: []string{"envs/prod/network.tfstate", "routes.tfstate"}},
+		{name: "relative path", patterns: []string{"envs/*/*.tfstate"}, expected: []string{"envs/prod/network.tfstate"}},
+		{name: "base name", patterns: []string{"r*.tfstate"}, expected: []string{"routes.tfstate"}},
+		{name: "no match", patterns: []string{"*.json"}, expected: []string{}},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			opts := []TfstateSourceOption{WithDirectory(dir)}
+
+			if c.patterns != nil {
+				opts = append(opts, WithPatterns(c.patterns...))
+			}
+
+			tfstates, err := NewTfstateSource(opts...).GetTfstate(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := names(tfstates); fmt.Sprint(got) != fmt.Sprint(c.expected) {
+				t.Fatalf("expected %v, got %v", c.expected, got)
+			}
+
+		})
+
+	}
+
+}
+
+func TestTfstateSourceChanges(t *testing.T) {
+
+	dir := t.TempDir()
+
+	routes := filepath.Join(dir, "routes.tfstate")
+	network := filepath.Join(dir, "network.tfstate")
+
+	writeFile(t, routes, tfstateContent(1))
+	writeFile(t, network, tfstateContent(1))
+
+	source := NewTfstateSource(WithDirectory(dir))
+
+	ctx := context.Background()
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || len(tfstates) != 2 {
+		t.Fatalf("unexpected tfstates %v, %v", tfstates, err)
+	}
+
+	// Rewriting the same content keeps the hash.
+	writeFile(t, routes, tfstateContent(1))
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	writeFile(t, routes, tfstateContent(2))
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil || len(tfstates) != 2 {
+		t.Fatalf("expected the changed tfstates, got %v, %v", tfstates, err)
+	}
+
+	for _, tfstate := range tfstates {
+		if tfstate.Name == "routes.tfstate" && tfstate.Serial != 2 {
+			t.Fatalf("expected serial 2, got %d", tfstate.Serial)
+		}
+	}
+
+	// A deleted file is removed from the state.
+	if err := os.Remove(network); err != nil {
+		t.Fatal(err)
+	}
+
+	tfstates, err = source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(tfstates); len(got) != 1 || got[0] != "routes.tfstate" {
+		t.Fatalf("deleted file still reported: %v", got)
+	}
+
+	if objects := source.(state.TfstateObjectsReporter).TfstateObjects(); len(objects) != 1 || objects[routes] == "" {
+		t.Fatalf("unexpected objects %v", objects)
+	}
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	source.(state.TfstateInvalidator).Invalidate()
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || len(tfstates) != 1 {
+		t.Fatalf("expected the current tfstates after invalidation, got %v, %v", tfstates, err)
+	}
+
+}
+
+func TestTfstateSourceWatch(t *testing.T) {
+
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "routes.tfstate"), tfstateContent(1))
+
+	source := NewTfstateSource(WithDirectory(dir), WithDebounce(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := source.(state.TfstateWatcher).Watch(ctx)
+	if changes == nil {
+		t.Fatal("watch not enabled")
+	}
+
+	expectChange := func(changed bool) {
+
+		t.Helper()
+
+		timeout := 5 * time.Second
+		if !changed {
+			timeout = 100 * time.Millisecond
+		}
+
+		select {
+		case <-changes:
+			if !changed {
+				t.Fatal("unexpected change notified")
+			}
+		case <-time.After(timeout):
+			if changed {
+				t.Fatal("no change notified")
+			}
+		}
+
+	}
+
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not a tfstate")
+	expectChange(false)
+
+	writeFile(t, filepath.Join(dir, "routes.tfstate"), tfstateContent(2))
+	expectChange(true)
+
+	// Files in directories created after the watch started are watched too.
+	if err := os.Mkdir(filepath.Join(dir, "envs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	writeFile(t, filepath.Join(dir, "envs", "network.tfstate"), tfstateContent(1))
+	expectChange(true)
+
+	cancel()
+
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("changes not closed after the context was cancelled")
+		}
+	}
+
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+type TfstateSourceOption func(*tfstateSource)
+
+func WithRepository(repository string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.repository = repository
+	}
+}
+
+func WithRef(ref string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.ref = ref
+	}
+}
+
+func WithDirectory(directory string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.directory = directory
+	}
+}
+
+func WithPatterns(patterns ...string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.patterns = patterns
+	}
+}
+
+func WithDecrypter(decrypter state.TfstateDecrypter) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.decrypter = decrypter
+	}
+}
+
+func WithVerifier(verifier state.TfstateVerifier) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.verifier = verifier
+	}
+}
+
+func WithCloneDirectory(cloneDirectory string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.cloneDirectory = cloneDirectory
+	}
+}
+
+func WithAuth(auth transport.AuthMethod) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.auth = auth
+	}
+}
+
+func WithBasicAuth(username, password string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		if len(username) == 0 && len(password) == 0 {
+			return
+		}
+		t.auth = &http.BasicAuth{
+			Username: username,
+			Password: password,
+		}
+	}
+}
+
+func WithSSHKeyFile(user, keyFile, password string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.sshUser = user
+		t.sshKeyFile = keyFile
+		t.sshKeyPassword = password
+	}
+}
+
+func WithLogger(log *zap.SugaredLogger) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.log = log
+	}
+}
+
+func NewTfstateSource(opts ...TfstateSourceOption) (state.TfstateSource, error) {
+
+	t := &tfstateSource{
+		ref:          "HEAD",
+		patterns:     DefaultPatterns,
+		sshUser:      "git",
+		tfstateFiles: make(map[string]*state.Tfstate),
+		log:          zap.NewNop().Sugar(),
+		rwLock:       new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if len(t.repository) == 0 {
+		return nil, ErrEmptyRepository
+	}
+
+	if len(t.sshKeyFile) > 0 {
+
+		auth, err := ssh.NewPublicKeysFromFile(t.sshUser, t.sshKeyFile, t.sshKeyPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		t.auth = auth
+
+	}
+
+	return t, nil
+
+}
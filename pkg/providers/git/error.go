@@ -0,0 +1,8 @@
+package git
+
+import "errors"
+
+var (
+	ErrEmptyRepository  = errors.New("empty git repository")
+	ErrRevisionNotFound = errors.New("git revision not found")
+)
@@ -0,0 +1,382 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	cruiserconfig "github.com/ultraviolet-black/cruiser/pkg/config"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"go.uber.org/zap"
+)
+
+const remoteName = "origin"
+
+// DefaultPatterns select tfstate files and route and xDS definitions in the
+// native config formats.
+var DefaultPatterns = []string{"*.tfstate", "*.yaml", "*.yml", "*.json", "*.textproto", "*.txtpb", "*.pbtxt", "*.prototext"}
+
+var fetchRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+type tfstateSource struct {
+	repository     string
+	ref            string
+	directory      string
+	patterns       []string
+	cloneDirectory string
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	auth           transport.AuthMethod
+	sshUser        string
+	sshKeyFile     string
+	sshKeyPassword string
+
+	repo *gogit.Repository
+
+	commit       string
+	tfstateFiles map[string]*state.Tfstate
+
+	invalidated atomic.Bool
+
+	log *zap.SugaredLogger
+
+	rwLock *sync.RWMutex
+}
+
+func (t *tfstateSource) matches(filePath string) bool {
+
+	for _, pattern := range t.patterns {
+
+		if ok, _ := path.Match(pattern, filePath); ok {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(filePath)); ok {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+func (t *tfstateSource) isSignature(filePath string) bool {
+	return t.verifier != nil && strings.HasSuffix(filePath, state.TfstateSignatureSuffix)
+}
+
+func readSignature(tree *object.Tree, filePath string) ([]byte, error) {
+
+	file, err := tree.File(filePath + state.TfstateSignatureSuffix)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(signature), nil
+
+}
+
+// decode reads tfstate files as terraform state and every other file as a
+// cruiser config in the format given by its extension.
+func decode(filePath string, data []byte) (*state.Tfstate, error) {
+
+	format, err := cruiserconfig.FormatFromPath(filePath)
+	if err != nil {
+
+		tfstate := &state.Tfstate{}
+
+		if err := json.Unmarshal(data, tfstate); err != nil {
+			return nil, err
+		}
+
+		return tfstate, nil
+
+	}
+
+	c, err := cruiserconfig.Unmarshal(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ToTfstate()
+
+}
+
+func (t *tfstateSource) TfstateObjects() map[string]string {
+
+	t.rwLock.RLock()
+	defer t.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(t.tfstateFiles))
+
+	for filePath := range t.tfstateFiles {
+		objects[fmt.Sprintf("%s//%s", t.repository, filePath)] = t.commit
+	}
+
+	return objects
+
+}
+
+func (t *tfstateSource) initRepository() (*gogit.Repository, error) {
+
+	var (
+		repo *gogit.Repository
+		err  error
+	)
+
+	if len(t.cloneDirectory) == 0 {
+		repo, err = gogit.Init(memory.NewStorage(), nil)
+	} else {
+		repo, err = gogit.PlainOpen(t.cloneDirectory)
+		if errors.Is(err, gogit.ErrRepositoryNotExists) {
+			repo, err = gogit.PlainInit(t.cloneDirectory, true)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := repo.Remote(remoteName); errors.Is(err, gogit.ErrRemoteNotFound) {
+
+		if _, err := repo.CreateRemote(&config.RemoteConfig{
+			Name: remoteName,
+			URLs: []string{t.repository},
+		}); err != nil {
+			return nil, err
+		}
+
+	} else if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+
+}
+
+func (t *tfstateSource) syncHead(ctx context.Context, repo *gogit.Repository) error {
+
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return err
+	}
+
+	refs, err := remote.ListContext(ctx, &gogit.ListOptions{
+		Auth: t.auth,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+
+		if ref.Name() != plumbing.HEAD {
+			continue
+		}
+
+		if ref.Type() == plumbing.SymbolicReference {
+			return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref.Target()))
+		}
+
+		return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, ref.Hash()))
+
+	}
+
+	return nil
+
+}
+
+func (t *tfstateSource) open(ctx context.Context) (*gogit.Repository, error) {
+
+	endpoint, err := transport.NewEndpoint(t.repository)
+	if err != nil {
+		return nil, state.Permanent(err)
+	}
+
+	if endpoint.Protocol == "file" {
+		return gogit.PlainOpen(endpoint.Path)
+	}
+
+	if t.repo == nil {
+
+		repo, err := t.initRepository()
+		if err != nil {
+			return nil, err
+		}
+
+		t.repo = repo
+
+	}
+
+	err = t.repo.FetchContext(ctx, &gogit.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   fetchRefSpecs,
+		Auth:       t.auth,
+		Tags:       gogit.NoTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+
+	if plumbing.ReferenceName(t.ref) == plumbing.HEAD {
+		if err := t.syncHead(ctx, t.repo); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.repo, nil
+
+}
+
+func (t *tfstateSource) readTfstates(ctx context.Context, commit *object.Commit) (map[string]*state.Tfstate, error) {
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	directory := path.Clean(t.directory)
+
+	if directory != "." {
+		tree, err = tree.Tree(directory)
+		if err != nil {
+			return nil, state.Permanent(fmt.Errorf("%s@%s: %w", directory, commit.Hash, err))
+		}
+	}
+
+	tfstates := make(map[string]*state.Tfstate)
+
+	err = tree.Files().ForEach(func(file *object.File) error {
+
+		if t.isSignature(file.Name) || !t.matches(file.Name) {
+			return nil
+		}
+
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+
+		filePath := path.Join(directory, file.Name)
+
+		data := []byte(content)
+
+		if t.verifier != nil {
+
+			signature, err := readSignature(tree, file.Name)
+			if err != nil {
+				return err
+			}
+
+			data, err = t.verifier.Verify(data, signature)
+			if err != nil {
+				return fmt.Errorf("%s@%s: %w", filePath, commit.Hash, err)
+			}
+
+		}
+
+		if t.decrypter != nil {
+			data, err = t.decrypter.Decrypt(ctx, data)
+			if err != nil {
+				return fmt.Errorf("%s@%s: %w", filePath, commit.Hash, err)
+			}
+		}
+
+		tfstate, err := decode(filePath, data)
+		if err != nil {
+			return state.Permanent(fmt.Errorf("%s@%s: %w", filePath, commit.Hash, err))
+		}
+
+		tfstate.Name = filePath
+
+		tfstates[filePath] = tfstate
+
+		return nil
+
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tfstates, nil
+
+}
+
+func (t *tfstateSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+
+	repo, err := t.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(t.ref))
+	if err != nil {
+		return nil, state.Permanent(fmt.Errorf("%w: %s: %v", ErrRevisionNotFound, t.ref, err))
+	}
+
+	invalidated := t.invalidated.Swap(false)
+
+	if hash.String() == t.commit && !invalidated {
+		return nil, nil
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tfstateFiles, err := t.readTfstates(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash.String() != t.commit {
+		t.log.Infow("git revision changed",
+			"previous", t.commit,
+			"commit", hash.String(),
+		)
+	}
+
+	t.commit = hash.String()
+	t.tfstateFiles = tfstateFiles
+
+	tfstates := make([]*state.Tfstate, 0, len(tfstateFiles))
+
+	for _, tfstate := range tfstateFiles {
+		tfstates = append(tfstates, tfstate)
+	}
+
+	return tfstates, nil
+
+}
+
+func (t *tfstateSource) Invalidate() {
+	t.invalidated.Store(true)
+}
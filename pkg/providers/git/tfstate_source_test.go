@@ -0,0 +1,403 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+// Remote repositories are served in process under this scheme, so the fetch
+// path is exercised without a git binary or a network server.
+const testScheme = "gittest"
+
+func TestMain(m *testing.M) {
+
+	if err := observability.InitializeLog(observability.WithLogLevel("fatal")); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+
+}
+
+type testRepository struct {
+	t *testing.T
+
+	work *gogit.Repository
+	dir  string
+
+	// url of the bare repository the work repository pushes to
+	url string
+}
+
+// newTestRepository creates a work repository and a local bare repository
+// served under the test scheme.
+func newTestRepository(t *testing.T) *testRepository {
+
+	dir := t.TempDir()
+
+	work, err := gogit.PlainInit(filepath.Join(dir, "work"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bareDir := filepath.Join(dir, "bare.git")
+
+	bare, err := gogit.PlainInit(bareDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := fmt.Sprintf("%s://localhost%s", testScheme, bareDir)
+
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.InstallProtocol(testScheme, server.NewServer(server.MapLoader{endpoint.String(): bare.Storer}))
+	t.Cleanup(func() { client.InstallProtocol(testScheme, nil) })
+
+	if _, err := work.CreateRemote(&config.RemoteConfig{Name: remoteName, URLs: []string{url}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testRepository{t: t, work: work, dir: filepath.Join(dir, "work"), url: url}
+
+}
+
+// commit writes and removes files in the work repository, commits and pushes
+// the changes to the bare repository.
+func (r *testRepository) commit(files map[string]string, removed ...string) string {
+
+	r.t.Helper()
+
+	worktree, err := r.work.Worktree()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	for name, content := range files {
+
+		filePath := filepath.Join(r.dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+
+		if _, err := worktree.Add(name); err != nil {
+			r.t.Fatal(err)
+		}
+
+	}
+
+	for _, name := range removed {
+		if _, err := worktree.Remove(name); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+
+	hash, err := worktree.Commit("update", &gogit.CommitOptions{
+		AllowEmptyCommits: true,
+		Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	if err := r.work.Push(&gogit.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/heads/*"},
+	}); err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		r.t.Fatal(err)
+	}
+
+	return hash.String()
+
+}
+
+func tfstateContent(serial int) string {
+	return fmt.Sprintf(`{"version":4,"serial":%d,"lineage":"lineage","resources":[]}`, serial)
+}
+
+func serials(tfstates []*state.Tfstate) map[string]int64 {
+
+	serials := make(map[string]int64, len(tfstates))
+
+	for _, tfstate := range tfstates {
+		serials[tfstate.Name] = tfstate.Serial
+	}
+
+	return serials
+
+}
+
+func objectNames(source state.TfstateSource, commit string) ([]string, error) {
+
+	names := []string{}
+
+	for name, version := range source.(state.TfstateObjectsReporter).TfstateObjects() {
+
+		if version != commit {
+			return nil, fmt.Errorf("%s reported at %s, expected %s", name, version, commit)
+		}
+
+		names = append(names, name)
+
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+
+}
+
+func testSync(t *testing.T, r *testRepository, repository string, opts ...TfstateSourceOption) {
+
+	ctx := context.Background()
+
+	first := r.commit(map[string]string{
+		"states/network.tfstate": tfstateContent(1),
+		"states/routes.tfstate":  tfstateContent(1),
+		"states/README.md":       "states",
+		"other/ignored.tfstate":  tfstateContent(1),
+	})
+
+	source, err := NewTfstateSource(append([]TfstateSourceOption{
+		WithRepository(repository),
+		WithDirectory("states"),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(tfstates); len(got) != 2 || got["states/network.tfstate"] != 1 || got["states/routes.tfstate"] != 1 {
+		t.Fatalf("unexpected tfstates %v", got)
+	}
+
+	names, err := objectNames(source, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 2 || names[0] != repository+"//states/network.tfstate" {
+		t.Fatalf("unexpected objects %v", names)
+	}
+
+	// The same commit is not reported again.
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	second := r.commit(map[string]string{"states/routes.tfstate": tfstateContent(2)}, "states/network.tfstate")
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := serials(tfstates); len(got) != 1 || got["states/routes.tfstate"] != 2 {
+		t.Fatalf("unexpected tfstates %v", got)
+	}
+
+	if _, err = objectNames(source, second); err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalidated source reports the current commit again.
+	source.(state.TfstateInvalidator).Invalidate()
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 1 {
+		t.Fatalf("expected the current tfstates after invalidation, got %v, %v", tfstates, err)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+}
+
+func TestTfstateSourceLocalRepository(t *testing.T) {
+
+	r := newTestRepository(t)
+
+	testSync(t, r, r.dir)
+
+}
+
+func TestTfstateSourceFetch(t *testing.T) {
+
+	r := newTestRepository(t)
+
+	testSync(t, r, r.url)
+
+}
+
+func TestTfstateSourceFetchToCloneDirectory(t *testing.T) {
+
+	r := newTestRepository(t)
+
+	cloneDirectory := filepath.Join(t.TempDir(), "clone")
+
+	testSync(t, r, r.url, WithCloneDirectory(cloneDirectory))
+
+	if _, err := gogit.PlainOpen(cloneDirectory); err != nil {
+		t.Fatalf("clone directory not initialized: %v", err)
+	}
+
+	// A restarted source reuses the clone directory.
+	source, err := NewTfstateSource(WithRepository(r.url), WithDirectory("states"), WithCloneDirectory(cloneDirectory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tfstates, err := source.GetTfstate(context.Background()); err != nil || len(tfstates) != 1 {
+		t.Fatalf("unexpected tfstates %v, %v", tfstates, err)
+	}
+
+}
+
+func TestTfstateSourceRef(t *testing.T) {
+
+	r := newTestRepository(t)
+
+	first := r.commit(map[string]string{"routes.tfstate": tfstateContent(1)})
+
+	head, err := r.work.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.work.Storer.SetReference(plumbing.NewHashReference("refs/heads/release", plumbing.NewHash(first))); err != nil {
+		t.Fatal(err)
+	}
+
+	r.commit(map[string]string{"routes.tfstate": tfstateContent(2)})
+
+	ctx := context.Background()
+
+	cases := map[string]int64{
+		"release":             1,
+		head.Name().String():  2,
+		string(plumbing.HEAD): 2,
+	}
+
+	for ref, serial := range cases {
+
+		source, err := NewTfstateSource(WithRepository(r.url), WithRef(ref))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tfstates, err := source.GetTfstate(ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+
+		if got := serials(tfstates); got["routes.tfstate"] != serial {
+			t.Errorf("%s: expected serial %d, got %v", ref, serial, got)
+		}
+
+	}
+
+	source, err := NewTfstateSource(WithRepository(r.url), WithRef("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = source.GetTfstate(ctx)
+	if !errors.Is(err, ErrRevisionNotFound) || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent %v, got %v", ErrRevisionNotFound, err)
+	}
+
+}
+
+func TestTfstateSourceConfigFormats(t *testing.T) {
+
+	r := newTestRepository(t)
+
+	r.commit(map[string]string{
+		"routes/api.yaml":         "routes:\n- name: api\n- name: admin\n  parent_name: api\n",
+		"routes/orders.json":      `{"routes":[{"name":"orders"}]}`,
+		"routes/health.textproto": "routes {\n  name: \"health\"\n}\n",
+		"network.tfstate":         tfstateContent(1),
+		"README.md":               "routes",
+	})
+
+	source, err := NewTfstateSource(WithRepository(r.dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tfstates, err := source.GetTfstate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := make(map[string]int, len(tfstates))
+
+	for _, tfstate := range tfstates {
+		for _, resource := range tfstate.Resources {
+			if resource.Type == "cruiser_route" {
+				routes[tfstate.Name] += len(resource.Instances)
+			}
+		}
+	}
+
+	if len(tfstates) != 4 || routes["routes/api.yaml"] != 2 || routes["routes/orders.json"] != 1 || routes["routes/health.textproto"] != 1 {
+		t.Fatalf("unexpected routes %v in %d tfstates", routes, len(tfstates))
+	}
+
+	r.commit(map[string]string{"routes/api.yaml": "routes:\n- name: api\n  bogus: true\n"})
+
+	_, err = source.GetTfstate(context.Background())
+	if err == nil || state.ClassifyError(err) != state.PermanentError || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("expected a permanent error with the line, got %v", err)
+	}
+
+}
+
+func TestTfstateSourceInvalidTfstate(t *testing.T) {
+
+	r := newTestRepository(t)
+
+	r.commit(map[string]string{"routes.tfstate": "not json"})
+
+	source, err := NewTfstateSource(WithRepository(r.dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = source.GetTfstate(context.Background()); err == nil || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+
+	if _, err = NewTfstateSource(); !errors.Is(err, ErrEmptyRepository) {
+		t.Errorf("expected %v, got %v", ErrEmptyRepository, err)
+	}
+
+}
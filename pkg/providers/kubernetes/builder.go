@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+type TfstateSourceOption func(*tfstateSource)
+
+func WithClient(client kubernetes.Interface) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.client = client
+	}
+}
+
+func WithCruiserRoutes(dynamicClient dynamic.Interface) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.dynamicClient = dynamicClient
+	}
+}
+
+func WithNamespace(namespace string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.namespace = namespace
+	}
+}
+
+func WithLabelSelector(labelSelector string) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.labelSelector = labelSelector
+	}
+}
+
+func WithResyncPeriod(resyncPeriod time.Duration) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.resyncPeriod = resyncPeriod
+	}
+}
+
+func WithDecrypter(decrypter state.TfstateDecrypter) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.decrypter = decrypter
+	}
+}
+
+func WithVerifier(verifier state.TfstateVerifier) TfstateSourceOption {
+	return func(t *tfstateSource) {
+		t.verifier = verifier
+	}
+}
+
+func NewTfstateSource(opts ...TfstateSourceOption) (state.TfstateSource, error) {
+
+	t := &tfstateSource{
+		labelSelector: DefaultLabelSelector,
+		resyncPeriod:  10 * time.Minute,
+		objects:       make(map[string]*kubernetesObject),
+		rwLock:        new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.client == nil {
+		return nil, ErrNilClient
+	}
+
+	return t, nil
+
+}
@@ -0,0 +1,7 @@
+package kubernetes
+
+import "errors"
+
+var (
+	ErrNilClient = errors.New("nil kubernetes client")
+)
@@ -0,0 +1,496 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/config"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	"google.golang.org/protobuf/encoding/protojson"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const DefaultLabelSelector = "cruiser.ultraviolet-black.io/config=true"
+
+var CruiserRouteResource = schema.GroupVersionResource{
+	Group:    "cruiser.ultraviolet-black.io",
+	Version:  "v1alpha1",
+	Resource: "cruiserroutes",
+}
+
+type kubernetesObject struct {
+	tfstate         *state.Tfstate
+	resourceVersion string
+}
+
+type tfstateSource struct {
+	client        kubernetes.Interface
+	dynamicClient dynamic.Interface
+
+	namespace     string
+	labelSelector string
+	resyncPeriod  time.Duration
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	configMapLister corev1listers.ConfigMapLister
+	routeLister     cache.GenericLister
+
+	objects map[string]*kubernetesObject
+
+	invalidated atomic.Bool
+
+	rwLock *sync.RWMutex
+}
+
+func (t *tfstateSource) open(ctx context.Context, content, signature []byte) ([]byte, error) {
+
+	var err error
+
+	if t.verifier != nil {
+		content, err = t.verifier.Verify(content, signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.decrypter == nil {
+		return content, nil
+	}
+
+	return t.decrypter.Decrypt(ctx, content)
+
+}
+
+// configMapSignature returns the sidecar signature of a configmap key, stored
+// under the same key with the signature suffix.
+func (t *tfstateSource) configMapSignature(configMap *corev1.ConfigMap, key string) []byte {
+
+	if t.verifier == nil {
+		return nil
+	}
+
+	signature, ok := configMap.Data[key+state.TfstateSignatureSuffix]
+	if !ok {
+		return nil
+	}
+
+	return []byte(signature)
+
+}
+
+func (t *tfstateSource) configMapTfstate(ctx context.Context, configMap *corev1.ConfigMap) (*state.Tfstate, error) {
+
+	merged := &config.Config{}
+
+	keys := make([]string, 0, len(configMap.Data))
+
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		format, err := config.FormatFromPath(key)
+		if err != nil {
+			continue
+		}
+
+		content, err := t.open(ctx, []byte(configMap.Data[key]), t.configMapSignature(configMap, key))
+		if err != nil {
+			return nil, fmt.Errorf("configmap %s/%s key %s: %w", configMap.Namespace, configMap.Name, key, err)
+		}
+
+		c, err := config.Unmarshal(content, format)
+		if err != nil {
+			return nil, state.Permanent(fmt.Errorf("configmap %s/%s key %s: %w", configMap.Namespace, configMap.Name, key, err))
+		}
+
+		merged.Routes = append(merged.Routes, c.Routes...)
+		merged.Listeners = append(merged.Listeners, c.Listeners...)
+		merged.VirtualHosts = append(merged.VirtualHosts, c.VirtualHosts...)
+		merged.RouteConfigurations = append(merged.RouteConfigurations, c.RouteConfigurations...)
+		merged.Clusters = append(merged.Clusters, c.Clusters...)
+		merged.ClusterLoadAssignments = append(merged.ClusterLoadAssignments, c.ClusterLoadAssignments...)
+
+	}
+
+	tfstate, err := merged.ToTfstate()
+	if err != nil {
+		return nil, state.Permanent(err)
+	}
+
+	return tfstate, nil
+
+}
+
+// The spec of a cruiserroute can neither be encrypted nor signed, it still
+// goes through the verifier and the decrypter so that required signatures and
+// encryption reject it.
+func (t *tfstateSource) cruiserRouteTfstate(ctx context.Context, object *unstructured.Unstructured) (*state.Tfstate, error) {
+
+	spec, ok := object.Object["spec"]
+	if !ok {
+		spec = map[string]interface{}{}
+	}
+
+	out, err := json.Marshal(spec)
+	if err != nil {
+		return nil, state.Permanent(err)
+	}
+
+	out, err = t.open(ctx, out, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cruiserroute %s/%s: %w", object.GetNamespace(), object.GetName(), err)
+	}
+
+	route := &serverpb.Router_Route{}
+
+	if err := protojson.Unmarshal(out, route); err != nil {
+		return nil, state.Permanent(fmt.Errorf("cruiserroute %s/%s: %w", object.GetNamespace(), object.GetName(), err))
+	}
+
+	if len(route.Name) == 0 {
+		route.Name = object.GetName()
+	}
+
+	tfstate, err := (&config.Config{Routes: []*serverpb.Router_Route{route}}).ToTfstate()
+	if err != nil {
+		return nil, state.Permanent(err)
+	}
+
+	return tfstate, nil
+
+}
+
+func (t *tfstateSource) listConfigMaps(ctx context.Context) ([]*corev1.ConfigMap, error) {
+
+	if t.configMapLister != nil {
+
+		selector, err := labels.Parse(t.labelSelector)
+		if err != nil {
+			return nil, state.Permanent(err)
+		}
+
+		return t.configMapLister.ConfigMaps(t.namespace).List(selector)
+
+	}
+
+	list, err := t.client.CoreV1().ConfigMaps(t.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: t.labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	configMaps := make([]*corev1.ConfigMap, 0, len(list.Items))
+
+	for i := range list.Items {
+		configMaps = append(configMaps, &list.Items[i])
+	}
+
+	return configMaps, nil
+
+}
+
+func (t *tfstateSource) listCruiserRoutes(ctx context.Context) ([]*unstructured.Unstructured, error) {
+
+	if t.dynamicClient == nil {
+		return nil, nil
+	}
+
+	if t.routeLister != nil {
+
+		selector, err := labels.Parse(t.labelSelector)
+		if err != nil {
+			return nil, state.Permanent(err)
+		}
+
+		objects, err := t.routeLister.ByNamespace(t.namespace).List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		routes := make([]*unstructured.Unstructured, 0, len(objects))
+
+		for _, object := range objects {
+			if route, ok := object.(*unstructured.Unstructured); ok {
+				routes = append(routes, route)
+			}
+		}
+
+		return routes, nil
+
+	}
+
+	list, err := t.dynamicClient.Resource(CruiserRouteResource).Namespace(t.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: t.labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*unstructured.Unstructured, 0, len(list.Items))
+
+	for i := range list.Items {
+		routes = append(routes, &list.Items[i])
+	}
+
+	return routes, nil
+
+}
+
+func (t *tfstateSource) TfstateObjects() map[string]string {
+
+	t.rwLock.RLock()
+	defer t.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(t.objects))
+
+	for key, object := range t.objects {
+		objects[key] = object.resourceVersion
+	}
+
+	return objects
+
+}
+
+func (t *tfstateSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	t.rwLock.Lock()
+	defer t.rwLock.Unlock()
+
+	configMaps, err := t.listConfigMaps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := t.listCruiserRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]*kubernetesObject, len(configMaps)+len(routes))
+
+	needUpdate := t.invalidated.Swap(false)
+
+	translate := func(key, resourceVersion string, toTfstate func() (*state.Tfstate, error)) error {
+
+		if object, ok := t.objects[key]; ok && object.resourceVersion == resourceVersion {
+			objects[key] = object
+			return nil
+		}
+
+		tfstate, err := toTfstate()
+		if err != nil {
+			return err
+		}
+
+		tfstate.Name = key
+
+		objects[key] = &kubernetesObject{
+			tfstate:         tfstate,
+			resourceVersion: resourceVersion,
+		}
+
+		needUpdate = true
+
+		return nil
+
+	}
+
+	for _, configMap := range configMaps {
+
+		configMap := configMap
+
+		key := fmt.Sprintf("configmaps/%s/%s", configMap.Namespace, configMap.Name)
+
+		if err := translate(key, configMap.ResourceVersion, func() (*state.Tfstate, error) {
+			return t.configMapTfstate(ctx, configMap)
+		}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	for _, route := range routes {
+
+		route := route
+
+		key := fmt.Sprintf("%s/%s/%s", CruiserRouteResource.Resource, route.GetNamespace(), route.GetName())
+
+		if err := translate(key, route.GetResourceVersion(), func() (*state.Tfstate, error) {
+			return t.cruiserRouteTfstate(ctx, route)
+		}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	if len(objects) != len(t.objects) {
+		needUpdate = true
+	}
+
+	t.objects = objects
+
+	if !needUpdate {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(objects))
+
+	for key := range objects {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	tfstates := make([]*state.Tfstate, 0, len(keys))
+
+	for _, key := range keys {
+		tfstates = append(tfstates, objects[key].tfstate)
+	}
+
+	return tfstates, nil
+
+}
+
+func (t *tfstateSource) Invalidate() {
+	t.invalidated.Store(true)
+}
+
+func (t *tfstateSource) Watch(ctx context.Context) <-chan struct{} {
+
+	log := observability.LogFromContext(ctx).With("namespace", t.namespace, "labelSelector", t.labelSelector)
+
+	changes := make(chan struct{}, 1)
+
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			notify()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			notify()
+		},
+		DeleteFunc: func(obj interface{}) {
+			notify()
+		},
+	}
+
+	tweakListOptions := func(options *metav1.ListOptions) {
+		options.LabelSelector = t.labelSelector
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(t.client, t.resyncPeriod,
+		informers.WithNamespace(t.namespace),
+		informers.WithTweakListOptions(tweakListOptions),
+	)
+
+	configMaps := factory.Core().V1().ConfigMaps()
+
+	if _, err := configMaps.Informer().AddEventHandler(handler); err != nil {
+		log.Errorw("error watching configmaps, falling back to polling", "error", err)
+		return nil
+	}
+
+	var dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+
+	var routes informers.GenericInformer
+
+	if t.dynamicClient != nil {
+
+		dynamicFactory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(t.dynamicClient, t.resyncPeriod, t.namespace, tweakListOptions)
+
+		routes = dynamicFactory.ForResource(CruiserRouteResource)
+
+		if _, err := routes.Informer().AddEventHandler(handler); err != nil {
+			log.Errorw("error watching cruiserroutes, falling back to polling", "error", err)
+			return nil
+		}
+
+	}
+
+	factory.Start(ctx.Done())
+
+	if dynamicFactory != nil {
+		dynamicFactory.Start(ctx.Done())
+	}
+
+	go func() {
+
+		defer close(changes)
+
+		// Shutdown waits for the event handlers to return, so none of them
+		// notifies once the channel is closed.
+		defer func() {
+
+			factory.Shutdown()
+
+			if dynamicFactory != nil {
+				dynamicFactory.Shutdown()
+			}
+
+		}()
+
+		for _, ok := range factory.WaitForCacheSync(ctx.Done()) {
+			if !ok {
+				return
+			}
+		}
+
+		if dynamicFactory != nil {
+			for _, ok := range dynamicFactory.WaitForCacheSync(ctx.Done()) {
+				if !ok {
+					return
+				}
+			}
+		}
+
+		t.rwLock.Lock()
+
+		t.configMapLister = configMaps.Lister()
+
+		if routes != nil {
+			t.routeLister = routes.Lister()
+		}
+
+		t.rwLock.Unlock()
+
+		log.Infow("kubernetes informers synchronized")
+
+		notify()
+
+		<-ctx.Done()
+
+	}()
+
+	return changes
+
+}
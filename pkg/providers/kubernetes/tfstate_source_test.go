@@ -0,0 +1,441 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/ultraviolet-black/cruiser/pkg/encryption"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"github.com/ultraviolet-black/cruiser/pkg/signature"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testNamespace = "cruiser"
+
+var configLabels = map[string]string{"cruiser.ultraviolet-black.io/config": "true"}
+
+func TestMain(m *testing.M) {
+
+	if err := observability.InitializeLog(observability.WithLogLevel("fatal")); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+
+}
+
+func configMap(name, resourceVersion string, objectLabels map[string]string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       testNamespace,
+			Labels:          objectLabels,
+			ResourceVersion: resourceVersion,
+		},
+		Data: data,
+	}
+}
+
+func cruiserRoute(name, resourceVersion string, spec map[string]interface{}) *unstructured.Unstructured {
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": CruiserRouteResource.GroupVersion().String(),
+		"kind":       "CruiserRoute",
+		"spec":       spec,
+	}}
+
+	route.SetName(name)
+	route.SetNamespace(testNamespace)
+	route.SetLabels(configLabels)
+	route.SetResourceVersion(resourceVersion)
+
+	return route
+
+}
+
+func newTestClients(objects ...runtime.Object) (*fake.Clientset, *dynamicfake.FakeDynamicClient) {
+
+	clientset := fake.NewSimpleClientset()
+	routes := []runtime.Object{}
+
+	for _, object := range objects {
+
+		switch o := object.(type) {
+
+		case *corev1.ConfigMap:
+			clientset.Tracker().Add(o)
+
+		default:
+			routes = append(routes, o)
+
+		}
+
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		CruiserRouteResource: "CruiserRouteList",
+	}, routes...)
+
+	return clientset, dynamicClient
+
+}
+
+func newTestTfstateSource(t *testing.T, clientset *fake.Clientset, dynamicClient *dynamicfake.FakeDynamicClient) state.TfstateSource {
+
+	source, err := NewTfstateSource(
+		WithClient(clientset),
+		WithCruiserRoutes(dynamicClient),
+		WithNamespace(testNamespace),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return source
+
+}
+
+func routeCounts(tfstates []*state.Tfstate) map[string]int {
+
+	counts := make(map[string]int, len(tfstates))
+
+	for _, tfstate := range tfstates {
+		for _, resource := range tfstate.Resources {
+			if resource.Type == "cruiser_route" {
+				counts[tfstate.Name] += len(resource.Instances)
+			}
+		}
+	}
+
+	return counts
+
+}
+
+func TestTfstateSourcePolling(t *testing.T) {
+
+	clientset, dynamicClient := newTestClients(
+		configMap("routes", "1", configLabels, map[string]string{
+			"routes.yaml": "routes:\n- name: api\n- name: admin\n",
+			"README":      "ignored",
+		}),
+		configMap("unlabeled", "1", nil, map[string]string{
+			"routes.yaml": "routes:\n- name: other\n",
+		}),
+		cruiserRoute("orders", "1", map[string]interface{}{}),
+	)
+
+	source := newTestTfstateSource(t, clientset, dynamicClient)
+
+	ctx := context.Background()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := routeCounts(tfstates)
+
+	if len(tfstates) != 2 || counts["configmaps/cruiser/routes"] != 2 || counts["cruiserroutes/cruiser/orders"] != 1 {
+		t.Fatalf("unexpected tfstates %v", counts)
+	}
+
+	if objects := source.(state.TfstateObjectsReporter).TfstateObjects(); len(objects) != 2 || objects["configmaps/cruiser/routes"] != "1" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	// Unchanged resource versions are not reported again.
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	if _, err := clientset.CoreV1().ConfigMaps(testNamespace).Update(ctx, configMap("routes", "2", configLabels, map[string]string{
+		"routes.yaml": "routes:\n- name: api\n",
+	}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if counts = routeCounts(tfstates); counts["configmaps/cruiser/routes"] != 1 {
+		t.Fatalf("updated configmap not translated: %v", counts)
+	}
+
+	if err := dynamicClient.Resource(CruiserRouteResource).Namespace(testNamespace).Delete(ctx, "orders", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 1 {
+		t.Fatalf("deleted route still reported: %v, %v", tfstates, err)
+	}
+
+	// An invalidated source reports the current objects again.
+	source.(state.TfstateInvalidator).Invalidate()
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 1 {
+		t.Fatalf("expected the current tfstates after invalidation, got %v, %v", tfstates, err)
+	}
+
+}
+
+func TestTfstateSourceWatch(t *testing.T) {
+
+	clientset, dynamicClient := newTestClients(
+		configMap("routes", "1", configLabels, map[string]string{
+			"routes.yaml": "routes:\n- name: api\n",
+		}),
+		cruiserRoute("orders", "1", map[string]interface{}{"name": "orders-v1"}),
+	)
+
+	source := newTestTfstateSource(t, clientset, dynamicClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := source.(state.TfstateWatcher).Watch(ctx)
+	if changes == nil {
+		t.Fatal("watch disabled")
+	}
+
+	waitChange := func() {
+
+		t.Helper()
+
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				t.Fatal("watch stopped")
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("no change notified")
+		}
+
+	}
+
+	// Informers notify once their caches are synchronized.
+	waitChange()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := routeCounts(tfstates); len(counts) != 2 || counts["cruiserroutes/cruiser/orders"] != 1 {
+		t.Fatalf("unexpected tfstates %v", counts)
+	}
+
+	if _, err := clientset.CoreV1().ConfigMaps(testNamespace).Create(ctx, configMap("more-routes", "1", configLabels, map[string]string{
+		"routes.json": `{"routes":[{"name":"more"}]}`,
+	}), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The listers catch up with the informers, so poll the source until the
+	// new configmap is read from the cache.
+	deadline := time.Now().Add(10 * time.Second)
+
+	for {
+
+		waitChange()
+
+		if tfstates, err = source.GetTfstate(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tfstates) == 3 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("created configmap not reported: %v", routeCounts(tfstates))
+		}
+
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-changes:
+		for ok {
+			_, ok = <-changes
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch not stopped on cancel")
+	}
+
+}
+
+func TestTfstateSourceErrors(t *testing.T) {
+
+	clientset, dynamicClient := newTestClients(
+		configMap("routes", "1", configLabels, map[string]string{
+			"routes.yaml": "routes: [",
+		}),
+	)
+
+	_, err := newTestTfstateSource(t, clientset, dynamicClient).GetTfstate(context.Background())
+	if err == nil || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent error for an invalid configmap, got %v", err)
+	}
+
+	if _, err = NewTfstateSource(); !errors.Is(err, ErrNilClient) {
+		t.Errorf("expected %v, got %v", ErrNilClient, err)
+	}
+
+}
+
+func TestTfstateSourceDecrypts(t *testing.T) {
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypter, err := encryption.NewEncrypter(encryption.WithAgeRecipients(identity.Recipient()), encryption.WithArmor(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := encrypter.Encrypt(context.Background(), []byte("routes:\n- name: secret\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientset, dynamicClient := newTestClients(
+		configMap("routes", "1", configLabels, map[string]string{
+			"routes.yaml": string(ciphertext),
+		}),
+	)
+
+	decrypter, err := encryption.NewDecrypter(encryption.WithAgeIdentities(identity), encryption.WithRequireEncryption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewTfstateSource(
+		WithClient(clientset),
+		WithCruiserRoutes(dynamicClient),
+		WithNamespace(testNamespace),
+		WithDecrypter(decrypter),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := routeCounts(tfstates); counts["configmaps/cruiser/routes"] != 1 {
+		t.Fatalf("unexpected tfstates %v", counts)
+	}
+
+	// A cruiserroute spec cannot be encrypted and is rejected when encryption
+	// is required.
+	if _, err := dynamicClient.Resource(CruiserRouteResource).Namespace(testNamespace).Create(ctx, cruiserRoute("orders", "1", map[string]interface{}{}), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = source.GetTfstate(ctx); !errors.Is(err, encryption.ErrUnencryptedTfstate) || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent %v, got %v", encryption.ErrUnencryptedTfstate, err)
+	}
+
+}
+
+func TestTfstateSourceVerifies(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := signature.NewSigner(signature.WithPrivateKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []byte("routes:\n- name: signed\n")
+
+	sidecar, err := signer.Sign(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature of a key is stored next to it, under the same key with the
+	// signature suffix.
+	clientset, dynamicClient := newTestClients(
+		configMap("routes", "1", configLabels, map[string]string{
+			"routes.yaml": string(routes),
+			"routes.yaml" + state.TfstateSignatureSuffix: string(sidecar),
+		}),
+	)
+
+	verifier, err := signature.NewVerifier(signature.WithTrustedKeys(key.Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewTfstateSource(
+		WithClient(clientset),
+		WithCruiserRoutes(dynamicClient),
+		WithNamespace(testNamespace),
+		WithVerifier(verifier),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := routeCounts(tfstates); counts["configmaps/cruiser/routes"] != 1 {
+		t.Fatalf("unexpected tfstates %v", counts)
+	}
+
+	if _, err := clientset.CoreV1().ConfigMaps(testNamespace).Update(ctx, configMap("routes", "2", configLabels, map[string]string{
+		"routes.yaml": "routes:\n- name: tampered\n",
+		"routes.yaml" + state.TfstateSignatureSuffix: string(sidecar),
+	}), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = source.GetTfstate(ctx); !errors.Is(err, signature.ErrInvalidSignature) || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent %v, got %v", signature.ErrInvalidSignature, err)
+	}
+
+	if err := clientset.CoreV1().ConfigMaps(testNamespace).Delete(ctx, "routes", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cruiserroute spec cannot be signed and is rejected.
+	if _, err := dynamicClient.Resource(CruiserRouteResource).Namespace(testNamespace).Create(ctx, cruiserRoute("orders", "1", map[string]interface{}{}), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = source.GetTfstate(ctx); !errors.Is(err, signature.ErrUnsignedTfstate) || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent %v, got %v", signature.ErrUnsignedTfstate, err)
+	}
+
+}
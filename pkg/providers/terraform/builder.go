@@ -0,0 +1,133 @@
+package terraform
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type HttpBackendSourceOption func(*httpBackendSource)
+
+func WithHttpBackendAddresses(addresses ...string) HttpBackendSourceOption {
+	return func(h *httpBackendSource) {
+		h.addresses = append(h.addresses, addresses...)
+	}
+}
+
+func WithHttpBackendBasicAuth(username, password string) HttpBackendSourceOption {
+	return func(h *httpBackendSource) {
+		h.username = username
+		h.password = password
+	}
+}
+
+func WithHttpBackendClient(httpClient *http.Client) HttpBackendSourceOption {
+	return func(h *httpBackendSource) {
+		h.httpClient = httpClient
+	}
+}
+
+func WithHttpBackendDecrypter(decrypter state.TfstateDecrypter) HttpBackendSourceOption {
+	return func(h *httpBackendSource) {
+		h.decrypter = decrypter
+	}
+}
+
+func WithHttpBackendVerifier(verifier state.TfstateVerifier) HttpBackendSourceOption {
+	return func(h *httpBackendSource) {
+		h.verifier = verifier
+	}
+}
+
+func NewHttpBackendSource(opts ...HttpBackendSourceOption) (state.TfstateSource, error) {
+
+	h := &httpBackendSource{
+		addresses:  []string{},
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		states:     make(map[string]*httpBackendState),
+		rwLock:     new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if len(h.addresses) == 0 {
+		return nil, ErrEmptyAddress
+	}
+
+	return h, nil
+
+}
+
+type CloudSourceOption func(*cloudSource)
+
+func WithCloudAddress(address string) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.address = address
+	}
+}
+
+func WithCloudToken(token string) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.token = token
+	}
+}
+
+func WithCloudOrganization(organization string) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.organization = organization
+	}
+}
+
+func WithCloudWorkspaces(workspaces ...string) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.workspaces = append(c.workspaces, workspaces...)
+	}
+}
+
+func WithCloudClient(httpClient *http.Client) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithCloudDecrypter(decrypter state.TfstateDecrypter) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.decrypter = decrypter
+	}
+}
+
+func WithCloudVerifier(verifier state.TfstateVerifier) CloudSourceOption {
+	return func(c *cloudSource) {
+		c.verifier = verifier
+	}
+}
+
+func NewCloudSource(opts ...CloudSourceOption) (state.TfstateSource, error) {
+
+	c := &cloudSource{
+		address:    "https://app.terraform.io",
+		workspaces: []string{},
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		states:     make(map[string]*cloudWorkspaceState),
+		rwLock:     new(sync.RWMutex),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if len(c.token) == 0 {
+		return nil, ErrEmptyToken
+	}
+
+	if len(c.organization) == 0 {
+		return nil, ErrEmptyOrganization
+	}
+
+	return c, nil
+
+}
@@ -0,0 +1,260 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+const cloudContentType = "application/vnd.api+json"
+
+type cloudWorkspaceState struct {
+	workspaceId    string
+	stateVersionId string
+	tfstate        *state.Tfstate
+}
+
+type cloudSource struct {
+	address      string
+	token        string
+	organization string
+	workspaces   []string
+
+	httpClient *http.Client
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	states map[string]*cloudWorkspaceState
+
+	invalidated atomic.Bool
+
+	rwLock *sync.RWMutex
+}
+
+type cloudWorkspaceResponse struct {
+	Data struct {
+		Id string `json:"id"`
+	} `json:"data"`
+}
+
+type cloudStateVersionResponse struct {
+	Data struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Serial                 int64  `json:"serial"`
+			HostedStateDownloadUrl string `json:"hosted-state-download-url"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func (c *cloudSource) TfstateObjects() map[string]string {
+
+	c.rwLock.RLock()
+	defer c.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(c.states))
+
+	for workspace, workspaceState := range c.states {
+		objects[fmt.Sprintf("%s/%s", c.organization, workspace)] = workspaceState.stateVersionId
+	}
+
+	return objects
+
+}
+
+// authorized reports whether the token may be sent to the address, only the
+// configured api host gets it. State download urls may point to another host
+// and are signed, so they are fetched without credentials.
+func (c *cloudSource) authorized(address *url.URL) bool {
+
+	api, err := url.Parse(c.address)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(address.Scheme, api.Scheme) && strings.EqualFold(address.Host, api.Host)
+
+}
+
+func (c *cloudSource) get(ctx context.Context, address string) (*http.Response, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, state.Permanent(err)
+	}
+
+	if c.authorized(req.URL) {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+
+	req.Header.Set("Content-Type", cloudContentType)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		defer res.Body.Close()
+		return nil, statusError(req, res)
+	}
+
+	return res, nil
+
+}
+
+func (c *cloudSource) getJson(ctx context.Context, address string, v interface{}) (bool, error) {
+
+	res, err := c.get(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return false, state.Permanent(fmt.Errorf("%s: %w", address, err))
+	}
+
+	return true, nil
+
+}
+
+func (c *cloudSource) apiUrl(format string, args ...interface{}) string {
+
+	for i, arg := range args {
+		args[i] = url.PathEscape(fmt.Sprint(arg))
+	}
+
+	return fmt.Sprintf("%s/api/v2/%s", strings.TrimSuffix(c.address, "/"), fmt.Sprintf(format, args...))
+
+}
+
+func (c *cloudSource) workspaceId(ctx context.Context, workspace string) (string, error) {
+
+	if workspaceState, ok := c.states[workspace]; ok {
+		return workspaceState.workspaceId, nil
+	}
+
+	workspaceResponse := &cloudWorkspaceResponse{}
+
+	found, err := c.getJson(ctx, c.apiUrl("organizations/%s/workspaces/%s", c.organization, workspace), workspaceResponse)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "", state.Permanent(fmt.Errorf("%w: workspace %s/%s not found", ErrUnexpectedStatus, c.organization, workspace))
+	}
+
+	return workspaceResponse.Data.Id, nil
+
+}
+
+func (c *cloudSource) fetch(ctx context.Context, workspace string) (*cloudWorkspaceState, bool, error) {
+
+	workspaceId, err := c.workspaceId(ctx, workspace)
+	if err != nil {
+		return nil, false, err
+	}
+
+	current, hasCurrent := c.states[workspace]
+
+	stateVersion := &cloudStateVersionResponse{}
+
+	found, err := c.getJson(ctx, c.apiUrl("workspaces/%s/current-state-version", workspaceId), stateVersion)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !found {
+		return &cloudWorkspaceState{workspaceId: workspaceId}, hasCurrent && current.tfstate != nil, nil
+	}
+
+	if hasCurrent && current.stateVersionId == stateVersion.Data.Id {
+		return current, false, nil
+	}
+
+	res, err := c.get(ctx, stateVersion.Data.Attributes.HostedStateDownloadUrl)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, false, statusError(res.Request, res)
+	}
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tfstate, err := decodeTfstate(ctx, fmt.Sprintf("%s/%s", c.organization, workspace), content, c.verifier, c.decrypter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &cloudWorkspaceState{
+		workspaceId:    workspaceId,
+		stateVersionId: stateVersion.Data.Id,
+		tfstate:        tfstate,
+	}, true, nil
+
+}
+
+func (c *cloudSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	c.rwLock.Lock()
+	defer c.rwLock.Unlock()
+
+	tfstates := []*state.Tfstate{}
+
+	// Fetched states are committed only once every workspace succeeded, so a
+	// failed poll reports the changes it already saw again on the next poll.
+	states := make(map[string]*cloudWorkspaceState, len(c.workspaces))
+
+	needUpdate := false
+
+	for _, workspace := range c.workspaces {
+
+		workspaceState, changed, err := c.fetch(ctx, workspace)
+		if err != nil {
+			return nil, err
+		}
+
+		needUpdate = needUpdate || changed
+
+		states[workspace] = workspaceState
+
+		if workspaceState.tfstate != nil {
+			tfstates = append(tfstates, workspaceState.tfstate)
+		}
+
+	}
+
+	c.states = states
+
+	if !c.invalidated.Swap(false) && !needUpdate {
+		return nil, nil
+	}
+
+	return tfstates, nil
+
+}
+
+func (c *cloudSource) Invalidate() {
+	c.invalidated.Store(true)
+}
@@ -0,0 +1,313 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type fakeCloudWorkspace struct {
+	id             string
+	stateVersionId string
+	serial         int
+}
+
+// fakeCloud serves the workspace and current state version endpoints of the
+// Terraform Cloud API, states are downloaded from a separate archivist host.
+type fakeCloud struct {
+	mu sync.Mutex
+
+	srv       *httptest.Server
+	archivist *httptest.Server
+
+	workspaces map[string]*fakeCloudWorkspace
+
+	downloadStatus int
+
+	lookups     int
+	downloads   int
+	leakedToken bool
+}
+
+func newFakeCloud(t *testing.T, workspaces map[string]*fakeCloudWorkspace) *fakeCloud {
+
+	f := &fakeCloud{workspaces: workspaces}
+
+	f.srv = httptest.NewServer(f)
+	t.Cleanup(f.srv.Close)
+
+	f.archivist = httptest.NewServer(http.HandlerFunc(f.serveDownload))
+	t.Cleanup(f.archivist.Close)
+
+	return f
+
+}
+
+func (f *fakeCloud) workspaceById(id string) *fakeCloudWorkspace {
+
+	for _, workspace := range f.workspaces {
+		if workspace.id == id {
+			return workspace
+		}
+	}
+
+	return nil
+
+}
+
+func (f *fakeCloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+
+	// api/v2/organizations/{organization}/workspaces/{workspace}
+	case len(parts) == 6 && parts[2] == "organizations" && parts[3] == "org":
+
+		f.lookups++
+
+		workspace, ok := f.workspaces[parts[5]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", cloudContentType)
+		fmt.Fprintf(w, `{"data":{"id":%q}}`, workspace.id)
+
+	// api/v2/workspaces/{id}/current-state-version
+	case len(parts) == 5 && parts[2] == "workspaces" && parts[4] == "current-state-version":
+
+		workspace := f.workspaceById(parts[3])
+		if workspace == nil || len(workspace.stateVersionId) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", cloudContentType)
+		fmt.Fprintf(w, `{"data":{"id":%q,"attributes":{"serial":%d,"hosted-state-download-url":"%s/download/%s"}}}`,
+			workspace.stateVersionId, workspace.serial, f.archivist.URL, workspace.stateVersionId)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+
+	}
+
+}
+
+// serveDownload serves signed state download urls, which must be fetched
+// without the api token.
+func (f *fakeCloud) serveDownload(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.downloads++
+
+	if len(r.Header.Get("Authorization")) > 0 {
+		f.leakedToken = true
+	}
+
+	if f.downloadStatus != 0 {
+		w.WriteHeader(f.downloadStatus)
+		return
+	}
+
+	stateVersionId := strings.TrimPrefix(r.URL.Path, "/download/")
+
+	for _, workspace := range f.workspaces {
+		if workspace.stateVersionId == stateVersionId {
+			fmt.Fprintf(w, `{"version":4,"serial":%d,"lineage":"lineage","resources":[]}`, workspace.serial)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+
+}
+
+func (f *fakeCloud) update(fn func(*fakeCloud)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func (f *fakeCloud) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookups, f.downloads
+}
+
+func newTestCloudSource(t *testing.T, f *fakeCloud, workspaces ...string) state.TfstateSource {
+
+	source, err := NewCloudSource(
+		WithCloudAddress(f.srv.URL),
+		WithCloudToken("token"),
+		WithCloudOrganization("org"),
+		WithCloudWorkspaces(workspaces...),
+		WithCloudClient(f.srv.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return source
+
+}
+
+func TestCloudSource(t *testing.T) {
+
+	f := newFakeCloud(t, map[string]*fakeCloudWorkspace{
+		"network": {id: "ws-network", stateVersionId: "sv-1", serial: 1},
+		"routes":  {id: "ws-routes"},
+	})
+
+	source := newTestCloudSource(t, f, "network", "routes")
+
+	ctx := context.Background()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The routes workspace has no state version yet and is skipped.
+	if len(tfstates) != 1 || tfstates[0].Name != "org/network" || tfstates[0].Serial != 1 {
+		t.Fatalf("unexpected tfstates %+v", tfstates)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	// Workspace ids are looked up once and the state is only downloaded when
+	// the current state version changes.
+	if lookups, downloads := f.counts(); lookups != 2 || downloads != 1 {
+		t.Fatalf("expected 2 lookups and 1 download, got %d and %d", lookups, downloads)
+	}
+
+	f.update(func(f *fakeCloud) {
+		f.workspaces["routes"].stateVersionId, f.workspaces["routes"].serial = "sv-2", 7
+	})
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 2 || tfstates[1].Name != "org/routes" || tfstates[1].Serial != 7 {
+		t.Fatalf("expected both workspaces, got %v, %v", tfstates, err)
+	}
+
+	objects := source.(state.TfstateObjectsReporter).TfstateObjects()
+
+	if objects["org/network"] != "sv-1" || objects["org/routes"] != "sv-2" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	if lookups, downloads := f.counts(); lookups != 2 || downloads != 2 {
+		t.Errorf("expected 2 lookups and 2 downloads, got %d and %d", lookups, downloads)
+	}
+
+	f.update(func(f *fakeCloud) {
+		if f.leakedToken {
+			t.Error("api token sent to the state download host")
+		}
+	})
+
+}
+
+func TestCloudSourcePartialFailure(t *testing.T) {
+
+	f := newFakeCloud(t, map[string]*fakeCloudWorkspace{
+		"network": {id: "ws-network", stateVersionId: "sv-1", serial: 1},
+		"routes":  {id: "ws-routes", stateVersionId: "sv-2", serial: 1},
+	})
+
+	source := newTestCloudSource(t, f, "network", "routes")
+
+	ctx := context.Background()
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || len(tfstates) != 2 {
+		t.Fatalf("unexpected tfstates %v, %v", tfstates, err)
+	}
+
+	// The network workspace changes but the routes download fails, the
+	// network change must still be reported once the poll succeeds.
+	f.update(func(f *fakeCloud) {
+		f.workspaces["network"].stateVersionId, f.workspaces["network"].serial = "sv-3", 2
+		f.workspaces["routes"].stateVersionId, f.workspaces["routes"].serial = "sv-4", 2
+		f.downloadStatus = http.StatusForbidden
+	})
+
+	_, err := source.GetTfstate(ctx)
+	if err == nil || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent error for a failed download, got %v", err)
+	}
+
+	f.update(func(f *fakeCloud) {
+		f.downloadStatus = 0
+		f.workspaces["routes"].stateVersionId = "sv-2"
+		f.workspaces["routes"].serial = 1
+	})
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil || len(tfstates) != 2 || tfstates[0].Serial != 2 {
+		t.Fatalf("expected the network change after a failed poll, got %v, %v", tfstates, err)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	// An invalidated source reports the current states again.
+	source.(state.TfstateInvalidator).Invalidate()
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 2 {
+		t.Fatalf("expected the current tfstates after invalidation, got %v, %v", tfstates, err)
+	}
+
+}
+
+func TestCloudSourceErrors(t *testing.T) {
+
+	f := newFakeCloud(t, map[string]*fakeCloudWorkspace{})
+
+	_, err := newTestCloudSource(t, f, "missing").GetTfstate(context.Background())
+	if err == nil || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent error for a missing workspace, got %v", err)
+	}
+
+	source, err := NewCloudSource(
+		WithCloudAddress(f.srv.URL),
+		WithCloudToken("wrong"),
+		WithCloudOrganization("org"),
+		WithCloudWorkspaces("network"),
+		WithCloudClient(f.srv.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = source.GetTfstate(context.Background()); err == nil || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent error for a rejected token, got %v", err)
+	}
+
+	if _, err = NewCloudSource(WithCloudOrganization("org")); err != ErrEmptyToken {
+		t.Errorf("expected %v, got %v", ErrEmptyToken, err)
+	}
+
+	if _, err = NewCloudSource(WithCloudToken("token")); err != ErrEmptyOrganization {
+		t.Errorf("expected %v, got %v", ErrEmptyOrganization, err)
+	}
+
+}
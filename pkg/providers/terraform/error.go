@@ -0,0 +1,10 @@
+package terraform
+
+import "errors"
+
+var (
+	ErrEmptyAddress      = errors.New("empty terraform state address")
+	ErrEmptyToken        = errors.New("empty terraform cloud token")
+	ErrEmptyOrganization = errors.New("empty terraform cloud organization")
+	ErrUnexpectedStatus  = errors.New("unexpected terraform API response status")
+)
@@ -0,0 +1,58 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+func statusError(req *http.Request, res *http.Response) error {
+
+	err := fmt.Errorf("%w: %s %s: %s", ErrUnexpectedStatus, req.Method, req.URL.Redacted(), res.Status)
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return state.Transient(err)
+	}
+
+	return state.Permanent(err)
+
+}
+
+// decodeTfstate verifies the embedded signature, since backends have no
+// room for a sidecar signature, then decrypts and parses the content.
+func decodeTfstate(ctx context.Context, name string, content []byte, verifier state.TfstateVerifier, decrypter state.TfstateDecrypter) (*state.Tfstate, error) {
+
+	var err error
+
+	if verifier != nil {
+
+		content, err = verifier.Verify(content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+	}
+
+	if decrypter != nil {
+
+		content, err = decrypter.Decrypt(ctx, content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+	}
+
+	tfstate := &state.Tfstate{}
+
+	if err := json.Unmarshal(content, tfstate); err != nil {
+		return nil, state.Permanent(fmt.Errorf("%s: %w", name, err))
+	}
+
+	tfstate.Name = name
+
+	return tfstate, nil
+
+}
@@ -0,0 +1,170 @@
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type httpBackendState struct {
+	tfstate *state.Tfstate
+	etag    string
+	hash    string
+}
+
+type httpBackendSource struct {
+	addresses []string
+
+	username string
+	password string
+
+	httpClient *http.Client
+
+	decrypter state.TfstateDecrypter
+	verifier  state.TfstateVerifier
+
+	states map[string]*httpBackendState
+
+	invalidated atomic.Bool
+
+	rwLock *sync.RWMutex
+}
+
+func (h *httpBackendSource) TfstateObjects() map[string]string {
+
+	h.rwLock.RLock()
+	defer h.rwLock.RUnlock()
+
+	objects := make(map[string]string, len(h.states))
+
+	for address, backendState := range h.states {
+
+		version := backendState.etag
+		if len(version) == 0 {
+			version = backendState.hash
+		}
+
+		objects[address] = version
+
+	}
+
+	return objects
+
+}
+
+func (h *httpBackendSource) fetch(ctx context.Context, address string, current *httpBackendState) (*httpBackendState, bool, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, false, state.Permanent(err)
+	}
+
+	if len(h.username) > 0 || len(h.password) > 0 {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	if current != nil && len(current.etag) > 0 {
+		req.Header.Set("If-None-Match", current.etag)
+	}
+
+	res, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+
+	case http.StatusNotModified:
+		return current, false, nil
+
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, current != nil, nil
+
+	case http.StatusOK:
+
+	default:
+		return nil, false, statusError(req, res)
+
+	}
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	if current != nil && current.hash == hash {
+		return &httpBackendState{
+			tfstate: current.tfstate,
+			etag:    res.Header.Get("ETag"),
+			hash:    hash,
+		}, false, nil
+	}
+
+	tfstate, err := decodeTfstate(ctx, address, content, h.verifier, h.decrypter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &httpBackendState{
+		tfstate: tfstate,
+		etag:    res.Header.Get("ETag"),
+		hash:    hash,
+	}, true, nil
+
+}
+
+func (h *httpBackendSource) GetTfstate(ctx context.Context) ([]*state.Tfstate, error) {
+
+	h.rwLock.Lock()
+	defer h.rwLock.Unlock()
+
+	tfstates := []*state.Tfstate{}
+
+	// Fetched states are committed only once every address succeeded, so a
+	// failed poll reports the changes it already saw again on the next poll.
+	states := make(map[string]*httpBackendState, len(h.addresses))
+
+	needUpdate := false
+
+	for _, address := range h.addresses {
+
+		backendState, changed, err := h.fetch(ctx, address, h.states[address])
+		if err != nil {
+			return nil, err
+		}
+
+		needUpdate = needUpdate || changed
+
+		if backendState == nil {
+			continue
+		}
+
+		states[address] = backendState
+
+		tfstates = append(tfstates, backendState.tfstate)
+
+	}
+
+	h.states = states
+
+	if !h.invalidated.Swap(false) && !needUpdate {
+		return nil, nil
+	}
+
+	return tfstates, nil
+
+}
+
+func (h *httpBackendSource) Invalidate() {
+	h.invalidated.Store(true)
+}
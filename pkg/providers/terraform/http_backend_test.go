@@ -0,0 +1,389 @@
+package terraform
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ultraviolet-black/cruiser/pkg/encryption"
+	"github.com/ultraviolet-black/cruiser/pkg/signature"
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type fakeHttpBackend struct {
+	mu sync.Mutex
+
+	status  int
+	serial  int
+	etag    string
+	matched int
+
+	username string
+	password string
+}
+
+func (f *fakeHttpBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if username, password, ok := r.BasicAuth(); !ok || username != f.username || password != f.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	if len(f.etag) > 0 && r.Header.Get("If-None-Match") == f.etag {
+		f.matched++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if len(f.etag) > 0 {
+		w.Header().Set("ETag", f.etag)
+	}
+
+	fmt.Fprintf(w, `{"version":4,"serial":%d,"lineage":"lineage","resources":[]}`, f.serial)
+
+}
+
+func (f *fakeHttpBackend) update(fn func(*fakeHttpBackend)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func newTestHttpBackendSource(t *testing.T, backend *fakeHttpBackend) (state.TfstateSource, string) {
+
+	srv := httptest.NewServer(backend)
+	t.Cleanup(srv.Close)
+
+	address := srv.URL + "/state/router"
+
+	source, err := NewHttpBackendSource(
+		WithHttpBackendAddresses(address),
+		WithHttpBackendBasicAuth("terraform", "secret"),
+		WithHttpBackendClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return source, address
+
+}
+
+func TestHttpBackendSource(t *testing.T) {
+
+	backend := &fakeHttpBackend{serial: 1, etag: `"1"`, username: "terraform", password: "secret"}
+
+	source, address := newTestHttpBackendSource(t, backend)
+
+	ctx := context.Background()
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tfstates) != 1 || tfstates[0].Serial != 1 || tfstates[0].Name != address {
+		t.Fatalf("unexpected tfstates %+v", tfstates)
+	}
+
+	// The ETag is sent back and a 304 reports the state as unchanged.
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	backend.update(func(f *fakeHttpBackend) {
+		if f.matched != 1 {
+			t.Fatalf("If-None-Match was not sent, %d matches", f.matched)
+		}
+	})
+
+	if objects := source.(state.TfstateObjectsReporter).TfstateObjects(); objects[address] != `"1"` {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	backend.update(func(f *fakeHttpBackend) {
+		f.serial, f.etag = 2, `"2"`
+	})
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 1 || tfstates[0].Serial != 2 {
+		t.Fatalf("expected updated state, got %v, %v", tfstates, err)
+	}
+
+	// A deleted state is reported once as an empty set.
+	backend.update(func(f *fakeHttpBackend) {
+		f.status = http.StatusNotFound
+	})
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates == nil || len(tfstates) != 0 {
+		t.Fatalf("expected deleted state, got %v, %v", tfstates, err)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state after deletion, got %v, %v", tfstates, err)
+	}
+
+	if objects := source.(state.TfstateObjectsReporter).TfstateObjects(); len(objects) != 0 {
+		t.Errorf("deleted state still reported: %v", objects)
+	}
+
+}
+
+func TestHttpBackendSourceWithoutEtag(t *testing.T) {
+
+	backend := &fakeHttpBackend{serial: 1, username: "terraform", password: "secret"}
+
+	source, _ := newTestHttpBackendSource(t, backend)
+
+	ctx := context.Background()
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || len(tfstates) != 1 {
+		t.Fatalf("unexpected tfstates %v, %v", tfstates, err)
+	}
+
+	// Without an ETag the content hash detects that nothing changed.
+	if tfstates, err := source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+}
+
+func TestHttpBackendSourcePartialFailure(t *testing.T) {
+
+	first := &fakeHttpBackend{serial: 1, etag: `"1"`, username: "terraform", password: "secret"}
+	second := &fakeHttpBackend{serial: 1, etag: `"1"`, username: "terraform", password: "secret"}
+
+	firstSrv := httptest.NewServer(first)
+	t.Cleanup(firstSrv.Close)
+
+	secondSrv := httptest.NewServer(second)
+	t.Cleanup(secondSrv.Close)
+
+	source, err := NewHttpBackendSource(
+		WithHttpBackendAddresses(firstSrv.URL, secondSrv.URL),
+		WithHttpBackendBasicAuth("terraform", "secret"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if tfstates, err := source.GetTfstate(ctx); err != nil || len(tfstates) != 2 {
+		t.Fatalf("unexpected tfstates %v, %v", tfstates, err)
+	}
+
+	// The first state changes but the second address fails, the change must
+	// still be reported once the poll succeeds.
+	first.update(func(f *fakeHttpBackend) {
+		f.serial, f.etag = 2, `"2"`
+	})
+
+	second.update(func(f *fakeHttpBackend) {
+		f.status = http.StatusBadGateway
+	})
+
+	if _, err := source.GetTfstate(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	second.update(func(f *fakeHttpBackend) {
+		f.status = 0
+	})
+
+	tfstates, err := source.GetTfstate(ctx)
+	if err != nil || len(tfstates) != 2 || tfstates[0].Serial != 2 {
+		t.Fatalf("expected the first change after a failed poll, got %v, %v", tfstates, err)
+	}
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || tfstates != nil {
+		t.Fatalf("expected unchanged state, got %v, %v", tfstates, err)
+	}
+
+	// An invalidated source reports the current states again.
+	source.(state.TfstateInvalidator).Invalidate()
+
+	if tfstates, err = source.GetTfstate(ctx); err != nil || len(tfstates) != 2 {
+		t.Fatalf("expected the current tfstates after invalidation, got %v, %v", tfstates, err)
+	}
+
+}
+
+func TestHttpBackendSourceErrors(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		backend  *fakeHttpBackend
+		expected state.ErrorClass
+	}{
+		{name: "unauthorized", backend: &fakeHttpBackend{username: "terraform", password: "other"}, expected: state.PermanentError},
+		{name: "server error", backend: &fakeHttpBackend{status: http.StatusBadGateway, username: "terraform", password: "secret"}, expected: state.TransientError},
+		{name: "rate limited", backend: &fakeHttpBackend{status: http.StatusTooManyRequests, username: "terraform", password: "secret"}, expected: state.TransientError},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			source, _ := newTestHttpBackendSource(t, c.backend)
+
+			_, err := source.GetTfstate(context.Background())
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if class := state.ClassifyError(err); class != c.expected {
+				t.Errorf("expected %s error, got %s: %v", c.expected, class, err)
+			}
+
+		})
+
+	}
+
+}
+
+func TestHttpBackendSourceDecrypts(t *testing.T) {
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypter, err := encryption.NewEncrypter(encryption.WithAgeRecipients(identity.Recipient()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := encrypter.Encrypt(context.Background(), []byte(`{"version":4,"serial":3,"resources":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(ciphertext)
+	}))
+	t.Cleanup(srv.Close)
+
+	decrypter, err := encryption.NewDecrypter(encryption.WithAgeIdentities(identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewHttpBackendSource(
+		WithHttpBackendAddresses(srv.URL),
+		WithHttpBackendClient(srv.Client()),
+		WithHttpBackendDecrypter(decrypter),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tfstates, err := source.GetTfstate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tfstates) != 1 || tfstates[0].Serial != 3 {
+		t.Fatalf("unexpected tfstates %+v", tfstates)
+	}
+
+	// Plaintext is rejected once encryption is required.
+	decrypter, err = encryption.NewDecrypter(encryption.WithRequireEncryption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext = []byte(`{"version":4,"serial":3,"resources":[]}`)
+
+	source, err = NewHttpBackendSource(
+		WithHttpBackendAddresses(srv.URL),
+		WithHttpBackendClient(srv.Client()),
+		WithHttpBackendDecrypter(decrypter),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = source.GetTfstate(context.Background()); !errors.Is(err, encryption.ErrUnencryptedTfstate) {
+		t.Fatalf("expected %v, got %v", encryption.ErrUnencryptedTfstate, err)
+	}
+
+}
+
+func TestHttpBackendSourceVerifies(t *testing.T) {
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := signature.NewSigner(signature.WithPrivateKey(private))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := signature.NewVerifier(signature.WithTrustedKeys(public))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := signer.SignInline([]byte(`{"version":4,"serial":4,"resources":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := signed
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	t.Cleanup(srv.Close)
+
+	newSource := func() state.TfstateSource {
+
+		source, err := NewHttpBackendSource(
+			WithHttpBackendAddresses(srv.URL),
+			WithHttpBackendClient(srv.Client()),
+			WithHttpBackendVerifier(verifier),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return source
+
+	}
+
+	tfstates, err := newSource().GetTfstate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tfstates) != 1 || tfstates[0].Serial != 4 {
+		t.Fatalf("unexpected tfstates %+v", tfstates)
+	}
+
+	// A backend has no room for a sidecar signature, so an unsigned state is
+	// rejected.
+	content = []byte(`{"version":4,"serial":4,"resources":[]}`)
+
+	_, err = newSource().GetTfstate(context.Background())
+	if !errors.Is(err, signature.ErrUnsignedTfstate) || state.ClassifyError(err) != state.PermanentError {
+		t.Fatalf("expected a permanent %v, got %v", signature.ErrUnsignedTfstate, err)
+	}
+
+}
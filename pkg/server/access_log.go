@@ -0,0 +1,98 @@
+package server
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/accesslog"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+)
+
+type countingReadCloser struct {
+	io.ReadCloser
+
+	bytesRead int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+
+	n, err := c.ReadCloser.Read(p)
+
+	c.bytesRead += int64(n)
+
+	return n, err
+
+}
+
+type accessLogHandler struct {
+	handler http.Handler
+
+	logger accesslog.Logger
+}
+
+func clientIp(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+
+}
+
+func (h *accessLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	info := observability.RequestInfoFromContext(r.Context())
+
+	if info == nil {
+		info = &observability.RequestInfo{}
+		r = r.WithContext(observability.ContextWithRequestInfo(r.Context(), info))
+	}
+
+	start := time.Now()
+
+	body := &countingReadCloser{ReadCloser: r.Body}
+	r.Body = body
+
+	rec := newResponseRecorder(w)
+
+	h.handler.ServeHTTP(rec, r)
+
+	rule := info.AccessLog
+
+	if rule != nil && rule.SampleRate != nil && rand.Float64() >= rule.GetSampleRate() {
+		return
+	}
+
+	entry := &accesslog.Entry{
+		Timestamp:         start,
+		RequestId:         observability.RequestIdFromContext(r.Context()),
+		Route:             info.RouteName,
+		Backend:           info.Backend,
+		Method:            r.Method,
+		Path:              r.URL.Path,
+		Query:             r.URL.RawQuery,
+		Protocol:          r.Proto,
+		Status:            rec.statusCode,
+		GrpcCode:          rec.grpcStatus(),
+		BytesIn:           body.bytesRead,
+		BytesOut:          rec.bytesWritten,
+		Duration:          float64(time.Since(start)) / float64(time.Millisecond),
+		UpstreamLatency:   float64(info.UpstreamLatency) / float64(time.Millisecond),
+		UpstreamRequestId: info.UpstreamRequestId,
+		ClientIp:          clientIp(r),
+		UserAgent:         r.UserAgent(),
+		Referer:           r.Referer(),
+	}
+
+	if rule != nil {
+		entry.Redact(rule.RedactFields...)
+	}
+
+	h.logger.Log(entry)
+
+}
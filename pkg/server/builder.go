@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/ultraviolet-black/cruiser/pkg/accesslog"
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
@@ -111,6 +113,7 @@ func WithBackendProvider(provider BackendProvider) RouterOption {
 type Router interface {
 	http.Handler
 	DoHealthcheck(context.Context)
+	Close()
 }
 
 func NewRouter(options ...RouterOption) Router {
@@ -119,8 +122,11 @@ func NewRouter(options ...RouterOption) Router {
 		rtr:      mux.NewRouter(),
 		provs:    make(map[BackendProviderKey]BackendProvider),
 		handlers: []*serverpb.Router_Handler{},
+		routes:   make(map[string]*serverpb.Router_Route),
 	}
 
+	r.rtr.Use(r.routeMiddleware)
+
 	for _, option := range options {
 		option(r)
 	}
@@ -129,20 +135,106 @@ func NewRouter(options ...RouterOption) Router {
 
 }
 
+type SwapHandlerOption func(*swapHandler)
+
+func WithDefaultHandler(handler http.Handler) SwapHandlerOption {
+	return func(h *swapHandler) {
+		h.defaultHandler = handler
+	}
+}
+
+func WithRouterVersionHeader(header string) SwapHandlerOption {
+	return func(h *swapHandler) {
+		h.versionHeader = header
+	}
+}
+
+func WithMaxStreamLifetime(maxStreamLifetime time.Duration) SwapHandlerOption {
+	return func(h *swapHandler) {
+		h.maxStreamLifetime = maxStreamLifetime
+	}
+}
+
+func WithSwapHandlerLogger(log *zap.SugaredLogger) SwapHandlerOption {
+	return func(h *swapHandler) {
+		h.log = log
+	}
+}
+
 type SwapHandler interface {
 	http.Handler
 	Swap(http.Handler)
+	Version() uint64
+	Ready() bool
 	Close()
 }
 
-func NewSwapHandler() SwapHandler {
+func NewSwapHandler(options ...SwapHandlerOption) SwapHandler {
 
 	h := &swapHandler{
-		handlerCh: make(chan http.Handler),
+		defaultHandler: NewUnavailableHandler(5 * time.Second),
+		versionHeader:  DefaultRouterVersionHeader,
+		log:            zap.NewNop().Sugar(),
+	}
+
+	for _, option := range options {
+		option(h)
+	}
+
+	return h
+
+}
+
+func NewUnavailableHandler(retryAfter time.Duration) http.Handler {
+	return &unavailableHandler{
+		retryAfter: retryAfter,
+	}
+}
+
+type RequestIdOption func(*requestIdHandler)
+
+func WithRequestIdHeader(header string) RequestIdOption {
+	return func(h *requestIdHandler) {
+		h.header = header
+	}
+}
+
+func WithTrustedRequestIdHeader(trusted bool) RequestIdOption {
+	return func(h *requestIdHandler) {
+		h.trusted = trusted
 	}
+}
 
-	go h.watchSwap()
+func NewRequestIdHandler(handler http.Handler, options ...RequestIdOption) http.Handler {
+
+	h := &requestIdHandler{
+		handler: handler,
+		header:  DefaultRequestIdHeader,
+	}
+
+	for _, option := range options {
+		option(h)
+	}
 
 	return h
 
 }
+
+func NewTracingHandler(handler http.Handler) http.Handler {
+	return &tracingHandler{
+		handler: handler,
+	}
+}
+
+func NewMetricsHandler(handler http.Handler) http.Handler {
+	return &metricsHandler{
+		handler: handler,
+	}
+}
+
+func NewAccessLogHandler(handler http.Handler, logger accesslog.Logger) http.Handler {
+	return &accessLogHandler{
+		handler: handler,
+		logger:  logger,
+	}
+}
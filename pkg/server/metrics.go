@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"google.golang.org/grpc/codes"
+)
+
+type metricsHandler struct {
+	handler http.Handler
+}
+
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	info := observability.RequestInfoFromContext(r.Context())
+
+	if info == nil {
+		info = &observability.RequestInfo{}
+		r = r.WithContext(observability.ContextWithRequestInfo(r.Context(), info))
+	}
+
+	observability.HttpRequestsInFlight.Inc()
+	defer observability.HttpRequestsInFlight.Dec()
+
+	start := time.Now()
+
+	rec := newResponseRecorder(w)
+
+	h.handler.ServeHTTP(rec, r)
+
+	route := info.RouteName
+	if len(route) == 0 {
+		route = "unmatched"
+	}
+
+	status := strconv.Itoa(rec.statusCode)
+
+	if grpcStatus := rec.grpcStatus(); len(grpcStatus) > 0 {
+		if code, err := strconv.ParseUint(grpcStatus, 10, 32); err == nil {
+			status = codes.Code(code).String()
+		}
+	}
+
+	observability.HttpRequestsTotal.WithLabelValues(route, r.Method, status).Inc()
+	observability.HttpRequestDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+)
+
+const (
+	DefaultRequestIdHeader = "X-Request-Id"
+
+	maxRequestIdLength = 128
+)
+
+type requestIdHandler struct {
+	handler http.Handler
+
+	header  string
+	trusted bool
+}
+
+func isValidRequestId(requestId string) bool {
+
+	if len(requestId) == 0 || len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for _, c := range requestId {
+
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+
+	}
+
+	return true
+
+}
+
+func (h *requestIdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	requestId := ""
+
+	if h.trusted {
+		requestId = r.Header.Get(h.header)
+	}
+
+	if !isValidRequestId(requestId) {
+		requestId = uuid.NewString()
+	}
+
+	r.Header.Set(h.header, requestId)
+	w.Header().Set(h.header, requestId)
+
+	ctx := observability.ContextWithRequestId(r.Context(), requestId)
+
+	h.handler.ServeHTTP(w, r.WithContext(ctx))
+
+}
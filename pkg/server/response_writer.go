@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+)
+
+type responseRecorder struct {
+	http.ResponseWriter
+
+	statusCode   int
+	bytesWritten int64
+	wroteHeader  bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+
+	if rec, ok := w.(*responseRecorder); ok {
+		return rec
+	}
+
+	return &responseRecorder{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(statusCode)
+
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+
+	r.wroteHeader = true
+
+	n, err := r.ResponseWriter.Write(b)
+
+	r.bytesWritten += int64(n)
+
+	return n, err
+
+}
+
+func (r *responseRecorder) Flush() {
+
+	r.wroteHeader = true
+
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) grpcStatus() string {
+
+	header := r.Header()
+
+	if grpcStatus := header.Get("Grpc-Status"); len(grpcStatus) > 0 {
+		return grpcStatus
+	}
+
+	return header.Get(http.TrailerPrefix + "Grpc-Status")
+
+}
@@ -2,11 +2,15 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type BackendProviderKey int
@@ -26,6 +30,26 @@ type router struct {
 	rtr      *mux.Router
 	provs    map[BackendProviderKey]BackendProvider
 	handlers []*serverpb.Router_Handler
+	routes   map[string]*serverpb.Router_Route
+
+	cancelHealthcheck context.CancelFunc
+}
+
+func backendName(handler *serverpb.Router_Handler) string {
+
+	if handler == nil {
+		return ""
+	}
+
+	switch backend := handler.Backend.(type) {
+
+	case *serverpb.Router_Handler_AwsLambda:
+		return fmt.Sprintf("aws_lambda:%s:%s", backend.AwsLambda.FunctionName, backend.AwsLambda.Qualifier)
+
+	}
+
+	return ""
+
 }
 
 func (r *router) parseProtoRouterConfig(routerConfig *serverpb.Router) error {
@@ -50,6 +74,8 @@ func (r *router) parseProtoRoute(route *serverpb.Router_Route) error {
 
 	rt := rtr.NewRoute().Name(route.Name)
 
+	r.routes[route.Name] = route
+
 	isGrpcCall := false
 
 	for _, matcher := range route.Matchers {
@@ -165,14 +191,71 @@ func (r *router) parseProtoRoute(route *serverpb.Router_Route) error {
 
 func (r *router) DoHealthcheck(ctx context.Context) {
 
+	ctx, r.cancelHealthcheck = context.WithCancel(ctx)
+
 	for _, prov := range r.provs {
 		prov.HealthCheckHandlers(ctx, r.handlers...)
 	}
 
 }
 
+func (r *router) Close() {
+
+	if r.cancelHealthcheck != nil {
+		r.cancelHealthcheck()
+	}
+
+}
+
 func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	r.rtr.ServeHTTP(w, req)
 
 }
+
+func (r *router) routeMiddleware(next http.Handler) http.Handler {
+
+	if next == nil {
+		next = http.NotFoundHandler()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		currentRoute := mux.CurrentRoute(req)
+		if currentRoute == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		routeName := currentRoute.GetName()
+
+		backend := ""
+
+		route, hasRoute := r.routes[routeName]
+		if hasRoute {
+			backend = backendName(route.Handler)
+		}
+
+		if info := observability.RequestInfoFromContext(req.Context()); info != nil {
+
+			info.RouteName = routeName
+			info.Backend = backend
+
+			if hasRoute {
+				info.AccessLog = route.AccessLog
+			}
+
+		}
+
+		req = req.WithContext(observability.ContextWithLogFields(req.Context(), "route", routeName, "backend", backend))
+
+		if span := trace.SpanFromContext(req.Context()); span.IsRecording() {
+			span.SetName(fmt.Sprintf("HTTP %s %s", req.Method, routeName))
+			span.SetAttributes(attribute.String("http.route", routeName))
+		}
+
+		next.ServeHTTP(w, req)
+
+	})
+
+}
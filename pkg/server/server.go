@@ -73,17 +73,21 @@ func (s *server) listen() error {
 		return err
 	}
 
-	if tlsConfig := s.tlsConfig(); tlsConfig != nil {
+	if s.tlsConfig != nil {
 
-		lis, err := tls.Listen("tcp", s.listenerAddress, tlsConfig)
+		if tlsConfig := s.tlsConfig(); tlsConfig != nil {
 
-		if err != nil {
-			return err
-		}
+			lis, err := tls.Listen("tcp", s.listenerAddress, tlsConfig)
+
+			if err != nil {
+				return err
+			}
 
-		s.listener = lis
+			s.listener = lis
 
-		return nil
+			return nil
+
+		}
 
 	}
 
@@ -1,26 +1,256 @@
 package server
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"go.uber.org/zap"
+)
+
+const DefaultRouterVersionHeader = "X-Cruiser-Router-Version"
+
+type generation struct {
+	version uint64
+	handler http.Handler
+
+	inFlight atomic.Int64
+	retired  atomic.Bool
+
+	lock      *sync.Mutex
+	nextId    uint64
+	cancels   map[uint64]context.CancelFunc
+	timer     *time.Timer
+	closeOnce *sync.Once
+
+	log *zap.SugaredLogger
+}
+
+func newGeneration(version uint64, handler http.Handler, log *zap.SugaredLogger) *generation {
+	return &generation{
+		version:   version,
+		handler:   handler,
+		log:       log,
+		lock:      new(sync.Mutex),
+		cancels:   make(map[uint64]context.CancelFunc),
+		closeOnce: new(sync.Once),
+	}
+}
+
+func (g *generation) label() string {
+	return strconv.FormatUint(g.version, 10)
+}
+
+func (g *generation) track(cancel context.CancelFunc) uint64 {
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.nextId++
+
+	g.cancels[g.nextId] = cancel
+
+	return g.nextId
+
+}
+
+func (g *generation) untrack(id uint64) {
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	delete(g.cancels, id)
+
+}
+
+func (g *generation) expire() {
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.log.Warnw("router generation exceeded max stream lifetime, cancelling requests",
+		"version", g.version,
+		"inFlight", len(g.cancels),
+	)
+
+	for _, cancel := range g.cancels {
+		cancel()
+	}
+
+}
+
+func (g *generation) close() {
+
+	g.closeOnce.Do(func() {
+
+		g.lock.Lock()
+		if g.timer != nil {
+			g.timer.Stop()
+		}
+		g.lock.Unlock()
+
+		if closer, ok := g.handler.(interface{ Close() }); ok {
+			closer.Close()
+		}
+
+		observability.RouterGenerationRequestsInFlight.DeleteLabelValues(g.label())
+		observability.RouterDrainingGenerations.Dec()
+
+		g.log.Debugw("router generation closed", "version", g.version)
+
+	})
+
+}
 
 type swapHandler struct {
-	handlerCh chan http.Handler
-	handler   http.Handler
+	current atomic.Pointer[generation]
+	version atomic.Uint64
+
+	defaultHandler    http.Handler
+	versionHeader     string
+	maxStreamLifetime time.Duration
+
+	ready atomic.Bool
+
+	log *zap.SugaredLogger
+}
+
+func (h *swapHandler) acquire() *generation {
+
+	for {
+
+		gen := h.current.Load()
+		if gen == nil {
+			return nil
+		}
+
+		gen.inFlight.Add(1)
+
+		if !gen.retired.Load() {
+			observability.RouterGenerationRequestsInFlight.WithLabelValues(gen.label()).Inc()
+			return gen
+		}
+
+		h.release(gen, false)
+
+	}
+
 }
 
-func (h *swapHandler) watchSwap() {
-	for handler := range h.handlerCh {
-		h.handler = handler
+func (h *swapHandler) release(gen *generation, tracked bool) {
+
+	if tracked {
+		observability.RouterGenerationRequestsInFlight.WithLabelValues(gen.label()).Dec()
+	}
+
+	if gen.inFlight.Add(-1) == 0 && gen.retired.Load() {
+		gen.close()
+	}
+
+}
+
+func (h *swapHandler) retire(gen *generation) {
+
+	observability.RouterDrainingGenerations.Inc()
+
+	gen.retired.Store(true)
+
+	if gen.inFlight.Load() == 0 {
+		gen.close()
+		return
+	}
+
+	if h.maxStreamLifetime > 0 {
+
+		gen.lock.Lock()
+		gen.timer = time.AfterFunc(h.maxStreamLifetime, gen.expire)
+		gen.lock.Unlock()
+
 	}
+
 }
 
 func (h *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.handler.ServeHTTP(w, r)
+
+	gen := h.acquire()
+	if gen == nil {
+		h.defaultHandler.ServeHTTP(w, r)
+		return
+	}
+	defer h.release(gen, true)
+
+	if len(h.versionHeader) > 0 {
+		w.Header().Set(h.versionHeader, gen.label())
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	id := gen.track(cancel)
+	defer gen.untrack(id)
+
+	gen.handler.ServeHTTP(w, r.WithContext(ctx))
+
 }
 
 func (h *swapHandler) Swap(handler http.Handler) {
-	h.handlerCh <- handler
+
+	gen := newGeneration(h.version.Add(1), handler, h.log)
+
+	old := h.current.Swap(gen)
+
+	h.ready.Store(true)
+
+	observability.RouterSwapsTotal.Inc()
+	observability.RouterGeneration.Set(float64(gen.version))
+
+	h.log.Infow("router generation installed", "version", gen.version)
+
+	if old != nil {
+		h.retire(old)
+	}
+
+}
+
+func (h *swapHandler) Version() uint64 {
+
+	if gen := h.current.Load(); gen != nil {
+		return gen.version
+	}
+
+	return 0
+
+}
+
+func (h *swapHandler) Ready() bool {
+	return h.ready.Load()
 }
 
 func (h *swapHandler) Close() {
-	close(h.handlerCh)
+
+	h.ready.Store(false)
+
+	if old := h.current.Swap(nil); old != nil {
+		h.retire(old)
+	}
+
+}
+
+type unavailableHandler struct {
+	retryAfter time.Duration
+}
+
+func (h *unavailableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if h.retryAfter > 0 {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(h.retryAfter.Round(time.Second).Seconds())))
+	}
+
+	http.Error(w, "service unavailable: no router configured yet", http.StatusServiceUnavailable)
+
 }
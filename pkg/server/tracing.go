@@ -0,0 +1,58 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingHandler struct {
+	handler http.Handler
+}
+
+func (h *tracingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	ctx, span := observability.Tracer().Start(
+		ctx,
+		fmt.Sprintf("HTTP %s", r.Method),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.target", r.URL.Path),
+			attribute.String("net.host.name", r.Host),
+			attribute.String("http.flavor", fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+		),
+	)
+	defer span.End()
+
+	if requestId := observability.RequestIdFromContext(ctx); len(requestId) > 0 {
+		span.SetAttributes(attribute.String("cruiser.request_id", requestId))
+	}
+
+	rec := newResponseRecorder(w)
+
+	h.handler.ServeHTTP(rec, r.WithContext(ctx))
+
+	span.SetAttributes(attribute.Int("http.status_code", rec.statusCode))
+
+	if grpcStatus := rec.grpcStatus(); len(grpcStatus) > 0 {
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", grpcStatus))
+
+		if grpcStatus != "0" {
+			span.SetStatus(codes.Error, fmt.Sprintf("grpc status %s", grpcStatus))
+		}
+	}
+
+	if rec.statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(rec.statusCode))
+	}
+
+}
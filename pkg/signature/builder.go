@@ -0,0 +1,123 @@
+package signature
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type VerifierOption func(*verifier)
+
+func WithTrustedKeyFiles(paths ...string) VerifierOption {
+	return func(v *verifier) {
+		v.keyFiles = append(v.keyFiles, paths...)
+	}
+}
+
+func WithTrustedKeys(keys ...crypto.PublicKey) VerifierOption {
+	return func(v *verifier) {
+		for _, key := range keys {
+			if keyId, err := KeyId(key); err == nil {
+				v.keys[keyId] = key
+			}
+		}
+	}
+}
+
+func NewVerifier(opts ...VerifierOption) (state.TfstateVerifier, error) {
+
+	v := &verifier{
+		keys: make(map[string]crypto.PublicKey),
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	for _, path := range v.keyFiles {
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := ParsePublicKeys(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, key := range keys {
+
+			keyId, err := KeyId(key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+
+			v.keys[keyId] = key
+
+		}
+
+	}
+
+	if len(v.keys) == 0 {
+		return nil, ErrNoTrustedKeys
+	}
+
+	return v, nil
+
+}
+
+type SignerOption func(*signer)
+
+func WithPrivateKeyFile(path string) SignerOption {
+	return func(s *signer) {
+		s.keyFile = path
+	}
+}
+
+func WithPrivateKey(key crypto.Signer) SignerOption {
+	return func(s *signer) {
+		s.key = key
+	}
+}
+
+func NewSigner(opts ...SignerOption) (Signer, error) {
+
+	s := &signer{}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.keyFile) > 0 {
+
+		content, err := os.ReadFile(s.keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParsePrivateKey(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.keyFile, err)
+		}
+
+		s.key = key
+
+	}
+
+	if s.key == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	keyId, err := KeyId(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	s.keyId = keyId
+
+	return s, nil
+
+}
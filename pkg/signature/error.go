@@ -0,0 +1,14 @@
+package signature
+
+import "errors"
+
+var (
+	ErrNoPrivateKey      = errors.New("no private key configured")
+	ErrNoTrustedKeys     = errors.New("no trusted public keys configured")
+	ErrUnsignedTfstate   = errors.New("tfstate is not signed")
+	ErrInvalidSignature  = errors.New("invalid tfstate signature")
+	ErrUntrustedKey      = errors.New("tfstate signed by an untrusted key")
+	ErrUnsupportedKey    = errors.New("unsupported key type, expected ed25519 or ecdsa")
+	ErrUnsupportedFormat = errors.New("unsupported signature format")
+	ErrNoPEMBlock        = errors.New("no PEM block found")
+)
@@ -0,0 +1,156 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+func KeyId(publicKey crypto.PublicKey) (string, error) {
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+
+	return hex.EncodeToString(sum[:8]), nil
+
+}
+
+func ParsePublicKeys(content []byte) ([]crypto.PublicKey, error) {
+
+	keys := []crypto.PublicKey{}
+
+	for {
+
+		var block *pem.Block
+
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+
+		var key interface{}
+		var err error
+
+		switch block.Type {
+
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+
+		case "CERTIFICATE":
+
+			var certificate *x509.Certificate
+
+			certificate, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = certificate.PublicKey
+			}
+
+		default:
+			continue
+
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkPublicKey(key); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoPEMBlock
+	}
+
+	return keys, nil
+
+}
+
+func ParsePrivateKey(content []byte) (crypto.Signer, error) {
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, ErrNoPEMBlock
+	}
+
+	var key interface{}
+	var err error
+
+	switch block.Type {
+
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+
+	if err := checkPublicKey(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+
+}
+
+func checkPublicKey(key crypto.PublicKey) error {
+
+	switch k := key.(type) {
+
+	case ed25519.PublicKey:
+		return nil
+
+	case *ecdsa.PublicKey:
+		if _, err := hashFor(k.Curve); err != nil {
+			return err
+		}
+		return nil
+
+	}
+
+	return fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+
+}
+
+func hashFor(curve elliptic.Curve) (crypto.Hash, error) {
+
+	switch curve {
+
+	case elliptic.P256():
+		return crypto.SHA256, nil
+
+	case elliptic.P384():
+		return crypto.SHA384, nil
+
+	case elliptic.P521():
+		return crypto.SHA512, nil
+
+	}
+
+	return 0, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, curve.Params().Name)
+
+}
@@ -0,0 +1,128 @@
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+const SignatureV1 = "cruiser.signature.v1"
+
+type signedObject struct {
+	Format    string `json:"cruiser_signature"`
+	KeyId     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	Signature []byte `json:"signature"`
+	Payload   []byte `json:"payload,omitempty"`
+}
+
+func parseSignedObject(content []byte) (*signedObject, bool) {
+
+	trimmed := bytes.TrimLeft(content, " \t\r\n")
+
+	if !bytes.HasPrefix(trimmed, []byte("{")) || !bytes.Contains(trimmed, []byte(`"cruiser_signature"`)) {
+		return nil, false
+	}
+
+	s := &signedObject{}
+
+	if err := json.Unmarshal(trimmed, s); err != nil || len(s.Format) == 0 {
+		return nil, false
+	}
+
+	return s, true
+
+}
+
+func algorithm(key crypto.PublicKey) (string, crypto.Hash, error) {
+
+	switch k := key.(type) {
+
+	case ed25519.PublicKey:
+		return "ed25519", 0, nil
+
+	case *ecdsa.PublicKey:
+
+		hash, err := hashFor(k.Curve)
+		if err != nil {
+			return "", 0, err
+		}
+
+		return fmt.Sprintf("ecdsa-%s", k.Curve.Params().Name), hash, nil
+
+	}
+
+	return "", 0, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+
+}
+
+func digest(hash crypto.Hash, payload []byte) []byte {
+
+	if hash == 0 {
+		return payload
+	}
+
+	h := hash.New()
+	h.Write(payload)
+
+	return h.Sum(nil)
+
+}
+
+func sign(key crypto.Signer, keyId string, payload []byte) (*signedObject, error) {
+
+	alg, hash, err := algorithm(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := key.Sign(rand.Reader, digest(hash, payload), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signedObject{
+		Format:    SignatureV1,
+		KeyId:     keyId,
+		Algorithm: alg,
+		Signature: signature,
+	}, nil
+
+}
+
+func verify(key crypto.PublicKey, s *signedObject, payload []byte) error {
+
+	alg, hash, err := algorithm(key)
+	if err != nil {
+		return err
+	}
+
+	if s.Algorithm != alg {
+		return state.Permanent(fmt.Errorf("%w: algorithm %s does not match key %s", ErrInvalidSignature, s.Algorithm, s.KeyId))
+	}
+
+	valid := false
+
+	switch k := key.(type) {
+
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(k, payload, s.Signature)
+
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(k, digest(hash, payload), s.Signature)
+
+	}
+
+	if !valid {
+		return state.Permanent(fmt.Errorf("%w: key %s", ErrInvalidSignature, s.KeyId))
+	}
+
+	return nil
+
+}
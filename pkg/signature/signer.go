@@ -0,0 +1,42 @@
+package signature
+
+import (
+	"crypto"
+	"encoding/json"
+)
+
+type Signer interface {
+	Sign(payload []byte) ([]byte, error)
+	SignInline(payload []byte) ([]byte, error)
+}
+
+type signer struct {
+	keyFile string
+
+	key   crypto.Signer
+	keyId string
+}
+
+func (s *signer) Sign(payload []byte) ([]byte, error) {
+
+	signed, err := sign(s.key, s.keyId, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(signed, "", "  ")
+
+}
+
+func (s *signer) SignInline(payload []byte) ([]byte, error) {
+
+	signed, err := sign(s.key, s.keyId, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	signed.Payload = payload
+
+	return json.MarshalIndent(signed, "", "  ")
+
+}
@@ -0,0 +1,58 @@
+package signature
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+type verifier struct {
+	keyFiles []string
+
+	keys map[string]crypto.PublicKey
+}
+
+func (v *verifier) Verify(content, signature []byte) ([]byte, error) {
+
+	if s, ok := parseSignedObject(content); ok && len(s.Payload) > 0 {
+
+		if err := v.verify(s, s.Payload); err != nil {
+			return nil, err
+		}
+
+		return s.Payload, nil
+
+	}
+
+	if signature == nil {
+		return nil, state.Permanent(ErrUnsignedTfstate)
+	}
+
+	s, ok := parseSignedObject(signature)
+	if !ok {
+		return nil, state.Permanent(fmt.Errorf("%w: malformed signature", ErrInvalidSignature))
+	}
+
+	if err := v.verify(s, content); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+
+}
+
+func (v *verifier) verify(s *signedObject, payload []byte) error {
+
+	if s.Format != SignatureV1 {
+		return state.Permanent(fmt.Errorf("%w: %s", ErrUnsupportedFormat, s.Format))
+	}
+
+	key, ok := v.keys[s.KeyId]
+	if !ok {
+		return state.Permanent(fmt.Errorf("%w: %s", ErrUntrustedKey, s.KeyId))
+	}
+
+	return verify(key, s, payload)
+
+}
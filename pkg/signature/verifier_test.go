@@ -0,0 +1,220 @@
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ultraviolet-black/cruiser/pkg/state"
+)
+
+var tfstate = []byte(`{"version":4,"serial":1,"lineage":"lineage","resources":[]}`)
+
+func newEd25519Key(t *testing.T) crypto.Signer {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+
+}
+
+func newEcdsaKey(t *testing.T) crypto.Signer {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+
+}
+
+func newSigner(t *testing.T, key crypto.Signer) Signer {
+
+	signer, err := NewSigner(WithPrivateKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+
+}
+
+func newVerifier(t *testing.T, keys ...crypto.PublicKey) state.TfstateVerifier {
+
+	verifier, err := NewVerifier(WithTrustedKeys(keys...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return verifier
+
+}
+
+func newSignedInline(t *testing.T, key crypto.Signer) []byte {
+
+	signed, err := newSigner(t, key).SignInline(tfstate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+
+}
+
+func tamper(t *testing.T, content []byte, fn func(*signedObject)) []byte {
+
+	s, ok := parseSignedObject(content)
+	if !ok {
+		t.Fatalf("not a signed object: %s", content)
+	}
+
+	fn(s)
+
+	out, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return out
+
+}
+
+func TestVerify(t *testing.T) {
+
+	keys := map[string]crypto.Signer{
+		"ed25519":     newEd25519Key(t),
+		"ecdsa-P-256": newEcdsaKey(t),
+	}
+
+	for name, key := range keys {
+
+		t.Run(name, func(t *testing.T) {
+
+			signer := newSigner(t, key)
+			verifier := newVerifier(t, key.Public())
+
+			inline, err := signer.SignInline(tfstate)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sidecar, err := signer.Sign(tfstate)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s, _ := parseSignedObject(sidecar); s.Algorithm != name {
+				t.Fatalf("expected algorithm %s, got %s", name, s.Algorithm)
+			}
+
+			payload, err := verifier.Verify(inline, nil)
+			if err != nil || !bytes.Equal(payload, tfstate) {
+				t.Fatalf("embedded signature not verified: %q, %v", payload, err)
+			}
+
+			payload, err = verifier.Verify(tfstate, sidecar)
+			if err != nil || !bytes.Equal(payload, tfstate) {
+				t.Fatalf("sidecar signature not verified: %q, %v", payload, err)
+			}
+
+			tampered := append([]byte{}, tfstate...)
+			tampered[len(tampered)-2] = ' '
+
+			cases := []struct {
+				name      string
+				content   []byte
+				signature []byte
+				expected  error
+			}{
+				{name: "tampered payload", content: tamper(t, inline, func(s *signedObject) { s.Payload = tampered }), expected: ErrInvalidSignature},
+				{name: "tampered content", content: tampered, signature: sidecar, expected: ErrInvalidSignature},
+				{name: "tampered signature", content: tfstate, signature: tamper(t, sidecar, func(s *signedObject) { s.Signature[len(s.Signature)-1] ^= 0xff }), expected: ErrInvalidSignature},
+				{name: "algorithm mismatch", content: tamper(t, inline, func(s *signedObject) { s.Algorithm = "ecdsa-P-384" }), expected: ErrInvalidSignature},
+				{name: "malformed signature", content: tfstate, signature: []byte("signature"), expected: ErrInvalidSignature},
+				{name: "unsupported format", content: tamper(t, inline, func(s *signedObject) { s.Format = "cruiser.signature.v0" }), expected: ErrUnsupportedFormat},
+				{name: "unsigned", content: tfstate, expected: ErrUnsignedTfstate},
+				{name: "untrusted key", content: newSignedInline(t, newEd25519Key(t)), expected: ErrUntrustedKey},
+			}
+
+			for _, c := range cases {
+
+				t.Run(c.name, func(t *testing.T) {
+
+					_, err := verifier.Verify(c.content, c.signature)
+					if !errors.Is(err, c.expected) || state.ClassifyError(err) != state.PermanentError {
+						t.Fatalf("expected a permanent %v, got %v", c.expected, err)
+					}
+
+				})
+
+			}
+
+		})
+
+	}
+
+}
+
+func TestVerifierKeyFiles(t *testing.T) {
+
+	ed25519Key, ecdsaKey := newEd25519Key(t), newEcdsaKey(t)
+
+	content := []byte{}
+
+	for _, key := range []crypto.Signer{ed25519Key, ecdsaKey} {
+
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content = append(content, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+
+	}
+
+	path := filepath.Join(t.TempDir(), "trusted.pem")
+
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every key of a file is trusted, so keys can be rotated by listing the
+	// previous and the next key.
+	verifier, err := NewVerifier(WithTrustedKeyFiles(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []crypto.Signer{ed25519Key, ecdsaKey} {
+		if _, err := verifier.Verify(newSignedInline(t, key), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+}
+
+func TestBuilderErrors(t *testing.T) {
+
+	if _, err := NewVerifier(); !errors.Is(err, ErrNoTrustedKeys) {
+		t.Errorf("expected %v, got %v", ErrNoTrustedKeys, err)
+	}
+
+	if _, err := NewSigner(); !errors.Is(err, ErrNoPrivateKey) {
+		t.Errorf("expected %v, got %v", ErrNoPrivateKey, err)
+	}
+
+}
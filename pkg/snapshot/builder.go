@@ -0,0 +1,73 @@
+package snapshot
+
+import (
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type StoreOption func(*store)
+
+func WithDirectory(directory string) StoreOption {
+	return func(s *store) {
+		s.directory = directory
+	}
+}
+
+func WithPrefix(prefix string) StoreOption {
+	return func(s *store) {
+		s.prefix = prefix
+	}
+}
+
+func WithMaxSnapshots(maxSnapshots int) StoreOption {
+	return func(s *store) {
+		s.maxSnapshots = maxSnapshots
+	}
+}
+
+func WithLogger(log *zap.SugaredLogger) StoreOption {
+	return func(s *store) {
+		s.log = log
+	}
+}
+
+type Store interface {
+	Save(*Snapshot) error
+	Latest() (*Snapshot, error)
+}
+
+func NewStore(opts ...StoreOption) (Store, error) {
+
+	s := &store{
+		prefix:       "snapshot",
+		maxSnapshots: 5,
+		log:          zap.NewNop().Sugar(),
+		lock:         new(sync.Mutex),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.directory) == 0 {
+		return nil, ErrEmptySnapshotDirectory
+	}
+
+	if err := os.MkdirAll(s.directory, 0755); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) > 0 {
+		s.sequence = entries[0].sequence
+	}
+
+	return s, nil
+
+}
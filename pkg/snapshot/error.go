@@ -0,0 +1,9 @@
+package snapshot
+
+import "errors"
+
+var (
+	ErrNoSnapshot                 = errors.New("no snapshot found")
+	ErrEmptySnapshotDirectory     = errors.New("empty snapshot directory")
+	ErrUnsupportedSnapshotVersion = errors.New("unsupported snapshot format version")
+)
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const FormatVersion = 1
+
+type Snapshot struct {
+	Sequence  uint64
+	CreatedAt time.Time
+
+	Router *serverpb.Router
+	Xds    map[string]map[string]types.Resource
+}
+
+func (s *Snapshot) Age() time.Duration {
+	return time.Since(s.CreatedAt)
+}
+
+type snapshotFile struct {
+	FormatVersion int       `json:"format_version"`
+	Sequence      uint64    `json:"sequence"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	Router json.RawMessage              `json:"router,omitempty"`
+	Xds    map[string][]json.RawMessage `json:"xds,omitempty"`
+}
+
+func encode(s *Snapshot) ([]byte, error) {
+
+	file := &snapshotFile{
+		FormatVersion: FormatVersion,
+		Sequence:      s.Sequence,
+		CreatedAt:     s.CreatedAt,
+	}
+
+	if s.Router != nil {
+
+		out, err := protojson.Marshal(s.Router)
+		if err != nil {
+			return nil, err
+		}
+
+		file.Router = out
+
+	}
+
+	if len(s.Xds) > 0 {
+
+		file.Xds = make(map[string][]json.RawMessage, len(s.Xds))
+
+		for typeUrl, resources := range s.Xds {
+
+			file.Xds[typeUrl] = make([]json.RawMessage, 0, len(resources))
+
+			for _, resource := range resources {
+
+				any, err := anypb.New(resource)
+				if err != nil {
+					return nil, err
+				}
+
+				out, err := protojson.Marshal(any)
+				if err != nil {
+					return nil, err
+				}
+
+				file.Xds[typeUrl] = append(file.Xds[typeUrl], out)
+
+			}
+
+		}
+
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+
+}
+
+func decode(data []byte) (*Snapshot, error) {
+
+	file := &snapshotFile{}
+
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+
+	if file.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, file.FormatVersion)
+	}
+
+	s := &Snapshot{
+		Sequence:  file.Sequence,
+		CreatedAt: file.CreatedAt,
+	}
+
+	if len(file.Router) > 0 {
+
+		s.Router = &serverpb.Router{}
+
+		if err := protojson.Unmarshal(file.Router, s.Router); err != nil {
+			return nil, err
+		}
+
+	}
+
+	if len(file.Xds) > 0 {
+
+		s.Xds = make(map[string]map[string]types.Resource, len(file.Xds))
+
+		for typeUrl, resources := range file.Xds {
+
+			s.Xds[typeUrl] = make(map[string]types.Resource, len(resources))
+
+			for _, raw := range resources {
+
+				any := &anypb.Any{}
+
+				if err := protojson.Unmarshal(raw, any); err != nil {
+					return nil, err
+				}
+
+				resource, err := any.UnmarshalNew()
+				if err != nil {
+					return nil, err
+				}
+
+				s.Xds[typeUrl][cache.GetResourceName(resource)] = resource
+
+			}
+
+		}
+
+	}
+
+	return s, nil
+
+}
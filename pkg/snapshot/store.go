@@ -0,0 +1,178 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"go.uber.org/zap"
+)
+
+const snapshotExtension = ".json"
+
+type store struct {
+	directory    string
+	prefix       string
+	maxSnapshots int
+
+	sequence uint64
+
+	log *zap.SugaredLogger
+
+	lock *sync.Mutex
+}
+
+type snapshotEntry struct {
+	sequence uint64
+	path     string
+}
+
+func (s *store) list() ([]snapshotEntry, error) {
+
+	dirEntries, err := os.ReadDir(s.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []snapshotEntry{}
+
+	for _, dirEntry := range dirEntries {
+
+		name := dirEntry.Name()
+
+		if dirEntry.IsDir() || !strings.HasPrefix(name, s.prefix+"-") || !strings.HasSuffix(name, snapshotExtension) {
+			continue
+		}
+
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, s.prefix+"-"), snapshotExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, snapshotEntry{
+			sequence: sequence,
+			path:     filepath.Join(s.directory, name),
+		})
+
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].sequence > entries[j].sequence
+	})
+
+	return entries, nil
+
+}
+
+func (s *store) Save(snapshot *Snapshot) error {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sequence++
+
+	snapshot.Sequence = s.sequence
+
+	if snapshot.CreatedAt.IsZero() {
+		snapshot.CreatedAt = time.Now()
+	}
+
+	data, err := encode(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.directory, fmt.Sprintf(".%s-*", s.prefix))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// The rename must not make a snapshot visible before its content is durable.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	path := filepath.Join(s.directory, fmt.Sprintf("%s-%020d%s", s.prefix, snapshot.Sequence, snapshotExtension))
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	observability.SetSnapshotTimestamp(snapshot.CreatedAt)
+
+	return s.prune()
+
+}
+
+func (s *store) prune() error {
+
+	if s.maxSnapshots <= 0 {
+		return nil
+	}
+
+	entries, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	for i := s.maxSnapshots; i < len(entries); i++ {
+		if err := os.Remove(entries[i].path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func (s *store) Latest() (*Snapshot, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+
+		data, err := os.ReadFile(entry.path)
+		if err != nil {
+			s.log.Warnw("error reading snapshot", "path", entry.path, "error", err)
+			continue
+		}
+
+		snapshot, err := decode(data)
+		if err != nil {
+			s.log.Warnw("error decoding snapshot", "path", entry.path, "error", err)
+			continue
+		}
+
+		return snapshot, nil
+
+	}
+
+	return nil, ErrNoSnapshot
+
+}
@@ -0,0 +1,192 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func newTestStore(t *testing.T, dir string, opts ...StoreOption) Store {
+
+	t.Helper()
+
+	s, err := NewStore(append([]StoreOption{WithDirectory(dir)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+
+}
+
+func routerSnapshot(names ...string) *Snapshot {
+
+	router := &serverpb.Router{}
+
+	for _, name := range names {
+		router.Routes = append(router.Routes, &serverpb.Router_Route{Name: name})
+	}
+
+	return &Snapshot{Router: router}
+
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+
+	s := newTestStore(t, t.TempDir())
+
+	router := &serverpb.Router{Routes: []*serverpb.Router_Route{
+		{Name: "api"},
+		{Name: "admin", ParentName: "api"},
+	}}
+
+	if err := s.Save(&Snapshot{Router: router}); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := s.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.Sequence != 1 || latest.CreatedAt.IsZero() || !proto.Equal(latest.Router, router) || latest.Xds != nil {
+		t.Fatalf("unexpected router snapshot %+v", latest)
+	}
+
+	cluster := &clusterv3.Cluster{Name: "backend", ConnectTimeout: durationpb.New(5 * time.Second)}
+	vhost := &routev3.VirtualHost{Name: "default", Domains: []string{"*"}}
+
+	xds := map[string]map[string]types.Resource{
+		resource.ClusterType:     {"backend": cluster},
+		resource.VirtualHostType: {"default": vhost},
+	}
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := s.Save(&Snapshot{CreatedAt: createdAt, Xds: xds}); err != nil {
+		t.Fatal(err)
+	}
+
+	if latest, err = s.Latest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.Sequence != 2 || !latest.CreatedAt.Equal(createdAt) || latest.Router != nil || len(latest.Xds) != 2 {
+		t.Fatalf("unexpected xDS snapshot %+v", latest)
+	}
+
+	if !proto.Equal(latest.Xds[resource.ClusterType]["backend"], cluster) || !proto.Equal(latest.Xds[resource.VirtualHostType]["default"], vhost) {
+		t.Fatalf("unexpected xDS resources %v", latest.Xds)
+	}
+
+}
+
+func TestStoreLatestSkipsInvalidSnapshots(t *testing.T) {
+
+	dir := t.TempDir()
+
+	s := newTestStore(t, dir)
+
+	if _, err := s.Latest(); !errors.Is(err, ErrNoSnapshot) {
+		t.Fatalf("expected %v, got %v", ErrNoSnapshot, err)
+	}
+
+	if err := s.Save(routerSnapshot("api")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Newer snapshots that are corrupt or written by another format version
+	// fall back to the latest readable one.
+	corrupt := filepath.Join(dir, "snapshot-00000000000000000002.json")
+	mismatch := filepath.Join(dir, "snapshot-00000000000000000003.json")
+
+	if err := os.WriteFile(corrupt, []byte(`{"format_version":1,"router":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(mismatch, []byte(`{"format_version":2,"sequence":3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := s.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.Sequence != 1 || latest.Router.Routes[0].Name != "api" {
+		t.Fatalf("unexpected snapshot %+v", latest)
+	}
+
+	data, err := os.ReadFile(mismatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := decode(data); !errors.Is(err, ErrUnsupportedSnapshotVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedSnapshotVersion, err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "snapshot-00000000000000000001.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Latest(); !errors.Is(err, ErrNoSnapshot) {
+		t.Fatalf("expected %v, got %v", ErrNoSnapshot, err)
+	}
+
+}
+
+func TestStorePrune(t *testing.T) {
+
+	dir := t.TempDir()
+
+	s := newTestStore(t, dir, WithMaxSnapshots(2))
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := s.Save(routerSnapshot(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{}
+
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+
+	if len(files) != 2 || files[0] != "snapshot-00000000000000000003.json" || files[1] != "snapshot-00000000000000000004.json" {
+		t.Fatalf("expected the 2 latest snapshots, got %v", files)
+	}
+
+	// A reopened store continues the sequence.
+	s = newTestStore(t, dir, WithMaxSnapshots(2))
+
+	if err := s.Save(routerSnapshot("e")); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := s.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.Sequence != 5 || latest.Router.Routes[0].Name != "e" {
+		t.Fatalf("unexpected snapshot %+v", latest)
+	}
+
+}
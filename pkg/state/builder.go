@@ -2,6 +2,8 @@ package state
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,12 +14,33 @@ import (
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+	"go.uber.org/zap"
 )
 
 type StateManagerOption func(*state)
 
-func NewXdsState() XdsState {
-	return &xdsState{
+type XdsStateOption func(*xdsState)
+
+func WithXdsChangeNotifiers(notifiers ...ChangeNotifier) XdsStateOption {
+	return func(xs *xdsState) {
+		xs.notifiers = append(xs.notifiers, notifiers...)
+	}
+}
+
+func WithXdsCollisionPolicy(policy CollisionPolicy) XdsStateOption {
+	return func(xs *xdsState) {
+		xs.provenance.policy = policy
+	}
+}
+
+func WithXdsLogger(log *zap.SugaredLogger) XdsStateOption {
+	return func(xs *xdsState) {
+		xs.provenance.log = log
+	}
+}
+
+func NewXdsState(opts ...XdsStateOption) XdsState {
+	xs := &xdsState{
 		listenersMap:              make(map[string]*listenerv3.Listener),
 		virtualHostsMap:           make(map[string]*routev3.VirtualHost),
 		routeConfigurationsMap:    make(map[string]*routev3.RouteConfiguration),
@@ -38,21 +61,74 @@ func NewXdsState() XdsState {
 		clusterCache:               cache.NewLinearCache(resource.ClusterType),
 		clusterLoadAssignmentCache: cache.NewLinearCache(resource.EndpointType),
 
+		provenance: newProvenanceTracker(zap.NewNop().Sugar()),
+
 		rwLock: new(sync.RWMutex),
 
+		notifiers: []ChangeNotifier{},
+
 		updateCh: make(chan XdsState),
 	}
+
+	for _, opt := range opts {
+		opt(xs)
+	}
+
+	return xs
 }
 
-func NewRoutesState() RoutesState {
-	return &routesState{
+type RoutesStateOption func(*routesState)
+
+func WithRoutesChangeNotifiers(notifiers ...ChangeNotifier) RoutesStateOption {
+	return func(r *routesState) {
+		r.notifiers = append(r.notifiers, notifiers...)
+	}
+}
+
+func WithInvalidRoutePolicy(policy InvalidRoutePolicy) RoutesStateOption {
+	return func(r *routesState) {
+		r.invalidRoutePolicy = policy
+	}
+}
+
+func WithRoutesCollisionPolicy(policy CollisionPolicy) RoutesStateOption {
+	return func(r *routesState) {
+		r.provenance.policy = policy
+	}
+}
+
+func WithRoutesLogger(log *zap.SugaredLogger) RoutesStateOption {
+	return func(r *routesState) {
+		r.log = log
+		r.provenance.log = log
+	}
+}
+
+func NewRoutesState(opts ...RoutesStateOption) RoutesState {
+
+	log := zap.NewNop().Sugar()
+
+	r := &routesState{
 		routes: NewGraph(func(r *serverpb.Router_Route) string {
 			return r.Name
 		}),
-		routesMap: make(map[string]*serverpb.Router_Route),
-		rwLock:    new(sync.RWMutex),
-		updateCh:  make(chan RoutesState),
+		routesMap:  make(map[string]*serverpb.Router_Route),
+		notifiers:  []ChangeNotifier{},
+		provenance: newProvenanceTracker(log),
+		log:        log,
+		rwLock:     new(sync.RWMutex),
+		updateCh:   make(chan RoutesState),
 	}
+
+	// The first build is signaled even without routes, it replaces the
+	// default handler or a restored snapshot.
+	r.invalidated.Store(true)
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 func WithPeriodicSyncInterval(interval time.Duration) StateManagerOption {
@@ -61,9 +137,56 @@ func WithPeriodicSyncInterval(interval time.Duration) StateManagerOption {
 	}
 }
 
+func WithRetryBackoff(initial, max time.Duration) StateManagerOption {
+	return func(s *state) {
+		s.retryInitialBackoff = initial
+		s.retryMaxBackoff = max
+	}
+}
+
+func WithMaxConsecutiveFailures(maxConsecutiveFailures int) StateManagerOption {
+	return func(s *state) {
+		s.maxConsecutiveFailures = maxConsecutiveFailures
+	}
+}
+
 func WithTfstateSource(tfstateSource TfstateSource) StateManagerOption {
 	return func(s *state) {
-		s.tfstateSource = tfstateSource
+		s.tfstateOrigins = append(s.tfstateOrigins, TfstateOrigin{Source: tfstateSource})
+	}
+}
+
+func WithNamedTfstateSource(name string, tfstateSource TfstateSource) StateManagerOption {
+	return func(s *state) {
+		s.tfstateOrigins = append(s.tfstateOrigins, TfstateOrigin{Name: name, Source: tfstateSource})
+	}
+}
+
+func WithTfstateOrigins(origins ...TfstateOrigin) StateManagerOption {
+	return func(s *state) {
+		s.tfstateOrigins = append(s.tfstateOrigins, origins...)
+	}
+}
+
+// WithVariables adds variables available as ${var.NAME}. Names are case
+// insensitive, variables of a later option take precedence over earlier ones.
+func WithVariables(variables map[string]string) StateManagerOption {
+	return func(s *state) {
+
+		names := make([]string, 0, len(variables))
+
+		for name := range variables {
+			names = append(names, name)
+		}
+
+		// Names differing only by case in the same map resolve the same way on
+		// every start.
+		sort.Strings(names)
+
+		for _, name := range names {
+			s.variables[strings.ToLower(name)] = variables[name]
+		}
+
 	}
 }
 
@@ -76,19 +199,30 @@ func WithManagers(managers ...Manager) StateManagerOption {
 type StateManager interface {
 	Start(context.Context)
 	ErrorCh() <-chan error
+	Status() SyncStatus
+	MarkDegraded(error)
 }
 
 func NewStateManager(opts ...StateManagerOption) StateManager {
 	s := &state{
 		managers:             []Manager{},
 		periodicSyncInterval: 5 * time.Second,
+		retryInitialBackoff:  time.Second,
+		retryMaxBackoff:      time.Minute,
+		latestTfstates:       make(map[string]*Tfstate),
+		variables:            make(map[string]string),
 		errCh:                make(chan error),
-		wg:                   new(sync.WaitGroup),
+		status: &syncStatus{
+			rwLock: new(sync.RWMutex),
+		},
+		wg: new(sync.WaitGroup),
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	s.tfstateSource = NewMergedTfstateSource(s.tfstateOrigins...)
+
 	return s
 }
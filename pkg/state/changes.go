@@ -0,0 +1,113 @@
+package state
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type ResourceChange struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+type ChangeSet struct {
+	Source   string            `json:"source"`
+	Added    []*ResourceChange `json:"added"`
+	Removed  []*ResourceChange `json:"removed"`
+	Modified []*ResourceChange `json:"modified"`
+}
+
+func (c *ChangeSet) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+type ChangeNotifier interface {
+	Notify(*ChangeSet)
+}
+
+func newChangeSet(source string) *ChangeSet {
+	return &ChangeSet{
+		Source:   source,
+		Added:    []*ResourceChange{},
+		Removed:  []*ResourceChange{},
+		Modified: []*ResourceChange{},
+	}
+}
+
+func marshalProtoJson(msg proto.Message) string {
+
+	out, err := protojson.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+
+	return string(out)
+
+}
+
+func diffResources[T proto.Message](changeSet *ChangeSet, resourceType string, before map[string]T, after map[string]T) {
+
+	names := make([]string, 0, len(before)+len(after))
+
+	for name := range before {
+		names = append(names, name)
+	}
+
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		oldResource, hadBefore := before[name]
+		newResource, hasAfter := after[name]
+
+		switch {
+
+		case !hadBefore:
+			changeSet.Added = append(changeSet.Added, &ResourceChange{
+				Type:  resourceType,
+				Name:  name,
+				After: marshalProtoJson(newResource),
+			})
+
+		case !hasAfter:
+			changeSet.Removed = append(changeSet.Removed, &ResourceChange{
+				Type:   resourceType,
+				Name:   name,
+				Before: marshalProtoJson(oldResource),
+			})
+
+		case !proto.Equal(oldResource, newResource):
+			changeSet.Modified = append(changeSet.Modified, &ResourceChange{
+				Type:   resourceType,
+				Name:   name,
+				Before: marshalProtoJson(oldResource),
+				After:  marshalProtoJson(newResource),
+			})
+
+		}
+
+	}
+
+}
+
+func notifyChanges(notifiers []ChangeNotifier, changeSet *ChangeSet) {
+
+	if changeSet.IsEmpty() {
+		return
+	}
+
+	for _, notifier := range notifiers {
+		notifier.Notify(changeSet)
+	}
+
+}
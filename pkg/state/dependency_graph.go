@@ -1,7 +1,7 @@
 package state
 
 import (
-	"container/list"
+	"sort"
 )
 
 type graph[T any] struct {
@@ -46,44 +46,68 @@ func (g *graph[T]) AddEdge(from, to T) {
 	fromNode.dependencies = append(fromNode.dependencies, toNode)
 }
 
+type visitState int
+
+const (
+	unvisited visitState = iota
+	visiting
+	visited
+)
+
 func (g *graph[T]) TopologicalSort() ([]T, error) {
 	result := []T{}
-	visited := make(map[string]bool)
-	l := list.New()
+	states := make(map[string]visitState)
+	path := []string{}
 
 	var visit func(n *node[T]) error
 
 	visit = func(n *node[T]) error {
 		key := g.keyGetter(n.val)
 
-		if _, ok := visited[key]; ok {
+		switch states[key] {
+
+		case visited:
 			return nil
+
+		case visiting:
+			for i, pathKey := range path {
+				if pathKey == key {
+					return &CycleError{Path: append(append([]string{}, path[i:]...), key)}
+				}
+			}
+			return &CycleError{Path: []string{key, key}}
+
 		}
-		visited[key] = true
+
+		states[key] = visiting
+		path = append(path, key)
+
 		for _, dependency := range n.dependencies {
 			if err := visit(dependency); err != nil {
 				return err
 			}
 		}
 
-		l.PushFront(n)
+		path = path[:len(path)-1]
+		states[key] = visited
+
+		result = append(result, n.val)
 
 		return nil
 	}
 
-	for _, node := range g.nodes {
+	keys := make([]string, 0, len(g.nodes))
 
-		key := g.keyGetter(node.val)
-
-		if _, ok := visited[key]; !ok {
-			if err := visit(node); err != nil {
-				return nil, err
-			}
-		}
+	for key := range g.nodes {
+		keys = append(keys, key)
 	}
 
-	for e := l.Front(); e != nil; e = e.Next() {
-		result = append(result, e.Value.(T))
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := visit(g.nodes[key]); err != nil {
+			return nil, err
+		}
 	}
 
 	return result, nil
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newStringGraph() Graph[string] {
+	return NewGraph(func(s string) string {
+		return s
+	})
+}
+
+func TestTopologicalSort(t *testing.T) {
+
+	g := newStringGraph()
+
+	g.AddEdge("admin", "api")
+	g.AddEdge("api", "root")
+	g.AddSingleNode("health")
+
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dependencies come before the nodes depending on them.
+	if expected := []string{"root", "api", "admin", "health"}; !reflect.DeepEqual(sorted, expected) {
+		t.Fatalf("expected %v, got %v", expected, sorted)
+	}
+
+}
+
+func TestTopologicalSortCycles(t *testing.T) {
+
+	cases := []struct {
+		name  string
+		edges [][2]string
+		path  []string
+		error string
+	}{
+		{name: "self loop", edges: [][2]string{{"api", "api"}}, path: []string{"api", "api"}, error: "cycle detected: api -> api"},
+		{name: "three nodes", edges: [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}}, path: []string{"a", "b", "c", "a"}, error: "a -> b -> c -> a"},
+		{name: "cycle below an acyclic node", edges: [][2]string{{"a", "b"}, {"b", "c"}, {"c", "b"}}, path: []string{"b", "c", "b"}, error: "b -> c -> b"},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			g := newStringGraph()
+
+			for _, edge := range c.edges {
+				g.AddEdge(edge[0], edge[1])
+			}
+
+			_, err := g.TopologicalSort()
+
+			cycle := &CycleError{}
+
+			if !errors.As(err, &cycle) || !errors.Is(err, ErrCycleDetected) {
+				t.Fatalf("expected a cycle error, got %v", err)
+			}
+
+			if !reflect.DeepEqual(cycle.Path, c.path) {
+				t.Errorf("expected path %v, got %v", c.path, cycle.Path)
+			}
+
+			if got := err.Error(); !strings.Contains(got, c.error) {
+				t.Errorf("expected %q in %q", c.error, got)
+			}
+
+		})
+
+	}
+
+}
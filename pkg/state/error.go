@@ -1,7 +1,128 @@
 package state
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
-	ErrNoParentFound = errors.New("no parent found")
+	ErrNoParentFound              = errors.New("no parent found")
+	ErrCycleDetected              = errors.New("dependency cycle detected")
+	ErrTooManyConsecutiveFailures = errors.New("too many consecutive state sync failures")
+	ErrResourceCollision          = errors.New("resource defined more than once")
+	ErrUnresolvedReference        = errors.New("unresolved reference")
+	ErrInvalidReference           = errors.New("invalid reference")
 )
+
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrCycleDetected, strings.Join(e.Path, " -> "))
+}
+
+func (e *CycleError) Unwrap() error {
+	return ErrCycleDetected
+}
+
+type MissingParentError struct {
+	Route  string
+	Parent string
+}
+
+func (e *MissingParentError) Error() string {
+	return fmt.Sprintf("%s: route %q references parent %q", ErrNoParentFound, e.Route, e.Parent)
+}
+
+func (e *MissingParentError) Unwrap() error {
+	return ErrNoParentFound
+}
+
+type CollisionError struct {
+	Type        string
+	Name        string
+	Definitions []string
+}
+
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("%s: %s %q defined by %s", ErrResourceCollision, e.Type, e.Name, strings.Join(e.Definitions, ", "))
+}
+
+func (e *CollisionError) Unwrap() error {
+	return ErrResourceCollision
+}
+
+type ReferenceError struct {
+	Reference string
+	Reason    string
+	Err       error
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s ${%s}: %s", e.Err, e.Reference, e.Reason)
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return e.Err
+}
+
+type ResourceError struct {
+	Address string
+	Err     error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Address, e.Err)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+type ErrorClass string
+
+const (
+	TransientError ErrorClass = "transient"
+	PermanentError ErrorClass = "permanent"
+)
+
+type classifiedError struct {
+	class ErrorClass
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func Transient(err error) error {
+	return &classifiedError{class: TransientError, err: err}
+}
+
+func Permanent(err error) error {
+	return &classifiedError{class: PermanentError, err: err}
+}
+
+func ClassifyError(err error) ErrorClass {
+
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return PermanentError
+	}
+
+	return TransientError
+
+}
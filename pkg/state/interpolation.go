@@ -0,0 +1,313 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type interpolationScope struct {
+	tfstates  []*Tfstate
+	variables map[string]string
+	lookupEnv func(string) (string, bool)
+}
+
+func newInterpolationScope(tfstates []*Tfstate, variables map[string]string) *interpolationScope {
+	return &interpolationScope{
+		tfstates:  tfstates,
+		variables: variables,
+		lookupEnv: os.LookupEnv,
+	}
+}
+
+func (t *Tfstate) unmarshalInstance(instance *TfstateResourceInstance, message proto.Message) error {
+
+	protoJson, err := t.resolveProtoJson(instance)
+	if err != nil {
+		return err
+	}
+
+	return protojson.Unmarshal([]byte(protoJson), message)
+
+}
+
+func (t *Tfstate) resolveProtoJson(instance *TfstateResourceInstance) (string, error) {
+
+	protoJson := instance.GetProtoJson()
+
+	if !strings.Contains(protoJson, "${") {
+		return protoJson, nil
+	}
+
+	scope := t.scope
+	if scope == nil {
+		scope = newInterpolationScope([]*Tfstate{t}, nil)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(protoJson))
+	decoder.UseNumber()
+
+	var value interface{}
+
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	value, err := scope.interpolateValue(value)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := &bytes.Buffer{}
+
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+
+}
+
+func (s *interpolationScope) interpolateValue(value interface{}) (interface{}, error) {
+
+	switch v := value.(type) {
+
+	case string:
+		return s.interpolateString(v)
+
+	case []interface{}:
+
+		for i, item := range v {
+
+			resolved, err := s.interpolateValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = resolved
+
+		}
+
+	case map[string]interface{}:
+
+		resolved := make(map[string]interface{}, len(v))
+
+		for key, item := range v {
+
+			resolvedKey, err := s.interpolateText(key)
+			if err != nil {
+				return nil, err
+			}
+
+			resolvedItem, err := s.interpolateValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[resolvedKey] = resolvedItem
+
+		}
+
+		return resolved, nil
+
+	}
+
+	return value, nil
+
+}
+
+// A string made of a single reference keeps the type of the referenced value,
+// so outputs can be used for numbers, booleans and nested messages.
+func (s *interpolationScope) interpolateString(text string) (interface{}, error) {
+
+	if strings.HasPrefix(text, "${") && strings.Index(text, "}") == len(text)-1 {
+		return s.resolve(text[2 : len(text)-1])
+	}
+
+	return s.interpolateText(text)
+
+}
+
+func (s *interpolationScope) interpolateText(text string) (string, error) {
+
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+
+	builder := &strings.Builder{}
+
+	for len(text) > 0 {
+
+		start := strings.Index(text, "${")
+		if start < 0 {
+			builder.WriteString(text)
+			break
+		}
+
+		if start > 0 && text[start-1] == '$' {
+			builder.WriteString(text[:start-1])
+			builder.WriteString("${")
+			text = text[start+2:]
+			continue
+		}
+
+		end := strings.Index(text[start:], "}")
+		if end < 0 {
+			return "", &ReferenceError{Reference: text[start+2:], Reason: "missing closing brace", Err: ErrInvalidReference}
+		}
+
+		value, err := s.resolve(text[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(text[:start])
+
+		switch v := value.(type) {
+
+		case string:
+			builder.WriteString(v)
+
+		case json.Number:
+			builder.WriteString(v.String())
+
+		default:
+
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+
+			builder.Write(encoded)
+
+		}
+
+		text = text[start+end+1:]
+
+	}
+
+	return builder.String(), nil
+
+}
+
+func (s *interpolationScope) resolve(reference string) (interface{}, error) {
+
+	namespace, name, ok := strings.Cut(reference, ".")
+	if !ok || len(name) == 0 {
+		return nil, &ReferenceError{Reference: reference, Reason: "expected output.<state>.<name>, env.<NAME> or var.<NAME>", Err: ErrInvalidReference}
+	}
+
+	switch namespace {
+
+	case "output":
+		return s.resolveOutput(reference, name)
+
+	case "env":
+
+		if value, ok := s.lookupEnv(name); ok {
+			return value, nil
+		}
+
+		return nil, &ReferenceError{Reference: reference, Reason: fmt.Sprintf("environment variable %q is not set", name), Err: ErrUnresolvedReference}
+
+	case "var":
+
+		if value, ok := s.variables[strings.ToLower(name)]; ok {
+			return value, nil
+		}
+
+		return nil, &ReferenceError{Reference: reference, Reason: fmt.Sprintf("variable %q is not defined", name), Err: ErrUnresolvedReference}
+
+	}
+
+	return nil, &ReferenceError{Reference: reference, Reason: fmt.Sprintf("unknown namespace %q", namespace), Err: ErrInvalidReference}
+
+}
+
+func (s *interpolationScope) resolveOutput(reference, output string) (interface{}, error) {
+
+	i := strings.LastIndex(output, ".")
+	if i <= 0 || i == len(output)-1 {
+		return nil, &ReferenceError{Reference: reference, Reason: "expected output.<state>.<name>", Err: ErrInvalidReference}
+	}
+
+	stateName, outputName := output[:i], output[i+1:]
+
+	tfstate, err := s.findTfstate(stateName)
+	if err != nil {
+		return nil, &ReferenceError{Reference: reference, Reason: err.Error(), Err: ErrUnresolvedReference}
+	}
+
+	value, ok := tfstate.Outputs[outputName]
+	if !ok {
+		return nil, &ReferenceError{Reference: reference, Reason: fmt.Sprintf("output %q not found in state %q", outputName, tfstate.Name), Err: ErrUnresolvedReference}
+	}
+
+	return value.Value, nil
+
+}
+
+// States are matched by name, by name without the .tfstate extension or, when
+// unambiguous, by base name. Later states take precedence over earlier ones.
+func (s *interpolationScope) findTfstate(name string) (*Tfstate, error) {
+
+	var found *Tfstate
+
+	byBase := map[string]*Tfstate{}
+
+	for _, tfstate := range s.tfstates {
+
+		if len(tfstate.Name) == 0 {
+			continue
+		}
+
+		trimmed := strings.TrimSuffix(tfstate.Name, ".tfstate")
+
+		if tfstate.Name == name || trimmed == name {
+			found = tfstate
+			continue
+		}
+
+		if path.Base(trimmed) == name {
+			byBase[tfstate.Name] = tfstate
+		}
+
+	}
+
+	if found != nil {
+		return found, nil
+	}
+
+	switch len(byBase) {
+
+	case 0:
+		return nil, fmt.Errorf("state %q not found", name)
+
+	case 1:
+		for _, tfstate := range byBase {
+			return tfstate, nil
+		}
+
+	}
+
+	names := make([]string, 0, len(byBase))
+
+	for stateName := range byBase {
+		names = append(names, stateName)
+	}
+
+	sort.Strings(names)
+
+	return nil, fmt.Errorf("state %q is ambiguous, matches %s", name, strings.Join(names, ", "))
+
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestScope() *interpolationScope {
+
+	network := &Tfstate{
+		Name: "envs/prod/network.tfstate",
+		Outputs: map[string]*TfstateOutput{
+			"port":    {Value: float64(8080)},
+			"tls":     {Value: true},
+			"host":    {Value: "backend.internal"},
+			"address": {Value: map[string]interface{}{"host": "backend.internal", "port": float64(8080)}},
+		},
+	}
+
+	staging := &Tfstate{
+		Name:    "envs/staging/network.tfstate",
+		Outputs: map[string]*TfstateOutput{"port": {Value: float64(9090)}},
+	}
+
+	scope := newInterpolationScope([]*Tfstate{network, staging}, map[string]string{"region": "eu-west-1"})
+
+	scope.lookupEnv = func(name string) (string, bool) {
+		if name == "STAGE" {
+			return "prod", true
+		}
+		return "", false
+	}
+
+	return scope
+
+}
+
+func resolveJson(scope *interpolationScope, protoJson string) (string, error) {
+
+	tfstate := &Tfstate{scope: scope}
+
+	return tfstate.resolveProtoJson(&TfstateResourceInstance{
+		Attributes: map[string]interface{}{"proto_json": protoJson},
+	})
+
+}
+
+func TestInterpolation(t *testing.T) {
+
+	cases := []struct {
+		name      string
+		protoJson string
+		expected  string
+	}{
+		{name: "no reference", protoJson: `{"name":"api"}`, expected: `{"name":"api"}`},
+		{name: "escape", protoJson: `{"name":"$${var.region}"}`, expected: `{"name":"${var.region}"}`},
+		{name: "escape next to a reference", protoJson: `{"name":"$${env.STAGE}-${env.STAGE}"}`, expected: `{"name":"${env.STAGE}-prod"}`},
+		{name: "whole string number", protoJson: `{"port":"${output.envs/prod/network.port}"}`, expected: `{"port":8080}`},
+		{name: "whole string bool", protoJson: `{"tls":"${output.envs/prod/network.tls}"}`, expected: `{"tls":true}`},
+		{name: "whole string object", protoJson: `{"address":"${output.envs/prod/network.address}"}`, expected: `{"address":{"host":"backend.internal","port":8080}}`},
+		{name: "embedded number", protoJson: `{"url":"http://backend:${output.envs/staging/network.tfstate.port}/"}`, expected: `{"url":"http://backend:9090/"}`},
+		{name: "embedded object", protoJson: `{"address":"address=${output.envs/prod/network.address}"}`, expected: `{"address":"address={\"host\":\"backend.internal\",\"port\":8080}"}`},
+		{name: "embedded references", protoJson: `{"name":"${env.STAGE}-${var.REGION}"}`, expected: `{"name":"prod-eu-west-1"}`},
+		{name: "keys and arrays", protoJson: `{"${var.region}":["${env.STAGE}",1]}`, expected: `{"eu-west-1":["prod",1]}`},
+		{name: "numbers kept", protoJson: `{"name":"${env.STAGE}","weight":10000000000000001}`, expected: `{"name":"prod","weight":10000000000000001}`},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			resolved, err := resolveJson(newTestScope(), c.protoJson)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.TrimSpace(resolved) != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, resolved)
+			}
+
+		})
+
+	}
+
+}
+
+func TestInterpolationErrors(t *testing.T) {
+
+	cases := []struct {
+		name      string
+		protoJson string
+		expected  error
+		reason    string
+	}{
+		{name: "ambiguous output", protoJson: `{"port":"${output.network.port}"}`, expected: ErrUnresolvedReference, reason: "envs/prod/network.tfstate, envs/staging/network.tfstate"},
+		{name: "missing state", protoJson: `{"port":"${output.dns.port}"}`, expected: ErrUnresolvedReference, reason: `state "dns" not found`},
+		{name: "missing output", protoJson: `{"port":"${output.envs/prod/network.missing}"}`, expected: ErrUnresolvedReference, reason: `output "missing" not found`},
+		{name: "unset env", protoJson: `{"name":"${env.MISSING}"}`, expected: ErrUnresolvedReference, reason: `environment variable "MISSING" is not set`},
+		{name: "undefined var", protoJson: `{"name":"prefix-${var.missing}"}`, expected: ErrUnresolvedReference, reason: `variable "missing" is not defined`},
+		{name: "unterminated", protoJson: `{"name":"prefix-${var.region"}`, expected: ErrInvalidReference, reason: "missing closing brace"},
+		{name: "unterminated whole string", protoJson: `{"name":"${var.region"}`, expected: ErrInvalidReference, reason: "missing closing brace"},
+		{name: "unknown namespace", protoJson: `{"name":"${data.region}"}`, expected: ErrInvalidReference, reason: `unknown namespace "data"`},
+		{name: "missing output name", protoJson: `{"name":"${output.network}"}`, expected: ErrInvalidReference, reason: "expected output.<state>.<name>"},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			_, err := resolveJson(newTestScope(), c.protoJson)
+
+			referenceErr := &ReferenceError{}
+
+			if !errors.Is(err, c.expected) || !errors.As(err, &referenceErr) {
+				t.Fatalf("expected a reference error wrapping %v, got %v", c.expected, err)
+			}
+
+			if !strings.Contains(referenceErr.Reason, c.reason) {
+				t.Errorf("expected reason %q, got %q", c.reason, referenceErr.Reason)
+			}
+
+		})
+
+	}
+
+}
+
+func TestVariablePrecedence(t *testing.T) {
+
+	// The config file [vars] table is applied first and --var second.
+	s := NewStateManager(
+		WithVariables(map[string]string{"foo": "config", "bar": "config"}),
+		WithVariables(map[string]string{"FOO": "cli"}),
+	).(*state)
+
+	expected := map[string]string{"foo": "cli", "bar": "config"}
+
+	if !reflect.DeepEqual(s.variables, expected) {
+		t.Fatalf("expected %v, got %v", expected, s.variables)
+	}
+
+	resolved, err := resolveJson(newInterpolationScope(nil, s.variables), `{"name":"${var.Foo}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := map[string]string{}
+
+	if err := json.Unmarshal([]byte(resolved), &value); err != nil || value["name"] != "cli" {
+		t.Fatalf("expected the cli variable, got %s, %v", resolved, err)
+	}
+
+}
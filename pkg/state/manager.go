@@ -2,8 +2,16 @@ package state
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type Manager interface {
@@ -11,63 +19,303 @@ type Manager interface {
 	Build() error
 }
 
+// ManagerInvalidator is implemented by managers that only signal changed
+// builds. After a build failed to be applied the next Build must signal it
+// again, even if nothing changed since.
+type ManagerInvalidator interface {
+	Invalidate()
+}
+
 type state struct {
 	managers []Manager
 
-	tfstateSource TfstateSource
+	tfstateOrigins []TfstateOrigin
+	tfstateSource  TfstateSource
+
+	variables map[string]string
 
 	periodicSyncInterval time.Duration
 
+	retryInitialBackoff    time.Duration
+	retryMaxBackoff        time.Duration
+	maxConsecutiveFailures int
+
+	latestTfstates map[string]*Tfstate
+
 	errCh chan error
 
+	status *syncStatus
+
 	wg *sync.WaitGroup
 }
 
+func componentName(component interface{}) string {
+
+	if stringer, ok := component.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	return strings.TrimPrefix(fmt.Sprintf("%T", component), "*")
+}
+
+func (s *state) getTfstate(ctx context.Context) ([]*Tfstate, error) {
+
+	ctx, span := observability.Tracer().Start(ctx, "TfstateSource.GetTfstate")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cruiser.tfstate_source", componentName(s.tfstateSource)))
+
+	tfstates, err := s.tfstateSource.GetTfstate(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("cruiser.tfstate_count", len(tfstates)))
+
+	return tfstates, nil
+
+}
+
+func buildManager(ctx context.Context, manager Manager) error {
+
+	_, span := observability.Tracer().Start(ctx, "Manager.Build")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("cruiser.manager", componentName(manager)))
+
+	if err := manager.Build(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (s *state) syncManager(ctx context.Context, manager Manager, tfstates []*Tfstate) error {
+
+	log := observability.LogFromContext(ctx).With("manager", componentName(manager))
+
+	for _, tfstate := range tfstates {
+		if err := manager.ReadFromTfstate(tfstate); err != nil {
+			log.Errorw("error reading tfstate", "error", err)
+			observability.StateSyncErrorsTotal.WithLabelValues(componentName(manager)).Inc()
+			return Permanent(err)
+		}
+	}
+
+	if err := buildManager(ctx, manager); err != nil {
+		log.Errorw("error building state", "error", err)
+		observability.StateSyncErrorsTotal.WithLabelValues(componentName(manager)).Inc()
+		return Permanent(err)
+	}
+
+	return nil
+
+}
+
+func (s *state) filterTfstates(ctx context.Context, tfstates []*Tfstate) []*Tfstate {
+
+	log := observability.LogFromContext(ctx)
+
+	filtered := make([]*Tfstate, 0, len(tfstates))
+
+	byLineage := make(map[string]int)
+
+	for _, tfstate := range tfstates {
+
+		if len(tfstate.Lineage) == 0 {
+			filtered = append(filtered, tfstate)
+			continue
+		}
+
+		if latest, ok := s.latestTfstates[tfstate.Lineage]; ok && tfstate.Serial < latest.Serial {
+			log.Warnw("ignoring stale tfstate", "lineage", tfstate.Lineage, "serial", tfstate.Serial, "latestSerial", latest.Serial)
+			tfstate = latest
+		}
+
+		if i, ok := byLineage[tfstate.Lineage]; ok {
+
+			kept, ignored := filtered[i], tfstate
+			if tfstate.Serial > kept.Serial {
+				kept, ignored = tfstate, kept
+			}
+
+			log.Warnw("ignoring duplicate tfstate", "lineage", tfstate.Lineage, "serial", ignored.Serial, "keptSerial", kept.Serial)
+
+			filtered[i] = kept
+
+			continue
+
+		}
+
+		byLineage[tfstate.Lineage] = len(filtered)
+
+		filtered = append(filtered, tfstate)
+
+	}
+
+	for _, tfstate := range filtered {
+		if len(tfstate.Lineage) > 0 {
+			s.latestTfstates[tfstate.Lineage] = tfstate
+		}
+	}
+
+	return filtered
+
+}
+
+func (s *state) sync(ctx context.Context) error {
+
+	tfstates, err := s.getTfstate(ctx)
+	if err != nil {
+		observability.LogFromContext(ctx).Errorw("error getting tfstate", "error", err, "source", componentName(s.tfstateSource))
+		return err
+	}
+
+	if tfstates == nil {
+		observability.LogFromContext(ctx).Debugw("tfstate unchanged, skipping build", "source", componentName(s.tfstateSource))
+		return nil
+	}
+
+	tfstates = s.filterTfstates(ctx, tfstates)
+
+	scope := newInterpolationScope(tfstates, s.variables)
+
+	for _, tfstate := range tfstates {
+		tfstate.scope = scope
+	}
+
+	errs := make([]error, len(s.managers))
+
+	for i, m := range s.managers {
+
+		s.wg.Add(1)
+
+		go func(i int, manager Manager) {
+
+			defer s.wg.Done()
+
+			errs[i] = s.syncManager(ctx, manager, tfstates)
+
+		}(i, m)
+
+	}
+
+	s.wg.Wait()
+
+	return errors.Join(errs...)
+
+}
+
+func (s *state) retryDelay(class ErrorClass, failures int) time.Duration {
+
+	backoff := s.retryMaxBackoff
+
+	if class == TransientError {
+		for backoff = s.retryInitialBackoff; failures > 1 && backoff < s.retryMaxBackoff; failures-- {
+			backoff *= 2
+		}
+	}
+
+	if backoff > s.retryMaxBackoff {
+		backoff = s.retryMaxBackoff
+	}
+
+	if backoff <= 0 {
+		return s.periodicSyncInterval
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+}
+
 func (s *state) periodicSync(ctx context.Context) {
 
+	log := observability.LogFromContext(ctx)
+
+	var changes <-chan struct{}
+
+	if watcher, ok := s.tfstateSource.(TfstateWatcher); ok {
+		changes = watcher.Watch(ctx)
+	}
+
+	// Permanent errors only clear when the tfstate changes, so only transient
+	// failures count towards the limit.
+	transientFailures := 0
+
 	for {
 
-		tfstates, err := s.tfstateSource.GetTfstate(ctx)
-		if err != nil {
-			s.errCh <- err
-			return
-		}
+		start := time.Now()
 
-		for _, m := range s.managers {
+		delay := s.periodicSyncInterval
 
-			s.wg.Add(1)
+		if err := s.sync(ctx); err != nil {
 
-			go func(manager Manager) {
+			if invalidator, ok := s.tfstateSource.(TfstateInvalidator); ok {
+				invalidator.Invalidate()
+			}
+
+			class := ClassifyError(err)
+
+			failures := s.status.failure(err)
+
+			observability.StateConsecutiveFailures.Set(float64(failures))
+
+			if class == TransientError {
+				transientFailures++
+			}
+
+			if s.maxConsecutiveFailures > 0 && transientFailures >= s.maxConsecutiveFailures {
+				s.errCh <- fmt.Errorf("%w (%d): %w", ErrTooManyConsecutiveFailures, transientFailures, err)
+				return
+			}
+
+			delay = s.retryDelay(class, failures)
 
-				for _, tfstate := range tfstates {
-					if err := manager.ReadFromTfstate(tfstate); err != nil {
-						s.errCh <- err
-						return
-					}
-				}
+			log.Warnw("state sync failed, keeping current configuration",
+				"error", err,
+				"class", class,
+				"consecutiveFailures", failures,
+				"retryIn", delay,
+			)
 
-				if err := manager.Build(); err != nil {
-					s.errCh <- err
-					return
-				}
+		} else {
 
-				s.wg.Done()
+			elapsed := time.Since(start)
 
-			}(m)
+			log.Debugw("state synchronized", "duration", elapsed)
+
+			observability.StateSyncDuration.Observe(elapsed.Seconds())
+			observability.StateLastSuccessfulSync.SetToCurrentTime()
+			observability.StateConsecutiveFailures.Set(0)
+
+			s.status.success()
+
+			transientFailures = 0
 
 		}
 
-		s.wg.Wait()
+		s.status.scheduled(time.Now().Add(delay))
 
 		select {
 
 		case <-ctx.Done():
-			if err := ctx.Err(); err != nil {
-				s.errCh <- err
-			}
 			return
 
-		case <-time.After(s.periodicSyncInterval):
+		case <-time.After(delay):
+
+		case _, ok := <-changes:
+
+			if !ok {
+				changes = nil
+				break
+			}
+
+			log.Debugw("tfstate change notified", "source", componentName(s.tfstateSource))
 
 		}
 
@@ -77,10 +325,45 @@ func (s *state) periodicSync(ctx context.Context) {
 
 func (s *state) Start(ctx context.Context) {
 
-	go s.periodicSync(ctx)
+	go s.periodicSync(observability.ContextWithLogFields(ctx, "component", "state"))
+
+}
+
+// MarkDegraded records a failure to apply the synchronized state downstream of
+// the managers. The current configuration is kept and the next sync applies the
+// full state again.
+func (s *state) MarkDegraded(err error) {
+
+	if invalidator, ok := s.tfstateSource.(TfstateInvalidator); ok {
+		invalidator.Invalidate()
+	}
+
+	for _, m := range s.managers {
+		if invalidator, ok := m.(ManagerInvalidator); ok {
+			invalidator.Invalidate()
+		}
+	}
+
+	failures := s.status.failure(err)
+
+	observability.StateConsecutiveFailures.Set(float64(failures))
 
 }
 
 func (s *state) ErrorCh() <-chan error {
 	return s.errCh
 }
+
+func (s *state) Status() SyncStatus {
+
+	status := s.status.get()
+
+	status.Source = componentName(s.tfstateSource)
+
+	if reporter, ok := s.tfstateSource.(TfstateObjectsReporter); ok {
+		status.Objects = reporter.TfstateObjects()
+	}
+
+	return status
+
+}
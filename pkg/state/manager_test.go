@@ -0,0 +1,351 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+)
+
+func TestMain(m *testing.M) {
+
+	if err := observability.InitializeLog(observability.WithLogLevel("fatal")); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+
+}
+
+type sourceResult struct {
+	tfstates []*Tfstate
+	err      error
+}
+
+// fakeTfstateSource hands control back to the test on every GetTfstate call,
+// so the sync loop can be stepped one iteration at a time.
+type fakeTfstateSource struct {
+	calls   chan struct{}
+	results chan sourceResult
+	changes chan struct{}
+
+	invalidations atomic.Int32
+}
+
+func newFakeTfstateSource() *fakeTfstateSource {
+	return &fakeTfstateSource{
+		calls:   make(chan struct{}),
+		results: make(chan sourceResult),
+		changes: make(chan struct{}, 1),
+	}
+}
+
+func (f *fakeTfstateSource) GetTfstate(ctx context.Context) ([]*Tfstate, error) {
+
+	select {
+	case f.calls <- struct{}{}:
+	case <-ctx.Done():
+		return nil, Permanent(ctx.Err())
+	}
+
+	select {
+	case result := <-f.results:
+		return result.tfstates, result.err
+	case <-ctx.Done():
+		return nil, Permanent(ctx.Err())
+	}
+
+}
+
+func (f *fakeTfstateSource) Watch(context.Context) <-chan struct{} {
+	return f.changes
+}
+
+func (f *fakeTfstateSource) Invalidate() {
+	f.invalidations.Add(1)
+}
+
+type fakeManager struct {
+	builds    atomic.Int32
+	failBuild atomic.Bool
+}
+
+func (m *fakeManager) ReadFromTfstate(*Tfstate) error {
+	return nil
+}
+
+func (m *fakeManager) Build() error {
+
+	m.builds.Add(1)
+
+	if m.failBuild.Load() {
+		return errors.New("build failed")
+	}
+
+	return nil
+
+}
+
+type syncLoop struct {
+	t       *testing.T
+	source  *fakeTfstateSource
+	manager *fakeManager
+	state   *state
+}
+
+func startSyncLoop(t *testing.T, opts ...StateManagerOption) *syncLoop {
+
+	source := newFakeTfstateSource()
+	manager := &fakeManager{}
+
+	s := NewStateManager(append([]StateManagerOption{
+		WithTfstateSource(source),
+		WithManagers(manager),
+		WithPeriodicSyncInterval(time.Hour),
+		WithRetryBackoff(time.Millisecond, 4*time.Millisecond),
+	}, opts...)...).(*state)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	s.Start(ctx)
+
+	l := &syncLoop{t: t, source: source, manager: manager, state: s}
+
+	l.waitCall()
+
+	return l
+
+}
+
+func (l *syncLoop) waitCall() {
+
+	l.t.Helper()
+
+	select {
+	case <-l.source.calls:
+	case <-time.After(5 * time.Second):
+		l.t.Fatal("sync loop did not call the tfstate source")
+	}
+
+}
+
+// step answers the pending GetTfstate call and waits until the loop comes back
+// to the source, at which point the status reflects the answered call.
+func (l *syncLoop) step(result sourceResult, notify bool) {
+
+	l.t.Helper()
+
+	l.source.results <- result
+
+	if notify {
+		l.source.changes <- struct{}{}
+	}
+
+	l.waitCall()
+
+}
+
+func TestRetryDelay(t *testing.T) {
+
+	s := &state{
+		periodicSyncInterval: 5 * time.Second,
+		retryInitialBackoff:  100 * time.Millisecond,
+		retryMaxBackoff:      time.Second,
+	}
+
+	transient := map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		20: time.Second,
+	}
+
+	for failures, backoff := range transient {
+		for i := 0; i < 100; i++ {
+			if delay := s.retryDelay(TransientError, failures); delay < backoff/2 || delay > backoff {
+				t.Fatalf("transient failure %d: delay %s outside [%s, %s]", failures, delay, backoff/2, backoff)
+			}
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if delay := s.retryDelay(PermanentError, 1); delay < s.retryMaxBackoff/2 || delay > s.retryMaxBackoff {
+			t.Fatalf("permanent failure: delay %s outside [%s, %s]", delay, s.retryMaxBackoff/2, s.retryMaxBackoff)
+		}
+	}
+
+	s.retryInitialBackoff, s.retryMaxBackoff = 0, 0
+
+	if delay := s.retryDelay(TransientError, 1); delay != s.periodicSyncInterval {
+		t.Errorf("expected the sync interval without backoff, got %s", delay)
+	}
+
+}
+
+func TestSyncLoopStatusTransitions(t *testing.T) {
+
+	l := startSyncLoop(t)
+
+	tfstates := []*Tfstate{{Version: 4, Lineage: "lineage", Serial: 1}}
+
+	// Failures are retried with the backoff instead of the hour long interval.
+	l.step(sourceResult{err: Transient(errors.New("unavailable"))}, false)
+
+	status := l.state.Status()
+
+	if !status.Degraded || status.ConsecutiveFailures != 1 || status.ErrorCount != 1 || status.LastErrorClass != TransientError {
+		t.Fatalf("unexpected status after transient failure: %+v", status)
+	}
+
+	if !status.LastSuccessfulSyncTime.IsZero() {
+		t.Fatal("successful sync recorded after a failure")
+	}
+
+	if time.Until(status.NextSyncTime) > time.Second {
+		t.Errorf("transient failure retried at %s", status.NextSyncTime)
+	}
+
+	l.step(sourceResult{err: Permanent(errors.New("corrupt"))}, false)
+
+	if status = l.state.Status(); status.ConsecutiveFailures != 2 || status.LastErrorClass != PermanentError {
+		t.Fatalf("unexpected status after permanent failure: %+v", status)
+	}
+
+	l.step(sourceResult{tfstates: tfstates}, true)
+
+	status = l.state.Status()
+
+	if status.Degraded || status.ConsecutiveFailures != 0 || status.ErrorCount != 2 || status.LastSuccessfulSyncTime.IsZero() {
+		t.Fatalf("unexpected status after success: %+v", status)
+	}
+
+	if time.Until(status.NextSyncTime) < 59*time.Minute {
+		t.Errorf("next sync after success scheduled at %s", status.NextSyncTime)
+	}
+
+	if builds := l.manager.builds.Load(); builds != 1 {
+		t.Fatalf("expected 1 build, got %d", builds)
+	}
+
+	// Unchanged tfstates skip the build.
+	l.step(sourceResult{}, true)
+
+	if builds := l.manager.builds.Load(); builds != 1 {
+		t.Fatalf("unchanged tfstate was built, %d builds", builds)
+	}
+
+	if invalidations := l.source.invalidations.Load(); invalidations != 2 {
+		t.Fatalf("expected 2 invalidations, got %d", invalidations)
+	}
+
+	// A failed build is permanent, invalidates the source and keeps the loop running.
+	l.manager.failBuild.Store(true)
+
+	l.step(sourceResult{tfstates: tfstates}, true)
+
+	if status = l.state.Status(); !status.Degraded || status.LastErrorClass != PermanentError {
+		t.Fatalf("unexpected status after build failure: %+v", status)
+	}
+
+	if invalidations := l.source.invalidations.Load(); invalidations != 3 {
+		t.Fatalf("build failure did not invalidate the source, %d invalidations", invalidations)
+	}
+
+	// The source reports no change, but the failed tfstates are applied again.
+	l.manager.failBuild.Store(false)
+
+	l.step(sourceResult{}, true)
+
+	if builds := l.manager.builds.Load(); builds != 3 {
+		t.Fatalf("expected the failed tfstates to be rebuilt, %d builds", builds)
+	}
+
+	if status = l.state.Status(); status.Degraded {
+		t.Fatalf("status still degraded after rebuild: %+v", status)
+	}
+
+}
+
+func TestSyncLoopFailureLimit(t *testing.T) {
+
+	l := startSyncLoop(t, WithMaxConsecutiveFailures(2))
+
+	for i := 0; i < 3; i++ {
+		l.step(sourceResult{err: Permanent(errors.New("corrupt"))}, false)
+	}
+
+	l.step(sourceResult{err: Transient(errors.New("unavailable"))}, false)
+
+	l.step(sourceResult{tfstates: []*Tfstate{{Version: 4}}}, true)
+
+	l.step(sourceResult{err: Transient(errors.New("unavailable"))}, false)
+
+	if status := l.state.Status(); status.ConsecutiveFailures != 1 {
+		t.Fatalf("success did not reset the failure count: %+v", status)
+	}
+
+	l.source.results <- sourceResult{err: Transient(errors.New("unavailable"))}
+
+	select {
+
+	case err := <-l.state.ErrorCh():
+		if !errors.Is(err, ErrTooManyConsecutiveFailures) {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+	case <-l.source.calls:
+		t.Fatal("sync loop continued past the failure limit")
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("failure limit not reported")
+
+	}
+
+}
+
+func TestSyncLoopWithoutFailureLimit(t *testing.T) {
+
+	l := startSyncLoop(t)
+
+	for i := 0; i < 20; i++ {
+		l.step(sourceResult{err: Transient(errors.New("unavailable"))}, false)
+	}
+
+	if status := l.state.Status(); status.ConsecutiveFailures != 20 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+}
+
+func TestMarkDegraded(t *testing.T) {
+
+	l := startSyncLoop(t)
+
+	l.step(sourceResult{tfstates: []*Tfstate{{Version: 4}}}, true)
+
+	l.state.MarkDegraded(errors.New("invalid routes"))
+
+	status := l.state.Status()
+
+	if !status.Degraded || status.LastError != "invalid routes" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	l.step(sourceResult{}, true)
+
+	if builds := l.manager.builds.Load(); builds != 2 {
+		t.Fatalf("degraded state not applied again, %d builds", builds)
+	}
+
+	if status = l.state.Status(); status.Degraded {
+		t.Fatalf("status still degraded: %+v", status)
+	}
+
+}
@@ -0,0 +1,149 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+type CollisionPolicy int
+
+const (
+	RejectCollisions CollisionPolicy = iota
+	HighestPrecedenceWins
+	WarnOnCollision
+)
+
+type Provenance struct {
+	Type       string   `json:"type"`
+	Name       string   `json:"name"`
+	Origin     string   `json:"origin"`
+	State      string   `json:"state,omitempty"`
+	Address    string   `json:"address"`
+	Overridden []string `json:"overridden,omitempty"`
+}
+
+type provenanceKey struct {
+	resourceType string
+	name         string
+}
+
+type provenanceTracker struct {
+	policy CollisionPolicy
+
+	pending map[provenanceKey]*Provenance
+	served  map[provenanceKey]*Provenance
+
+	log *zap.SugaredLogger
+}
+
+func newProvenanceTracker(log *zap.SugaredLogger) *provenanceTracker {
+	return &provenanceTracker{
+		log:     log,
+		pending: make(map[provenanceKey]*Provenance),
+		served:  make(map[provenanceKey]*Provenance),
+	}
+}
+
+// definition identifies where a resource is defined, the origin alone does not
+// tell apart two tfstates of the same source.
+func (p *Provenance) definition() string {
+
+	if len(p.State) == 0 {
+		return p.Origin
+	}
+
+	if len(p.Origin) == 0 {
+		return p.State
+	}
+
+	return fmt.Sprintf("%s:%s", p.Origin, p.State)
+
+}
+
+func (p *provenanceTracker) record(resourceType, name string, tfstate *Tfstate, address string) error {
+
+	key := provenanceKey{resourceType: resourceType, name: name}
+
+	provenance := &Provenance{
+		Type:    resourceType,
+		Name:    name,
+		Origin:  tfstate.Origin,
+		State:   tfstate.Name,
+		Address: address,
+	}
+
+	if existing, ok := p.pending[key]; ok {
+
+		switch p.policy {
+
+		case RejectCollisions:
+			return &CollisionError{Type: resourceType, Name: name, Definitions: []string{existing.definition(), provenance.definition()}}
+
+		case WarnOnCollision:
+			p.log.Warnw("resource defined more than once, highest precedence wins",
+				"type", resourceType,
+				"name", name,
+				"definition", provenance.definition(),
+				"overridden", existing.definition(),
+			)
+
+		}
+
+		provenance.Overridden = append(append([]string{}, existing.Overridden...), existing.definition())
+
+	}
+
+	p.pending[key] = provenance
+
+	return nil
+
+}
+
+func (p *provenanceTracker) reset() {
+	p.pending = make(map[provenanceKey]*Provenance)
+}
+
+func (p *provenanceTracker) commit(resourceType string, names map[string]struct{}) {
+
+	for key := range p.served {
+		if key.resourceType == resourceType {
+			delete(p.served, key)
+		}
+	}
+
+	for key, provenance := range p.pending {
+
+		if key.resourceType != resourceType {
+			continue
+		}
+
+		if _, ok := names[key.name]; ok {
+			p.served[key] = provenance
+		}
+
+		delete(p.pending, key)
+
+	}
+
+}
+
+func (p *provenanceTracker) list() []Provenance {
+
+	provenances := make([]Provenance, 0, len(p.served))
+
+	for _, provenance := range p.served {
+		provenances = append(provenances, *provenance)
+	}
+
+	sort.Slice(provenances, func(i, j int) bool {
+		if provenances[i].Type != provenances[j].Type {
+			return provenances[i].Type < provenances[j].Type
+		}
+		return provenances[i].Name < provenances[j].Name
+	})
+
+	return provenances
+
+}
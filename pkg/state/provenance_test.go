@@ -0,0 +1,122 @@
+package state
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestProvenanceCollisions(t *testing.T) {
+
+	platform := &Tfstate{Origin: "platform", Name: "routes.tfstate"}
+	platformOther := &Tfstate{Origin: "platform", Name: "other.tfstate"}
+	team := &Tfstate{Origin: "team", Name: "override.tfstate"}
+
+	cases := []struct {
+		name        string
+		policy      CollisionPolicy
+		first       *Tfstate
+		second      *Tfstate
+		definitions []string
+	}{
+		{name: "different origins", policy: RejectCollisions, first: platform, second: team, definitions: []string{"platform:routes.tfstate", "team:override.tfstate"}},
+		{name: "same origin", policy: RejectCollisions, first: platform, second: platformOther, definitions: []string{"platform:routes.tfstate", "platform:other.tfstate"}},
+		{name: "same tfstate", policy: RejectCollisions, first: platform, second: platform, definitions: []string{"platform:routes.tfstate", "platform:routes.tfstate"}},
+		{name: "highest precedence wins across origins", policy: HighestPrecedenceWins, first: platform, second: team},
+		{name: "highest precedence wins in the same origin", policy: HighestPrecedenceWins, first: platform, second: platformOther},
+		{name: "warn in the same origin", policy: WarnOnCollision, first: platform, second: platformOther},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			p := newProvenanceTracker(zap.NewNop().Sugar())
+			p.policy = c.policy
+
+			if err := p.record("cruiser_route", "api", c.first, "cruiser_route.api"); err != nil {
+				t.Fatal(err)
+			}
+
+			err := p.record("cruiser_route", "api", c.second, "cruiser_route.api_override")
+
+			if len(c.definitions) > 0 {
+
+				collision := &CollisionError{}
+
+				if !errors.As(err, &collision) || !errors.Is(err, ErrResourceCollision) {
+					t.Fatalf("expected a collision, got %v", err)
+				}
+
+				if !reflect.DeepEqual(collision.Definitions, c.definitions) {
+					t.Errorf("expected definitions %v, got %v", c.definitions, collision.Definitions)
+				}
+
+				return
+
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p.commit("cruiser_route", map[string]struct{}{"api": {}})
+
+			provenances := p.list()
+
+			if len(provenances) != 1 {
+				t.Fatalf("expected 1 provenance, got %v", provenances)
+			}
+
+			provenance := provenances[0]
+
+			if provenance.Origin != c.second.Origin || provenance.State != c.second.Name || provenance.Address != "cruiser_route.api_override" {
+				t.Errorf("lower precedence definition served: %+v", provenance)
+			}
+
+			if expected := []string{"platform:routes.tfstate"}; !reflect.DeepEqual(provenance.Overridden, expected) {
+				t.Errorf("expected overridden %v, got %v", expected, provenance.Overridden)
+			}
+
+		})
+
+	}
+
+}
+
+func TestProvenanceCommit(t *testing.T) {
+
+	p := newProvenanceTracker(zap.NewNop().Sugar())
+
+	tfstate := &Tfstate{Origin: "platform", Name: "routes.tfstate"}
+
+	for _, name := range []string{"api", "admin", "dangling"} {
+		if err := p.record("cruiser_route", name, tfstate, "cruiser_route."+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.record("cruiser_envoy_cluster", "api", tfstate, "cruiser_envoy_cluster.api"); err != nil {
+		t.Fatalf("same name with another type reported as a collision: %v", err)
+	}
+
+	// Only the served routes are committed, the cluster stays pending.
+	p.commit("cruiser_route", map[string]struct{}{"api": {}, "admin": {}})
+
+	provenances := p.list()
+
+	if len(provenances) != 2 || provenances[0].Name != "admin" || provenances[1].Name != "api" {
+		t.Fatalf("unexpected provenances %+v", provenances)
+	}
+
+	p.reset()
+
+	p.commit("cruiser_envoy_cluster", map[string]struct{}{"api": {}})
+
+	if provenances = p.list(); len(provenances) != 2 {
+		t.Fatalf("reset pending provenance was committed: %+v", provenances)
+	}
+
+}
@@ -1,23 +1,43 @@
 package state
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
-	"google.golang.org/protobuf/encoding/protojson"
+	"go.uber.org/zap"
 )
 
 type RoutesState interface {
 	GetRoutes() ([]*serverpb.Router_Route, error)
+	Provenance() []Provenance
 	UpdateCh() <-chan RoutesState
 	ReadFromTfstate(*Tfstate) error
 	Build() error
 }
 
+type InvalidRoutePolicy int
+
+const (
+	RejectInvalidRoutes InvalidRoutePolicy = iota
+	SkipInvalidRoutes
+)
+
 type routesState struct {
 	routes    Graph[*serverpb.Router_Route]
 	routesMap map[string]*serverpb.Router_Route
 
+	notifiers []ChangeNotifier
+
+	invalidRoutePolicy InvalidRoutePolicy
+
+	provenance *provenanceTracker
+
+	invalidated atomic.Bool
+
+	log *zap.SugaredLogger
+
 	rwLock *sync.RWMutex
 
 	updateCh chan RoutesState
@@ -39,7 +59,15 @@ func (r *routesState) ReadFromTfstate(tfstate *Tfstate) error {
 
 			route := &serverpb.Router_Route{}
 
-			if err := protojson.Unmarshal([]byte(instance.ProtoJson), route); err != nil {
+			if err := tfstate.unmarshalInstance(instance, route); err != nil {
+				r.routesMap = make(map[string]*serverpb.Router_Route)
+				r.provenance.reset()
+				return &ResourceError{Address: resource.Address(instance), Err: err}
+			}
+
+			if err := r.provenance.record(resource.Type, route.Name, tfstate, resource.Address(instance)); err != nil {
+				r.routesMap = make(map[string]*serverpb.Router_Route)
+				r.provenance.reset()
 				return err
 			}
 
@@ -53,28 +81,109 @@ func (r *routesState) ReadFromTfstate(tfstate *Tfstate) error {
 
 }
 
+func validateRouteAncestry(route *serverpb.Router_Route, routes map[string]*serverpb.Router_Route) error {
+
+	path := []string{route.Name}
+
+	for current := route; current.ParentName != ""; {
+
+		parent, ok := routes[current.ParentName]
+		if !ok {
+			return &MissingParentError{Route: current.Name, Parent: current.ParentName}
+		}
+
+		for i, name := range path {
+			if name == parent.Name {
+				return &CycleError{Path: append(path[i:], parent.Name)}
+			}
+		}
+
+		path = append(path, parent.Name)
+
+		current = parent
+
+	}
+
+	return nil
+
+}
+
 func (r *routesState) Build() error {
 
 	r.rwLock.Lock()
 	defer r.rwLock.Unlock()
 
-	for _, route := range r.routesMap {
+	defer r.provenance.reset()
+
+	desired := r.routesMap
+
+	r.routesMap = make(map[string]*serverpb.Router_Route)
+
+	routes := NewGraph(func(r *serverpb.Router_Route) string {
+		return r.Name
+	})
+
+	names := make([]string, 0, len(desired))
+
+	for name := range desired {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		route := desired[name]
+
+		if err := validateRouteAncestry(route, desired); err != nil {
+
+			if r.invalidRoutePolicy == SkipInvalidRoutes {
+				r.log.Warnw("skipping invalid route", "route", route.Name, "error", err)
+				continue
+			}
+
+			return err
+
+		}
 
 		if route.ParentName == "" {
-			r.routes.AddSingleNode(route)
+			routes.AddSingleNode(route)
 			continue
 		}
 
-		parent, ok := r.routesMap[route.ParentName]
-		if !ok {
-			return ErrNoParentFound
-		}
+		routes.AddEdge(route, desired[route.ParentName])
 
-		r.routes.AddEdge(route, parent)
+	}
 
+	before, err := routesByName(r.routes)
+	if err != nil {
+		return err
 	}
 
-	r.routesMap = make(map[string]*serverpb.Router_Route)
+	after, err := routesByName(routes)
+	if err != nil {
+		return err
+	}
+
+	served := make(map[string]struct{}, len(after))
+
+	for name := range after {
+		served[name] = struct{}{}
+	}
+
+	r.provenance.commit("cruiser_route", served)
+
+	changeSet := newChangeSet("routes")
+
+	diffResources(changeSet, "cruiser_route", before, after)
+
+	if !r.invalidated.Swap(false) && changeSet.IsEmpty() {
+		return nil
+	}
+
+	r.routes = routes
+
+	notifyChanges(r.notifiers, changeSet)
 
 	r.updateCh <- r
 
@@ -82,6 +191,12 @@ func (r *routesState) Build() error {
 
 }
 
+// Invalidate makes the next Build signal the routes again. It must not take
+// the lock, Build holds it until the router received the previous update.
+func (r *routesState) Invalidate() {
+	r.invalidated.Store(true)
+}
+
 func (r *routesState) GetRoutes() ([]*serverpb.Router_Route, error) {
 
 	r.rwLock.RLock()
@@ -90,3 +205,29 @@ func (r *routesState) GetRoutes() ([]*serverpb.Router_Route, error) {
 	return r.routes.TopologicalSort()
 
 }
+
+func (r *routesState) Provenance() []Provenance {
+
+	r.rwLock.RLock()
+	defer r.rwLock.RUnlock()
+
+	return r.provenance.list()
+
+}
+
+func routesByName(graph Graph[*serverpb.Router_Route]) (map[string]*serverpb.Router_Route, error) {
+
+	routes, err := graph.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	routesByName := make(map[string]*serverpb.Router_Route, len(routes))
+
+	for _, route := range routes {
+		routesByName[route.Name] = route
+	}
+
+	return routesByName, nil
+
+}
@@ -0,0 +1,332 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	serverpb "github.com/ultraviolet-black/cruiser/pkg/proto/server"
+)
+
+// staticTfstateSource returns the tfstates it is given on every call.
+type staticTfstateSource struct {
+	tfstates []*Tfstate
+}
+
+func (s *staticTfstateSource) GetTfstate(context.Context) ([]*Tfstate, error) {
+	return s.tfstates, nil
+}
+
+func routesTfstate(name string, routes ...string) *Tfstate {
+
+	instances := make([]*TfstateResourceInstance, 0, len(routes))
+
+	for _, route := range routes {
+		instances = append(instances, &TfstateResourceInstance{
+			Attributes: map[string]interface{}{"proto_json": route},
+		})
+	}
+
+	return &Tfstate{
+		Name: name,
+		Resources: []*TfstateResource{{
+			Mode:      "managed",
+			Type:      "cruiser_route",
+			Name:      "routes",
+			Instances: instances,
+		}},
+	}
+
+}
+
+type routesSync struct {
+	t      *testing.T
+	source *staticTfstateSource
+	routes RoutesState
+	state  *state
+	swaps  chan RoutesState
+}
+
+func newRoutesSync(t *testing.T, opts ...RoutesStateOption) *routesSync {
+
+	source := &staticTfstateSource{}
+	routes := NewRoutesState(opts...)
+
+	r := &routesSync{
+		t:      t,
+		source: source,
+		routes: routes,
+		state:  NewStateManager(WithTfstateSource(source), WithManagers(routes)).(*state),
+		swaps:  make(chan RoutesState, 16),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// Build blocks until the router picks the routes up, forward them so
+	// every swap can be counted.
+	go func() {
+		for {
+			select {
+			case routes := <-routes.UpdateCh():
+				r.swaps <- routes
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return r
+
+}
+
+// sync runs one sync with the given tfstates and reports whether the router
+// was asked to swap its routes.
+func (r *routesSync) sync(tfstates ...*Tfstate) bool {
+
+	r.t.Helper()
+
+	r.source.tfstates = tfstates
+
+	if err := r.state.sync(context.Background()); err != nil {
+		r.t.Fatal(err)
+	}
+
+	select {
+	case <-r.swaps:
+		return true
+	case <-time.After(50 * time.Millisecond):
+		return false
+	}
+
+}
+
+func (r *routesSync) served() map[string]*serverpb.Router_Route {
+
+	r.t.Helper()
+
+	routes, err := r.routes.GetRoutes()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+
+	byName := make(map[string]*serverpb.Router_Route, len(routes))
+
+	for _, route := range routes {
+
+		if _, ok := byName[route.Name]; ok {
+			r.t.Fatalf("route %s served more than once", route.Name)
+		}
+
+		byName[route.Name] = route
+
+	}
+
+	return byName
+
+}
+
+func TestRoutesStateReconciliation(t *testing.T) {
+
+	r := newRoutesSync(t)
+
+	if !r.sync(routesTfstate("routes",
+		`{"name":"api","matchers":[{"path_prefix":"/v1"}]}`,
+		`{"name":"admin","parent_name":"api"}`,
+		`{"name":"health"}`,
+	)) {
+		t.Fatal("initial routes not swapped")
+	}
+
+	if served := r.served(); len(served) != 3 {
+		t.Fatalf("unexpected routes %v", served)
+	}
+
+	// A route removed from the desired set disappears.
+	if !r.sync(routesTfstate("routes",
+		`{"name":"api","matchers":[{"path_prefix":"/v1"}]}`,
+		`{"name":"admin","parent_name":"api"}`,
+	)) {
+		t.Fatal("removed route not swapped")
+	}
+
+	if served := r.served(); len(served) != 2 || served["health"] != nil {
+		t.Fatalf("removed route still served: %v", served)
+	}
+
+	// A route with the same name is replaced rather than duplicated.
+	if !r.sync(routesTfstate("routes",
+		`{"name":"api","matchers":[{"path_prefix":"/v2"}]}`,
+		`{"name":"admin","parent_name":"api"}`,
+	)) {
+		t.Fatal("replaced route not swapped")
+	}
+
+	served := r.served()
+
+	if len(served) != 2 || served["api"].Matchers[0].GetPathPrefix() != "/v2" {
+		t.Fatalf("route not replaced: %v", served)
+	}
+
+	// The same desired set, even read from a new tfstate, does not swap.
+	if r.sync(routesTfstate("routes",
+		`{"name":"admin","parent_name":"api"}`,
+		`{"name":"api","matchers":[{"path_prefix":"/v2"}]}`,
+	)) {
+		t.Fatal("unchanged routes swapped")
+	}
+
+	if served := r.served(); len(served) != 2 {
+		t.Fatalf("unexpected routes %v", served)
+	}
+
+}
+
+func TestValidateRouteAncestry(t *testing.T) {
+
+	routes := map[string]*serverpb.Router_Route{}
+
+	for _, route := range []*serverpb.Router_Route{
+		{Name: "api"},
+		{Name: "admin", ParentName: "api"},
+		{Name: "self", ParentName: "self"},
+		{Name: "a", ParentName: "b"},
+		{Name: "b", ParentName: "c"},
+		{Name: "c", ParentName: "a"},
+		{Name: "orphan", ParentName: "missing"},
+		{Name: "orphan-child", ParentName: "orphan"},
+	} {
+		routes[route.Name] = route
+	}
+
+	if err := validateRouteAncestry(routes["admin"], routes); err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := map[string]string{
+		"self": "self -> self",
+		"a":    "a -> b -> c -> a",
+		"c":    "c -> a -> b -> c",
+	}
+
+	for name, path := range cycles {
+
+		err := validateRouteAncestry(routes[name], routes)
+
+		cycle := &CycleError{}
+
+		if !errors.As(err, &cycle) || !errors.Is(err, ErrCycleDetected) {
+			t.Fatalf("%s: expected a cycle error, got %v", name, err)
+		}
+
+		if !strings.HasSuffix(err.Error(), ": "+path) {
+			t.Errorf("%s: expected the path %s in %q", name, path, err)
+		}
+
+	}
+
+	// The missing parent is reported for the route referencing it, also when
+	// validating one of its descendants.
+	for _, name := range []string{"orphan", "orphan-child"} {
+
+		err := validateRouteAncestry(routes[name], routes)
+
+		missing := &MissingParentError{}
+
+		if !errors.As(err, &missing) || !errors.Is(err, ErrNoParentFound) {
+			t.Fatalf("%s: expected a missing parent error, got %v", name, err)
+		}
+
+		if missing.Route != "orphan" || missing.Parent != "missing" || !strings.Contains(err.Error(), `parent "missing"`) {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+
+	}
+
+}
+
+func invalidRoutesTfstate() *Tfstate {
+	return routesTfstate("routes",
+		`{"name":"api"}`,
+		`{"name":"admin","parent_name":"api"}`,
+		`{"name":"health"}`,
+		`{"name":"orphan","parent_name":"missing"}`,
+		`{"name":"orphan-child","parent_name":"orphan"}`,
+		`{"name":"a","parent_name":"b"}`,
+		`{"name":"b","parent_name":"a"}`,
+	)
+}
+
+func TestRoutesStateInvalidRoutes(t *testing.T) {
+
+	r := newRoutesSync(t)
+
+	r.source.tfstates = []*Tfstate{invalidRoutesTfstate()}
+
+	if err := r.state.sync(context.Background()); !errors.Is(err, ErrNoParentFound) && !errors.Is(err, ErrCycleDetected) {
+		t.Fatalf("expected the invalid routes to be rejected, got %v", err)
+	}
+
+}
+
+func TestRoutesStateSkipInvalidRoutes(t *testing.T) {
+
+	r := newRoutesSync(t, WithInvalidRoutePolicy(SkipInvalidRoutes))
+
+	if !r.sync(invalidRoutesTfstate()) {
+		t.Fatal("valid routes not swapped")
+	}
+
+	served := r.served()
+
+	if len(served) != 3 || served["api"] == nil || served["admin"] == nil || served["health"] == nil {
+		t.Fatalf("expected only the valid routes, got %v", served)
+	}
+
+}
+
+func TestRoutesStateDegraded(t *testing.T) {
+
+	r := newRoutesSync(t)
+
+	tfstate := routesTfstate("routes", `{"name":"api"}`)
+
+	if !r.sync(tfstate) {
+		t.Fatal("initial routes not swapped")
+	}
+
+	// The router failed to apply the routes, the unchanged routes are
+	// signaled again on the next sync.
+	r.state.MarkDegraded(errors.New("router not built"))
+
+	if !r.sync(tfstate) {
+		t.Fatal("routes not swapped again after a degraded sync")
+	}
+
+	if r.sync(tfstate) {
+		t.Fatal("unchanged routes swapped")
+	}
+
+}
+
+func TestRoutesStateFirstBuildWithoutRoutes(t *testing.T) {
+
+	r := newRoutesSync(t)
+
+	// The router must be built for the first successful sync, even if it has
+	// no routes, or it never becomes ready.
+	if !r.sync(routesTfstate("routes")) {
+		t.Fatal("first build without routes not swapped")
+	}
+
+	if served := r.served(); len(served) != 0 {
+		t.Fatalf("unexpected routes %v", served)
+	}
+
+	if r.sync(routesTfstate("routes")) {
+		t.Fatal("unchanged routes swapped")
+	}
+
+}
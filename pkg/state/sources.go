@@ -0,0 +1,207 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
+)
+
+type TfstateOrigin struct {
+	Name   string
+	Source TfstateSource
+}
+
+type originSource struct {
+	name     string
+	source   TfstateSource
+	tfstates []*Tfstate
+}
+
+type mergedTfstateSource struct {
+	origins []*originSource
+
+	dirty       bool
+	invalidated atomic.Bool
+
+	lock *sync.Mutex
+}
+
+func NewMergedTfstateSource(origins ...TfstateOrigin) TfstateSource {
+
+	m := &mergedTfstateSource{
+		origins: make([]*originSource, 0, len(origins)),
+		lock:    new(sync.Mutex),
+	}
+
+	for _, origin := range origins {
+
+		if origin.Source == nil {
+			continue
+		}
+
+		name := origin.Name
+		if len(name) == 0 {
+			name = componentName(origin.Source)
+		}
+
+		m.origins = append(m.origins, &originSource{
+			name:   name,
+			source: origin.Source,
+		})
+
+	}
+
+	return m
+
+}
+
+func (m *mergedTfstateSource) String() string {
+
+	names := make([]string, 0, len(m.origins))
+
+	for _, origin := range m.origins {
+		names = append(names, origin.name)
+	}
+
+	return strings.Join(names, ",")
+
+}
+
+func (m *mergedTfstateSource) GetTfstate(ctx context.Context) ([]*Tfstate, error) {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	errs := []error{}
+
+	for _, origin := range m.origins {
+
+		tfstates, err := origin.source.GetTfstate(ctx)
+		if err != nil {
+			observability.StateSyncErrorsTotal.WithLabelValues(origin.name).Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", origin.name, err))
+			continue
+		}
+
+		if tfstates == nil {
+			continue
+		}
+
+		for _, tfstate := range tfstates {
+			tfstate.Origin = origin.name
+		}
+
+		origin.tfstates = tfstates
+
+		m.dirty = true
+
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// Checked after polling, so an invalidation that arrives while a slow
+	// source is polled is honored by this call.
+	if m.invalidated.Swap(false) {
+		m.dirty = true
+	}
+
+	if !m.dirty {
+		return nil, nil
+	}
+
+	m.dirty = false
+
+	merged := []*Tfstate{}
+
+	for _, origin := range m.origins {
+		merged = append(merged, origin.tfstates...)
+	}
+
+	return merged, nil
+
+}
+
+// Invalidate does not take the lock, it may be called while GetTfstate is
+// waiting on a slow source.
+func (m *mergedTfstateSource) Invalidate() {
+
+	m.invalidated.Store(true)
+
+	for _, origin := range m.origins {
+		if invalidator, ok := origin.source.(TfstateInvalidator); ok {
+			invalidator.Invalidate()
+		}
+	}
+
+}
+
+func (m *mergedTfstateSource) TfstateObjects() map[string]string {
+
+	objects := make(map[string]string)
+
+	for _, origin := range m.origins {
+
+		reporter, ok := origin.source.(TfstateObjectsReporter)
+		if !ok {
+			continue
+		}
+
+		for key, version := range reporter.TfstateObjects() {
+
+			if len(m.origins) > 1 {
+				key = fmt.Sprintf("%s:%s", origin.name, key)
+			}
+
+			objects[key] = version
+
+		}
+
+	}
+
+	return objects
+
+}
+
+func (m *mergedTfstateSource) Watch(ctx context.Context) <-chan struct{} {
+
+	var merged chan struct{}
+
+	for _, origin := range m.origins {
+
+		watcher, ok := origin.source.(TfstateWatcher)
+		if !ok {
+			continue
+		}
+
+		changes := watcher.Watch(ctx)
+		if changes == nil {
+			continue
+		}
+
+		if merged == nil {
+			merged = make(chan struct{}, 1)
+		}
+
+		go func(changes <-chan struct{}) {
+
+			for range changes {
+				select {
+				case merged <- struct{}{}:
+				default:
+				}
+			}
+
+		}(changes)
+
+	}
+
+	return merged
+
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+type SyncStatus struct {
+	LastSyncTime           time.Time         `json:"last_sync_time"`
+	LastSuccessfulSyncTime time.Time         `json:"last_successful_sync_time"`
+	LastError              string            `json:"last_error,omitempty"`
+	LastErrorClass         ErrorClass        `json:"last_error_class,omitempty"`
+	LastErrorTime          time.Time         `json:"last_error_time,omitempty"`
+	ErrorCount             uint64            `json:"error_count"`
+	ConsecutiveFailures    int               `json:"consecutive_failures"`
+	Degraded               bool              `json:"degraded"`
+	NextSyncTime           time.Time         `json:"next_sync_time"`
+	Source                 string            `json:"source,omitempty"`
+	Objects                map[string]string `json:"objects,omitempty"`
+}
+
+type TfstateObjectsReporter interface {
+	TfstateObjects() map[string]string
+}
+
+type syncStatus struct {
+	status SyncStatus
+
+	rwLock *sync.RWMutex
+}
+
+func (s *syncStatus) success() {
+
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+
+	now := time.Now()
+
+	s.status.LastSyncTime = now
+	s.status.LastSuccessfulSyncTime = now
+	s.status.ConsecutiveFailures = 0
+	s.status.Degraded = false
+
+}
+
+func (s *syncStatus) failure(err error) int {
+
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+
+	now := time.Now()
+
+	s.status.LastSyncTime = now
+	s.status.LastError = err.Error()
+	s.status.LastErrorClass = ClassifyError(err)
+	s.status.LastErrorTime = now
+	s.status.ErrorCount++
+	s.status.ConsecutiveFailures++
+	s.status.Degraded = true
+
+	return s.status.ConsecutiveFailures
+
+}
+
+func (s *syncStatus) scheduled(next time.Time) {
+
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+
+	s.status.NextSyncTime = next
+
+}
+
+func (s *syncStatus) get() SyncStatus {
+
+	s.rwLock.RLock()
+	defer s.rwLock.RUnlock()
+
+	return s.status
+
+}
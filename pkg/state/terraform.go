@@ -2,24 +2,115 @@ package state
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 type TfstateResourceInstance struct {
-	ProtoJson string `json:"proto_json"`
+	IndexKey            interface{}            `json:"index_key,omitempty"`
+	SchemaVersion       int                    `json:"schema_version"`
+	Attributes          map[string]interface{} `json:"attributes,omitempty"`
+	SensitiveAttributes json.RawMessage        `json:"sensitive_attributes,omitempty"`
+	Private             string                 `json:"private,omitempty"`
+	Dependencies        []string               `json:"dependencies,omitempty"`
+	ProtoJson           string                 `json:"proto_json,omitempty"`
+}
+
+func (i *TfstateResourceInstance) GetProtoJson() string {
+
+	if protoJson, ok := i.Attributes["proto_json"].(string); ok {
+		return protoJson
+	}
+
+	return i.ProtoJson
+
 }
 
 type TfstateResource struct {
+	Module    string                     `json:"module,omitempty"`
+	Mode      string                     `json:"mode"`
 	Type      string                     `json:"type"`
 	Name      string                     `json:"name"`
-	Mode      string                     `json:"mode"`
 	Provider  string                     `json:"provider"`
 	Instances []*TfstateResourceInstance `json:"instances"`
 }
 
+func (r *TfstateResource) Address(instance *TfstateResourceInstance) string {
+
+	parts := []string{}
+
+	if len(r.Module) > 0 {
+		parts = append(parts, r.Module)
+	}
+
+	if r.Mode == "data" {
+		parts = append(parts, "data")
+	}
+
+	parts = append(parts, r.Type, r.Name)
+
+	address := strings.Join(parts, ".")
+
+	if instance == nil {
+		return address
+	}
+
+	switch key := instance.IndexKey.(type) {
+
+	case string:
+		address = fmt.Sprintf("%s[%q]", address, key)
+
+	case float64:
+		address = fmt.Sprintf("%s[%d]", address, int64(key))
+
+	}
+
+	return address
+
+}
+
+type TfstateOutput struct {
+	Value     interface{}     `json:"value"`
+	Type      json.RawMessage `json:"type,omitempty"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
 type Tfstate struct {
-	Resources []*TfstateResource `json:"resources"`
+	Version          int                       `json:"version"`
+	TerraformVersion string                    `json:"terraform_version,omitempty"`
+	Serial           int64                     `json:"serial"`
+	Lineage          string                    `json:"lineage,omitempty"`
+	Outputs          map[string]*TfstateOutput `json:"outputs,omitempty"`
+	Resources        []*TfstateResource        `json:"resources"`
+
+	Name   string `json:"-"`
+	Origin string `json:"-"`
+
+	scope *interpolationScope
 }
 
 type TfstateSource interface {
 	GetTfstate(context.Context) ([]*Tfstate, error)
 }
+
+type TfstateDecrypter interface {
+	Decrypt(context.Context, []byte) ([]byte, error)
+}
+
+const TfstateSignatureSuffix = ".sig"
+
+type TfstateVerifier interface {
+	Verify(content, signature []byte) ([]byte, error)
+}
+
+type TfstateWatcher interface {
+	Watch(context.Context) <-chan struct{}
+}
+
+// TfstateInvalidator is implemented by sources that return nil for unchanged
+// tfstates. After a failed sync the next GetTfstate must return the full set
+// again, even if nothing changed since.
+type TfstateInvalidator interface {
+	Invalidate()
+}
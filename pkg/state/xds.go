@@ -20,7 +20,7 @@ import (
 	"github.com/envoyproxy/go-control-plane/pkg/server/v3"
 
 	_ "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
-	_ "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/stream/v3"
 	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
 	_ "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
@@ -35,14 +35,17 @@ import (
 
 	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/ultraviolet-black/cruiser/pkg/observability"
 	"google.golang.org/grpc"
-	"google.golang.org/protobuf/encoding/protojson"
 )
 
 type XdsState interface {
 	Register(context.Context, *grpc.Server)
 	SetClusterEndpoints([]*endpointv3.ClusterLoadAssignment)
 	UpdateCh() <-chan XdsState
+	GetResources() map[string]map[string]types.Resource
+	Provenance() []Provenance
+	Restore(map[string]map[string]types.Resource)
 	ReadFromTfstate(*Tfstate) error
 	Build() error
 }
@@ -68,6 +71,10 @@ type xdsState struct {
 	clusterCache               *cache.LinearCache
 	clusterLoadAssignmentCache *cache.LinearCache
 
+	notifiers []ChangeNotifier
+
+	provenance *provenanceTracker
+
 	rwLock *sync.RWMutex
 
 	updateCh chan XdsState
@@ -83,17 +90,20 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 		for _, instance := range resource.Instances {
 
+			var name string
+
 			switch resource.Type {
 
 			case "cruiser_envoy_listener":
 
 				listener := &listenerv3.Listener{}
 
-				if err := protojson.Unmarshal([]byte(instance.ProtoJson), listener); err != nil {
-					return err
+				if err := tfstate.unmarshalInstance(instance, listener); err != nil {
+					xs.resetPending()
+					return &ResourceError{Address: resource.Address(instance), Err: err}
 				}
 
-				name := cache.GetResourceName(listener)
+				name = cache.GetResourceName(listener)
 
 				xs.listenersMap[name] = listener
 
@@ -101,11 +111,12 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 				virtualHost := &routev3.VirtualHost{}
 
-				if err := protojson.Unmarshal([]byte(instance.ProtoJson), virtualHost); err != nil {
-					return err
+				if err := tfstate.unmarshalInstance(instance, virtualHost); err != nil {
+					xs.resetPending()
+					return &ResourceError{Address: resource.Address(instance), Err: err}
 				}
 
-				name := cache.GetResourceName(virtualHost)
+				name = cache.GetResourceName(virtualHost)
 
 				xs.virtualHostsMap[name] = virtualHost
 
@@ -113,11 +124,12 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 				cluster := &clusterv3.Cluster{}
 
-				if err := protojson.Unmarshal([]byte(instance.ProtoJson), cluster); err != nil {
-					return err
+				if err := tfstate.unmarshalInstance(instance, cluster); err != nil {
+					xs.resetPending()
+					return &ResourceError{Address: resource.Address(instance), Err: err}
 				}
 
-				name := cache.GetResourceName(cluster)
+				name = cache.GetResourceName(cluster)
 
 				xs.clustersMap[name] = cluster
 
@@ -125,11 +137,12 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 				routeConfiguration := &routev3.RouteConfiguration{}
 
-				if err := protojson.Unmarshal([]byte(instance.ProtoJson), routeConfiguration); err != nil {
-					return err
+				if err := tfstate.unmarshalInstance(instance, routeConfiguration); err != nil {
+					xs.resetPending()
+					return &ResourceError{Address: resource.Address(instance), Err: err}
 				}
 
-				name := cache.GetResourceName(routeConfiguration)
+				name = cache.GetResourceName(routeConfiguration)
 
 				xs.routeConfigurationsMap[name] = routeConfiguration
 
@@ -137,11 +150,12 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 				clusterLoadAssignment := &endpointv3.ClusterLoadAssignment{}
 
-				if err := protojson.Unmarshal([]byte(instance.ProtoJson), clusterLoadAssignment); err != nil {
-					return err
+				if err := tfstate.unmarshalInstance(instance, clusterLoadAssignment); err != nil {
+					xs.resetPending()
+					return &ResourceError{Address: resource.Address(instance), Err: err}
 				}
 
-				name := cache.GetResourceName(clusterLoadAssignment)
+				name = cache.GetResourceName(clusterLoadAssignment)
 
 				if endpoints, ok := xs.endpointsMap[name]; ok {
 					clusterLoadAssignment.Endpoints = endpoints
@@ -149,6 +163,14 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 				xs.clusterLoadAssignmentsMap[name] = clusterLoadAssignment
 
+			default:
+				continue
+
+			}
+
+			if err := xs.provenance.record(resource.Type, name, tfstate, resource.Address(instance)); err != nil {
+				xs.resetPending()
+				return err
 			}
 
 		}
@@ -159,7 +181,33 @@ func (xs *xdsState) ReadFromTfstate(tfstate *Tfstate) error {
 
 }
 
-func transactXdsResource[T types.Resource](cache *cache.LinearCache, toUpdate map[string]T, toDelete map[string]T) error {
+func (xs *xdsState) resetPending() {
+
+	xs.listenersMap = make(map[string]*listenerv3.Listener)
+	xs.virtualHostsMap = make(map[string]*routev3.VirtualHost)
+	xs.routeConfigurationsMap = make(map[string]*routev3.RouteConfiguration)
+	xs.clustersMap = make(map[string]*clusterv3.Cluster)
+	xs.clusterLoadAssignmentsMap = make(map[string]*endpointv3.ClusterLoadAssignment)
+
+	xs.provenance.reset()
+
+}
+
+func resourceNames[T types.Resource](resources map[string]T) map[string]struct{} {
+
+	names := make(map[string]struct{}, len(resources))
+
+	for name := range resources {
+		names[name] = struct{}{}
+	}
+
+	return names
+
+}
+
+func transactXdsResource[T types.Resource](changeSet *ChangeSet, resourceType string, cache *cache.LinearCache, toUpdate map[string]T, toDelete map[string]T) error {
+
+	before := cache.GetResources()
 
 	updates := make(map[string]types.Resource)
 
@@ -179,7 +227,13 @@ func transactXdsResource[T types.Resource](cache *cache.LinearCache, toUpdate ma
 
 	}
 
-	return cache.UpdateResources(updates, deletes)
+	if err := cache.UpdateResources(updates, deletes); err != nil {
+		return err
+	}
+
+	diffResources(changeSet, resourceType, before, cache.GetResources())
+
+	return nil
 
 }
 
@@ -188,26 +242,36 @@ func (xs *xdsState) Build() error {
 	xs.rwLock.Lock()
 	defer xs.rwLock.Unlock()
 
-	if err := transactXdsResource(xs.clusterCache, xs.clustersMap, xs.clustersToDelete); err != nil {
+	defer xs.provenance.reset()
+
+	changeSet := newChangeSet("xds")
+
+	if err := transactXdsResource(changeSet, "cruiser_envoy_cluster", xs.clusterCache, xs.clustersMap, xs.clustersToDelete); err != nil {
 		return err
 	}
 
-	if err := transactXdsResource(xs.clusterLoadAssignmentCache, xs.clusterLoadAssignmentsMap, xs.clusterLoadAssignmentsToDelete); err != nil {
+	if err := transactXdsResource(changeSet, "cruiser_envoy_cluster_load_assignment", xs.clusterLoadAssignmentCache, xs.clusterLoadAssignmentsMap, xs.clusterLoadAssignmentsToDelete); err != nil {
 		return err
 	}
 
-	if err := transactXdsResource(xs.listenerCache, xs.listenersMap, xs.listenersToDelete); err != nil {
+	if err := transactXdsResource(changeSet, "cruiser_envoy_listener", xs.listenerCache, xs.listenersMap, xs.listenersToDelete); err != nil {
 		return err
 	}
 
-	if err := transactXdsResource(xs.virtualHostCache, xs.virtualHostsMap, xs.virtualHostsToDelete); err != nil {
+	if err := transactXdsResource(changeSet, "cruiser_envoy_virtual_host", xs.virtualHostCache, xs.virtualHostsMap, xs.virtualHostsToDelete); err != nil {
 		return err
 	}
 
-	if err := transactXdsResource(xs.routeConfigurationCache, xs.routeConfigurationsMap, xs.routeConfigurationsToDelete); err != nil {
+	if err := transactXdsResource(changeSet, "cruiser_envoy_route_configuration", xs.routeConfigurationCache, xs.routeConfigurationsMap, xs.routeConfigurationsToDelete); err != nil {
 		return err
 	}
 
+	xs.provenance.commit("cruiser_envoy_cluster", resourceNames(xs.clustersMap))
+	xs.provenance.commit("cruiser_envoy_cluster_load_assignment", resourceNames(xs.clusterLoadAssignmentsMap))
+	xs.provenance.commit("cruiser_envoy_listener", resourceNames(xs.listenersMap))
+	xs.provenance.commit("cruiser_envoy_virtual_host", resourceNames(xs.virtualHostsMap))
+	xs.provenance.commit("cruiser_envoy_route_configuration", resourceNames(xs.routeConfigurationsMap))
+
 	xs.listenersToDelete = xs.listenersMap
 	xs.virtualHostsToDelete = xs.virtualHostsMap
 	xs.routeConfigurationsToDelete = xs.routeConfigurationsMap
@@ -220,12 +284,71 @@ func (xs *xdsState) Build() error {
 	xs.clustersMap = make(map[string]*clusterv3.Cluster)
 	xs.clusterLoadAssignmentsMap = make(map[string]*endpointv3.ClusterLoadAssignment)
 
+	xs.updateResourceMetrics()
+
+	if changeSet.IsEmpty() {
+		return nil
+	}
+
+	notifyChanges(xs.notifiers, changeSet)
+
 	xs.updateCh <- xs
 
 	return nil
 
 }
 
+func (xs *xdsState) updateResourceMetrics() {
+
+	observability.XdsResources.WithLabelValues(resource.ListenerType).Set(float64(xs.listenerCache.NumResources()))
+	observability.XdsResources.WithLabelValues(resource.VirtualHostType).Set(float64(xs.virtualHostCache.NumResources()))
+	observability.XdsResources.WithLabelValues(resource.RouteType).Set(float64(xs.routeConfigurationCache.NumResources()))
+	observability.XdsResources.WithLabelValues(resource.ClusterType).Set(float64(xs.clusterCache.NumResources()))
+	observability.XdsResources.WithLabelValues(resource.EndpointType).Set(float64(xs.clusterLoadAssignmentCache.NumResources()))
+
+}
+
+func restoreXdsResource[T types.Resource](cache *cache.LinearCache, resources map[string]types.Resource, served map[string]T) {
+
+	typed := make(map[string]types.Resource, len(resources))
+
+	for name, resource := range resources {
+
+		if r, ok := resource.(T); ok {
+			typed[name] = r
+			served[name] = r
+		}
+
+	}
+
+	cache.SetResources(typed)
+
+}
+
+func (xs *xdsState) Restore(resources map[string]map[string]types.Resource) {
+
+	xs.rwLock.Lock()
+	defer xs.rwLock.Unlock()
+
+	restoreXdsResource(xs.clusterCache, resources[resource.ClusterType], xs.clustersToDelete)
+	restoreXdsResource(xs.clusterLoadAssignmentCache, resources[resource.EndpointType], xs.clusterLoadAssignmentsToDelete)
+	restoreXdsResource(xs.listenerCache, resources[resource.ListenerType], xs.listenersToDelete)
+	restoreXdsResource(xs.virtualHostCache, resources[resource.VirtualHostType], xs.virtualHostsToDelete)
+	restoreXdsResource(xs.routeConfigurationCache, resources[resource.RouteType], xs.routeConfigurationsToDelete)
+
+	xs.updateResourceMetrics()
+
+}
+
+func (xs *xdsState) Provenance() []Provenance {
+
+	xs.rwLock.RLock()
+	defer xs.rwLock.RUnlock()
+
+	return xs.provenance.list()
+
+}
+
 func (xs *xdsState) SetClusterEndpoints(assignments []*endpointv3.ClusterLoadAssignment) {
 
 	xs.rwLock.Lock()
@@ -262,7 +385,24 @@ func (xs *xdsState) Register(ctx context.Context, grpcServer *grpc.Server) {
 		},
 	}
 
-	xdsServer := server.NewServer(ctx, cache, nil)
+	callbacks := server.CallbackFuncs{
+		StreamOpenFunc: func(context.Context, int64, string) error {
+			observability.XdsConnectedStreams.Inc()
+			return nil
+		},
+		StreamClosedFunc: func(int64, *corev3.Node) {
+			observability.XdsConnectedStreams.Dec()
+		},
+		DeltaStreamOpenFunc: func(context.Context, int64, string) error {
+			observability.XdsConnectedStreams.Inc()
+			return nil
+		},
+		DeltaStreamClosedFunc: func(int64, *corev3.Node) {
+			observability.XdsConnectedStreams.Dec()
+		},
+	}
+
+	xdsServer := server.NewServer(ctx, cache, callbacks)
 
 	discoverygrpc.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
 
@@ -275,3 +415,15 @@ func (xs *xdsState) Register(ctx context.Context, grpcServer *grpc.Server) {
 	routeservice.RegisterVirtualHostDiscoveryServiceServer(grpcServer, xdsServer)
 
 }
+
+func (xs *xdsState) GetResources() map[string]map[string]types.Resource {
+
+	return map[string]map[string]types.Resource{
+		resource.ListenerType:    xs.listenerCache.GetResources(),
+		resource.VirtualHostType: xs.virtualHostCache.GetResources(),
+		resource.RouteType:       xs.routeConfigurationCache.GetResources(),
+		resource.ClusterType:     xs.clusterCache.GetResources(),
+		resource.EndpointType:    xs.clusterLoadAssignmentCache.GetResources(),
+	}
+
+}